@@ -0,0 +1,277 @@
+package gollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrStopAgent may be returned by an AgentOptions hook to end RunAgent early
+// without an error; the result's StopReason is AgentStopped.
+var ErrStopAgent = errors.New("agent stopped")
+
+// ErrMaxIterations is returned by RunAgent when the model is still calling
+// tools after AgentOptions.MaxIterations turns.
+var ErrMaxIterations = errors.New("agent reached max iterations")
+
+// ErrTruncated is returned by RunAgent when a turn hits the output token cap
+// and AgentOptions.OnTruncated is TruncationFail.
+var ErrTruncated = errors.New("agent turn truncated")
+
+// TruncationPolicy decides what RunAgent does with a turn for which
+// ResponseMessageGenerate.Truncated reports true.
+type TruncationPolicy int
+
+const (
+	// TruncationFail stops with ErrTruncated.
+	TruncationFail TruncationPolicy = iota
+	// TruncationStop stops without an error, treating the truncated turn as
+	// the final one.
+	TruncationStop
+	// TruncationContinue drops any (possibly incomplete) tool calls from the
+	// truncated turn, asks the model to continue with
+	// AgentOptions.ContinuePrompt and takes another turn.
+	TruncationContinue
+)
+
+// AgentStopReason describes why RunAgent finished.
+type AgentStopReason string
+
+const (
+	AgentDone          AgentStopReason = "done"           // the model answered without calling tools
+	AgentStopped       AgentStopReason = "stopped"        // StopWhen matched or a hook returned ErrStopAgent
+	AgentTruncated     AgentStopReason = "truncated"      // a turn was truncated (TruncationFail or TruncationStop)
+	AgentMaxIterations AgentStopReason = "max_iterations" // MaxIterations turns were taken
+	AgentError         AgentStopReason = "error"          // a turn, tool hook or context failed
+)
+
+// DefaultContinuePrompt is the message sent after a truncated turn under
+// TruncationContinue.
+const DefaultContinuePrompt = "Your previous response was cut off. Continue exactly where you left off."
+
+// AgentOptions configures RunAgent. Any hook may return ErrStopAgent to end
+// the run cleanly; other hook errors end it with that error.
+type AgentOptions struct {
+	// MaxIterations is the maximum number of turns taken (default 10).
+	MaxIterations int
+
+	// BeforeTurn is called before each turn with its zero-based index and
+	// the options about to be sent, which it may modify.
+	BeforeTurn func(ctx context.Context, turn int, opts *RequestOptions) error
+	// AfterTurn is called with each successful response, before its tool
+	// calls are run.
+	AfterTurn func(ctx context.Context, turn int, resp *ResponseMessageGenerate) error
+	// BeforeToolCall is called before each tool call is run. If it returns a
+	// non-nil result the tool is not called and that result is used instead,
+	// e.g. to deny a call that needs approval.
+	BeforeToolCall func(ctx context.Context, call ToolCall) (*ToolResult, error)
+	// AfterToolCall is called with the result of each tool call, which it may
+	// modify. Tool errors have already been turned into an IsError result.
+	AfterToolCall func(ctx context.Context, call ToolCall, res *ToolResult) error
+
+	// Registry, if set, supplies the tools: each turn offers its enabled
+	// tools, read after BeforeTurn so the hook can switch sets on and off,
+	// and tool calls are resolved with its Lookup. The tools argument and
+	// req.Tools are then ignored. The tool hooks see calls under the
+	// resolved tool's qualified name, whatever form the model used.
+	Registry *ToolRegistry
+
+	// ToolExec controls how the tool calls of one turn are run; see
+	// ExecuteToolCalls. With a Concurrency above 1 the tool hooks are called
+	// concurrently.
+	ToolExec ToolExecOptions
+
+	// StopWhen, if set, is checked after each turn (after AfterTurn); when it
+	// reports true the run ends without running that turn's tool calls.
+	StopWhen func(resp *ResponseMessageGenerate) bool
+
+	// OnTruncated selects how truncated turns are handled (default
+	// TruncationFail).
+	OnTruncated TruncationPolicy
+	// ContinuePrompt is sent after a truncated turn under TruncationContinue
+	// (default DefaultContinuePrompt).
+	ContinuePrompt string
+}
+
+// AgentResult is the outcome of RunAgent. It is returned even when RunAgent
+// fails, describing the run up to the failure.
+type AgentResult struct {
+	// Messages is the full transcript: the initial messages followed by every
+	// assistant turn and tool result.
+	Messages []Message
+	// Final is the last response received, or nil if no turn succeeded.
+	Final *ResponseMessageGenerate
+	// Usage is the token usage summed over all turns.
+	Usage Usage
+	// ToolUsage is the token usage reported by tools in ToolResult.Usage,
+	// such as sub-agents run with NewSubAgentTool. It is kept apart from
+	// Usage since it may have been spent on other models.
+	ToolUsage  Usage
+	Iterations int // turns taken
+	StopReason AgentStopReason
+}
+
+// Text returns the assistant text of the final response.
+func (r *AgentResult) Text() string {
+	if r.Final == nil || len(r.Final.Choices) == 0 {
+		return ""
+	}
+	return r.Final.Choices[0].Message.Content
+}
+
+// toolCaller is implemented by clients that trace tool execution, such as
+// *Client.
+type toolCaller interface {
+	HandleToolCall(ctx context.Context, tools []*Tool, call ToolCall) (*ToolResult, error)
+}
+
+// RunAgent runs the tool-use loop: it takes a turn with req, runs every tool
+// call in the response with HandleToolCall, appends the assistant message and
+// tool results to the conversation, and repeats until the model answers
+// without calling a tool. If req.Tools is empty it is filled from tools.
+//
+// A tool that fails, or that the model calls with bad arguments or by an
+// unknown name, does not end the run: its error is sent back to the model as
+// the tool result so it can recover.
+func RunAgent(ctx context.Context, t Turner, req RequestOptions, tools []*Tool, opts AgentOptions) (*AgentResult, error) {
+	if opts.MaxIterations <= 0 {
+		opts.MaxIterations = 10
+	}
+	if opts.ContinuePrompt == "" {
+		opts.ContinuePrompt = DefaultContinuePrompt
+	}
+	if len(req.Tools) == 0 {
+		for _, tool := range tools {
+			req.Tools = append(req.Tools, tool.ApiDef())
+		}
+	}
+
+	handle := func(tools []*Tool) func(context.Context, ToolCall) (*ToolResult, error) {
+		if tc, ok := t.(toolCaller); ok {
+			return func(ctx context.Context, call ToolCall) (*ToolResult, error) {
+				return tc.HandleToolCall(ctx, tools, call)
+			}
+		}
+		return func(ctx context.Context, call ToolCall) (*ToolResult, error) {
+			return HandleToolCall(ctx, tools, call)
+		}
+	}
+
+	res := &AgentResult{Messages: append([]Message(nil), req.Messages...)}
+	stop := func(reason AgentStopReason, err error) (*AgentResult, error) {
+		if errors.Is(err, ErrStopAgent) {
+			reason, err = AgentStopped, nil
+		}
+		res.StopReason = reason
+		return res, err
+	}
+
+	for turn := 0; turn < opts.MaxIterations; turn++ {
+		turnOpts := req
+		turnOpts.Messages = append([]Message(nil), res.Messages...)
+		if opts.BeforeTurn != nil {
+			if err := opts.BeforeTurn(ctx, turn, &turnOpts); err != nil {
+				return stop(AgentError, err)
+			}
+		}
+		if opts.Registry != nil {
+			turnOpts.Tools = opts.Registry.ApiDefs()
+		}
+
+		resp, err := t.TurnContext(ctx, turnOpts)
+		if err != nil {
+			return stop(AgentError, fmt.Errorf("error in agent turn %d: %w", turn, err))
+		}
+		res.Iterations++
+		res.Final = resp
+		res.Usage.Add(resp.Usage)
+		if opts.AfterTurn != nil {
+			if err := opts.AfterTurn(ctx, turn, resp); err != nil {
+				return stop(AgentError, err)
+			}
+		}
+		if len(resp.Choices) == 0 {
+			return stop(AgentError, fmt.Errorf("agent turn %d returned no choices", turn))
+		}
+
+		msg := resp.Choices[0].Message
+		if msg.Role == "" {
+			msg.Role = "assistant"
+		}
+
+		if resp.Truncated() {
+			switch opts.OnTruncated {
+			case TruncationStop:
+				res.Messages = append(res.Messages, msg)
+				return stop(AgentTruncated, nil)
+			case TruncationContinue:
+				msg.ToolCalls = nil
+				if msg.Content != "" || len(msg.ThinkingBlocks) > 0 {
+					res.Messages = append(res.Messages, msg)
+				}
+				res.Messages = append(res.Messages, Message{Role: "user", Content: opts.ContinuePrompt})
+				continue
+			default:
+				res.Messages = append(res.Messages, msg)
+				return stop(AgentTruncated, ErrTruncated)
+			}
+		}
+
+		res.Messages = append(res.Messages, msg)
+		if opts.StopWhen != nil && opts.StopWhen(resp) {
+			return stop(AgentStopped, nil)
+		}
+		if len(msg.ToolCalls) == 0 {
+			return stop(AgentDone, nil)
+		}
+
+		execTools := tools
+		if opts.Registry != nil {
+			execTools = opts.Registry.toolsFor(msg.ToolCalls)
+		}
+		results, err := executeToolCalls(ctx, execTools, msg.ToolCalls, opts.ToolExec, handle(execTools), agentToolHook(&opts))
+		if err != nil {
+			return stop(AgentError, err)
+		}
+		for i, call := range msg.ToolCalls {
+			res.Messages = append(res.Messages, ToolResultMessage(call, results[i]))
+			if results[i].Usage != nil {
+				res.ToolUsage.Add(*results[i].Usage)
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return stop(AgentError, err)
+		}
+	}
+	return stop(AgentMaxIterations, ErrMaxIterations)
+}
+
+// agentToolHook wraps each tool call run by RunAgent with the BeforeToolCall
+// and AfterToolCall hooks. Only hook errors are returned; tool failures have
+// already become IsError results.
+func agentToolHook(opts *AgentOptions) func(context.Context, ToolCall, func(context.Context) *ToolResult) (*ToolResult, error) {
+	return func(ctx context.Context, call ToolCall, run func(context.Context) *ToolResult) (*ToolResult, error) {
+		if opts.Registry != nil {
+			if t, ok := opts.Registry.Lookup(call.Function.Name); ok {
+				call.Function.Name = t.Name
+			}
+		}
+		var res *ToolResult
+		if opts.BeforeToolCall != nil {
+			r, err := opts.BeforeToolCall(ctx, call)
+			if err != nil {
+				return nil, err
+			}
+			res = r
+		}
+		if res == nil {
+			res = run(ctx)
+		}
+		if opts.AfterToolCall != nil {
+			if err := opts.AfterToolCall(ctx, call, res); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	}
+}
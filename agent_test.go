@@ -0,0 +1,161 @@
+package gollama
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// scriptedTurner answers turns with the responses returned by next.
+type scriptedTurner struct {
+	next  func(turn int, opts RequestOptions) *ResponseMessageGenerate
+	calls []RequestOptions
+}
+
+func (s *scriptedTurner) Turn(opts RequestOptions) (*ResponseMessageGenerate, error) {
+	return s.TurnContext(context.Background(), opts)
+}
+
+func (s *scriptedTurner) TurnContext(ctx context.Context, opts RequestOptions) (*ResponseMessageGenerate, error) {
+	s.calls = append(s.calls, opts)
+	return s.next(len(s.calls)-1, opts), nil
+}
+
+func agentResponse(text, stop string, calls ...ToolCall) *ResponseMessageGenerate {
+	return &ResponseMessageGenerate{
+		StopReason: stop,
+		Usage:      Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12},
+		Choices:    []GenChoice{{Message: Message{Role: "assistant", Content: text, ToolCalls: calls}}},
+	}
+}
+
+func agentToolCall(id, name, args string) ToolCall {
+	return ToolCall{ID: id, Type: "function", Function: ToolCallFunction{Name: name, Arguments: args}}
+}
+
+var echoTool = &Tool{
+	Name: "echo",
+	Call: StringResultCall(func(ctx context.Context, params any) (string, error) {
+		return "echo " + params.(map[string]any)["s"].(string), nil
+	}),
+}
+
+func TestRunAgent(t *testing.T) {
+	turner := &scriptedTurner{next: func(turn int, opts RequestOptions) *ResponseMessageGenerate {
+		switch turn {
+		case 0:
+			return agentResponse("", "tool_use", agentToolCall("a", "echo", `{"s":"hi"}`), agentToolCall("b", "missing", `{}`))
+		default:
+			return agentResponse("all done", "end_turn")
+		}
+	}}
+
+	var hooks []string
+	res, err := RunAgent(context.Background(), turner,
+		RequestOptions{Model: "m", Messages: []Message{{Role: "user", Content: "go"}}},
+		[]*Tool{echoTool},
+		AgentOptions{
+			BeforeTurn: func(ctx context.Context, turn int, opts *RequestOptions) error {
+				hooks = append(hooks, "before-turn")
+				return nil
+			},
+			AfterTurn: func(ctx context.Context, turn int, resp *ResponseMessageGenerate) error {
+				hooks = append(hooks, "after-turn")
+				return nil
+			},
+			BeforeToolCall: func(ctx context.Context, call ToolCall) (*ToolResult, error) {
+				hooks = append(hooks, "before-"+call.Function.Name)
+				return nil, nil
+			},
+			AfterToolCall: func(ctx context.Context, call ToolCall, res *ToolResult) error {
+				hooks = append(hooks, "after-"+call.Function.Name)
+				return nil
+			},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StopReason != AgentDone || res.Iterations != 2 || res.Text() != "all done" {
+		t.Errorf("result = %+v", res)
+	}
+	if res.Usage.PromptTokens != 20 || res.Usage.TotalTokens != 24 {
+		t.Errorf("usage = %+v", res.Usage)
+	}
+	want := "before-turn after-turn before-echo after-echo before-missing after-missing before-turn after-turn"
+	if got := strings.Join(hooks, " "); got != want {
+		t.Errorf("hooks = %s", got)
+	}
+
+	// user, assistant, two tool results, final assistant.
+	if len(res.Messages) != 5 {
+		t.Fatalf("transcript has %d messages", len(res.Messages))
+	}
+	if m := res.Messages[2]; m.Role != "tool" || m.ToolCallID != "a" || m.Content != "echo hi" {
+		t.Errorf("tool result = %+v", m)
+	}
+	if m := res.Messages[3]; m.ToolCallID != "b" || !strings.Contains(m.Content, `no such tool "missing"`) {
+		t.Errorf("unknown tool result = %+v", m)
+	}
+	if len(turner.calls[1].Messages) != 4 || len(turner.calls[0].Tools) != 1 || turner.calls[0].Tools[0].Function.Name != "echo" {
+		t.Errorf("second turn sent %+v", turner.calls[1])
+	}
+}
+
+func TestRunAgentLimits(t *testing.T) {
+	loop := &scriptedTurner{next: func(turn int, opts RequestOptions) *ResponseMessageGenerate {
+		return agentResponse("", "tool_use", agentToolCall("a", "echo", `{"s":"again"}`))
+	}}
+	req := RequestOptions{Messages: []Message{{Role: "user", Content: "go"}}}
+
+	res, err := RunAgent(context.Background(), loop, req, []*Tool{echoTool}, AgentOptions{MaxIterations: 3})
+	if !errors.Is(err, ErrMaxIterations) || res.Iterations != 3 || res.StopReason != AgentMaxIterations {
+		t.Errorf("max iterations: %+v, %v", res, err)
+	}
+
+	res, err = RunAgent(context.Background(), loop, req, []*Tool{echoTool}, AgentOptions{
+		StopWhen: func(resp *ResponseMessageGenerate) bool { return true },
+	})
+	if err != nil || res.Iterations != 1 || res.StopReason != AgentStopped || len(res.Messages) != 2 {
+		t.Errorf("StopWhen: %+v, %v", res, err)
+	}
+
+	res, err = RunAgent(context.Background(), loop, req, []*Tool{echoTool}, AgentOptions{
+		BeforeToolCall: func(ctx context.Context, call ToolCall) (*ToolResult, error) { return nil, ErrStopAgent },
+	})
+	if err != nil || res.StopReason != AgentStopped {
+		t.Errorf("ErrStopAgent: %+v, %v", res, err)
+	}
+}
+
+func TestRunAgentTruncation(t *testing.T) {
+	truncating := func() *scriptedTurner {
+		return &scriptedTurner{next: func(turn int, opts RequestOptions) *ResponseMessageGenerate {
+			if turn == 0 {
+				return agentResponse("partial", "max_tokens", agentToolCall("a", "echo", `{"s":`))
+			}
+			return agentResponse("rest", "end_turn")
+		}}
+	}
+	req := RequestOptions{Messages: []Message{{Role: "user", Content: "go"}}}
+
+	res, err := RunAgent(context.Background(), truncating(), req, nil, AgentOptions{})
+	if !errors.Is(err, ErrTruncated) || res.StopReason != AgentTruncated {
+		t.Errorf("fail policy: %+v, %v", res, err)
+	}
+
+	res, err = RunAgent(context.Background(), truncating(), req, nil, AgentOptions{OnTruncated: TruncationStop})
+	if err != nil || res.StopReason != AgentTruncated || res.Text() != "partial" {
+		t.Errorf("stop policy: %+v, %v", res, err)
+	}
+
+	turner := truncating()
+	res, err = RunAgent(context.Background(), turner, req, nil, AgentOptions{OnTruncated: TruncationContinue})
+	if err != nil || res.StopReason != AgentDone || res.Text() != "rest" {
+		t.Fatalf("continue policy: %+v, %v", res, err)
+	}
+	sent := turner.calls[1].Messages
+	if len(sent) != 3 || len(sent[1].ToolCalls) != 0 || sent[2].Content != DefaultContinuePrompt {
+		t.Errorf("continuation sent %+v", sent)
+	}
+}
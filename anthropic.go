@@ -1,6 +1,7 @@
 package gollama
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -17,6 +18,17 @@ type anthropicRequest struct {
 	Tools        []anthropicTool        `json:"tools,omitempty"`
 	Thinking     *anthropicThinking     `json:"thinking,omitempty"`
 	OutputConfig *anthropicOutputConfig `json:"output_config,omitempty"`
+
+	// repairs records malformed tool call arguments in the history that were
+	// fixed with RepairJSON while building the request.
+	repairs []argumentRepair
+}
+
+// argumentRepair describes the fixes applied to one replayed tool call.
+type argumentRepair struct {
+	Tool  string
+	ID    string
+	Fixes []string
 }
 
 // anthropicThinking configures extended/adaptive reasoning. Type is "adaptive"
@@ -374,8 +386,15 @@ func buildAnthropicRequest(opts RequestOptions) (*anthropicRequest, error) {
 				antMsg.Content = append(antMsg.Content, textBlock)
 			}
 			for j, tc := range msg.ToolCalls {
+				args, fixes, err := RepairJSON(tc.Function.Arguments)
+				if err != nil {
+					return nil, fmt.Errorf("error parsing tool call arguments for %q: %w", tc.Function.Name, err)
+				}
+				if len(fixes) > 0 {
+					req.repairs = append(req.repairs, argumentRepair{Tool: tc.Function.Name, ID: tc.ID, Fixes: fixes})
+				}
 				var input any
-				if err := json.Unmarshal([]byte(tc.Function.Arguments), &input); err != nil {
+				if err := json.Unmarshal([]byte(args), &input); err != nil {
 					return nil, fmt.Errorf("error parsing tool call arguments for %q: %w", tc.Function.Name, err)
 				}
 				// Defensive: truncate tool names that exceed the API limit.
@@ -566,33 +585,48 @@ func parseAnthropicResponse(resp *http.Response) (*ResponseMessageGenerate, erro
 // ChatCompletionAnthropic sends a request using Anthropic's native API format with caching support.
 // The system prompt and the last user message before each assistant turn are marked for caching.
 func (c *Client) ChatCompletionAnthropic(opts RequestOptions) (*ResponseMessageGenerate, error) {
+	return c.chatCompletionAnthropic(context.Background(), opts)
+}
+
+func (c *Client) chatCompletionAnthropic(ctx context.Context, opts RequestOptions) (*ResponseMessageGenerate, error) {
 	req, err := buildAnthropicRequest(opts)
 	if err != nil {
 		return nil, err
 	}
-
-	// The native Anthropic API requires an anthropic-version header. Default it
-	// here so callers don't have to; a caller that set one explicitly wins.
-	if _, ok := c.headers["anthropic-version"]; !ok {
-		c.SetHeader("anthropic-version", "2023-06-01")
-	}
+	c.logArgumentRepairs(ctx, req.repairs)
 
 	// Send request to Anthropic's native endpoint
-	resp, err := c.prepareRequest(req, c.anthropicEndpoint("/messages"))
+	call, err := newCall("anthropic", "chat", http.MethodPost, c.anthropicEndpoint("/messages"), &opts, req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	return invoke(ctx, c, call, func(ctx context.Context, call *Call) (*ResponseMessageGenerate, error) {
+		meta := &ResponseMeta{}
+		resp, err := c.roundTrip(ctx, meta, call)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
 
-	return parseAnthropicResponse(resp)
+		result, err := parseAnthropicResponse(resp)
+		if err != nil {
+			return nil, err
+		}
+		meta.finish()
+		result.Meta = meta
+		return result, nil
+	})
 }
 
 // IsAnthropicAPI checks if the client is configured to use Anthropic's API.
 // Returns true if SetAnthropicMode(true) was called, or if the base URL
 // contains "anthropic.com" (auto-detection fallback).
 func (c *Client) IsAnthropicAPI() bool {
-	if c.anthropicMode != nil {
-		return *c.anthropicMode
+	c.mu.RLock()
+	mode := c.anthropicMode
+	c.mu.RUnlock()
+	if mode != nil {
+		return *mode
 	}
 	return strings.Contains(c.baseURL, "anthropic.com")
 }
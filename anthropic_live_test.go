@@ -1,25 +1,99 @@
 package gollama
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
 
+// liveCassetteDir holds the cassettes of the live tests.
+var liveCassetteDir = filepath.Join("testdata", "cassettes")
+
 // liveAnthropicClient builds a client against the real Anthropic API, skipping
 // the test when no key is available. Set ANTHROPIC_API_KEY to run.
+//
+// If <liveCassetteDir>/<test name>.json exists the test replays it offline
+// instead. Set GOLLAMA_RECORD=1 along with the key to (re)record it.
 func liveAnthropicClient(t *testing.T) *Client {
 	t.Helper()
+	path := filepath.Join(liveCassetteDir, strings.ReplaceAll(t.Name(), "/", "_")+".json")
 	key := os.Getenv("ANTHROPIC_API_KEY")
+	record := key != "" && os.Getenv("GOLLAMA_RECORD") != ""
+
+	var opts []Option
+	if _, err := os.Stat(path); err == nil && !record {
+		cas, err := NewCassette(path, CassetteReplay)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts = append(opts, WithTransport(cas))
+		key = "replayed"
+	} else if record {
+		cas, err := NewCassette(path, CassetteRecord)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Error(err)
+			}
+			if err := cas.Save(); err != nil {
+				t.Errorf("saving cassette: %v", err)
+			}
+		})
+		opts = append(opts, WithTransport(cas))
+	}
 	if key == "" {
 		t.Skip("ANTHROPIC_API_KEY not set; skipping live Anthropic test")
 	}
-	c := NewClient("https://api.anthropic.com")
+	c := NewClient("https://api.anthropic.com", opts...)
 	c.SetAnthropicMode(true)
 	c.SetAPIKey(key)
 	return c
 }
 
+// TestLiveAnthropicClientReplay records a session as GOLLAMA_RECORD would,
+// but against a local server, and checks that liveAnthropicClient replays
+// it offline without an API key.
+func TestLiveAnthropicClientReplay(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	saved := liveCassetteDir
+	liveCassetteDir = t.TempDir()
+	defer func() { liveCassetteDir = saved }()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"role":"assistant","model":"claude","content":[{"type":"text","text":"3"}],"stop_reason":"end_turn","usage":{"input_tokens":12,"output_tokens":1}}`)
+	}))
+	opts := RequestOptions{
+		Model:    liveThinkingModel,
+		Messages: []Message{{Role: "user", Content: "How many times does the letter r appear in 'strawberry'?"}},
+		Options:  &Options{MaxTokens: 64},
+	}
+	rec, err := NewCassette(filepath.Join(liveCassetteDir, t.Name()+".json"), CassetteRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewClient(srv.URL, WithAnthropicMode(true), WithAPIKey("sk-ant-recording"), WithTransport(rec)).Turn(opts); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := liveAnthropicClient(t).Turn(opts)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if got := resp.Choices[0].Message.Content; got != "3" {
+		t.Errorf("replayed %q", got)
+	}
+}
+
 const liveThinkingModel = "claude-opus-4-8"
 
 // TestAnthropicThinkingLive_Reasoning checks that adaptive thinking + a
@@ -0,0 +1,234 @@
+package gollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Approval is the decision an ApprovalPolicy makes about a tool call.
+type Approval int
+
+const (
+	ApprovalAllow Approval = iota // run the call
+	ApprovalDeny                  // refuse the call, telling the model why
+	ApprovalAsk                   // ask a person through ApprovalPolicy.Ask
+)
+
+func (a Approval) String() string {
+	switch a {
+	case ApprovalDeny:
+		return "deny"
+	case ApprovalAsk:
+		return "ask"
+	default:
+		return "allow"
+	}
+}
+
+// ApprovalRule matches tool calls by tool name and arguments.
+type ApprovalRule struct {
+	// Tool is a path.Match pattern for the tool name, e.g. "shell" or
+	// "github__*". Empty matches every tool.
+	Tool string
+	// Args maps argument paths to patterns the argument must match. A path
+	// names a top-level argument or, with dots, a nested one ("options.force").
+	// Strings are matched as is, other values as JSON. All patterns must
+	// match; a missing argument does not.
+	Args map[string]*regexp.Regexp
+	// Decision is applied to matching calls.
+	Decision Approval
+	// Reason is sent to the model when a matching call is denied.
+	Reason string
+}
+
+// matches reports whether the rule applies to a call of tool with args.
+func (r *ApprovalRule) matches(tool string, args map[string]any) bool {
+	if r.Tool != "" {
+		if ok, _ := path.Match(r.Tool, tool); !ok {
+			return false
+		}
+	}
+	for p, re := range r.Args {
+		v, ok := argumentAt(args, p)
+		if !ok || !re.MatchString(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// argumentAt returns the argument at a dotted path as a string.
+func argumentAt(args map[string]any, p string) (string, bool) {
+	var cur any = args
+	for _, key := range strings.Split(p, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return "", false
+		}
+		if cur, ok = m[key]; !ok {
+			return "", false
+		}
+	}
+	if s, ok := cur.(string); ok {
+		return s, true
+	}
+	b, _ := json.Marshal(cur)
+	return string(b), true
+}
+
+// ApprovalRequest is passed to ApprovalPolicy.Ask.
+type ApprovalRequest struct {
+	Call ToolCall
+	Args map[string]any // decoded arguments; nil if they are not valid JSON
+	Rule *ApprovalRule  // the rule that asked, or nil for the policy default
+}
+
+// ApprovalRecord is one entry of the audit trail.
+type ApprovalRecord struct {
+	Time       time.Time
+	ToolCallID string
+	Tool       string
+	Arguments  string
+	// Decision is the outcome: ApprovalAllow or ApprovalDeny.
+	Decision Approval
+	// Rule is the index of the matching rule in ApprovalPolicy.Rules, or -1
+	// if the policy default applied.
+	Rule int
+	// Asked is set if the decision was made by ApprovalPolicy.Ask.
+	Asked  bool
+	Reason string
+}
+
+// ApprovalPolicy gates tool calls: each call is allowed, denied, or put to a
+// person, according to the first rule that matches it. Use BeforeToolCall
+// as AgentOptions.BeforeToolCall, or call Check directly.
+type ApprovalPolicy struct {
+	// Rules are tried in order; the first match decides.
+	Rules []ApprovalRule
+	// Default applies to calls no rule matches (default ApprovalAllow).
+	Default Approval
+	// Ask is called for ApprovalAsk decisions and reports whether the call
+	// is approved, with an optional reason for a denial. Without Ask such
+	// calls are denied. With concurrent tool execution it may be called
+	// concurrently.
+	Ask func(ctx context.Context, req ApprovalRequest) (approved bool, reason string, err error)
+	// Audit, if set, is called with every decision.
+	Audit func(ctx context.Context, rec ApprovalRecord)
+	// Registry, if set, resolves each call with its Lookup before the rules
+	// are tried, so that they match the tool's qualified name however the
+	// model spelled it. RunAgent already does this when
+	// AgentOptions.Registry is set.
+	Registry *ToolRegistry
+}
+
+// Check decides whether call may run. It returns the audited record; an
+// error is returned only if Ask fails, in which case the call is audited as
+// denied.
+func (p *ApprovalPolicy) Check(ctx context.Context, call ToolCall) (ApprovalRecord, error) {
+	if p.Registry != nil {
+		if t, ok := p.Registry.Lookup(call.Function.Name); ok {
+			call.Function.Name = t.Name
+		}
+	}
+	rec := ApprovalRecord{
+		Time:       time.Now(),
+		ToolCallID: call.ID,
+		Tool:       call.Function.Name,
+		Arguments:  call.Function.Arguments,
+		Rule:       -1,
+	}
+
+	var args map[string]any
+	if fixed, _, err := RepairJSON(call.Function.Arguments); err == nil {
+		json.Unmarshal([]byte(fixed), &args)
+	}
+
+	decision := p.Default
+	var rule *ApprovalRule
+	for i := range p.Rules {
+		if p.Rules[i].matches(call.Function.Name, args) {
+			rule = &p.Rules[i]
+			rec.Rule = i
+			decision = rule.Decision
+			if decision == ApprovalDeny {
+				rec.Reason = rule.Reason
+			}
+			break
+		}
+	}
+
+	if decision == ApprovalAsk {
+		rec.Asked = true
+		decision = ApprovalDeny
+		if p.Ask == nil {
+			rec.Reason = "approval required but no approver is configured"
+		} else {
+			approved, reason, err := p.Ask(ctx, ApprovalRequest{Call: call, Args: args, Rule: rule})
+			if err != nil {
+				rec.Decision = ApprovalDeny
+				rec.Reason = err.Error()
+				if p.Audit != nil {
+					p.Audit(ctx, rec)
+				}
+				return rec, fmt.Errorf("error asking for approval of %q: %w", call.Function.Name, err)
+			}
+			if approved {
+				decision = ApprovalAllow
+			} else {
+				rec.Reason = reason
+			}
+		}
+	}
+	if decision == ApprovalDeny && rec.Reason == "" {
+		rec.Reason = "denied by policy"
+	}
+	rec.Decision = decision
+
+	if p.Audit != nil {
+		p.Audit(ctx, rec)
+	}
+	return rec, nil
+}
+
+// BeforeToolCall has the signature of AgentOptions.BeforeToolCall. It
+// returns nil for allowed calls, letting them run, and an IsError result
+// carrying the reason for denied ones.
+func (p *ApprovalPolicy) BeforeToolCall(ctx context.Context, call ToolCall) (*ToolResult, error) {
+	rec, err := p.Check(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Decision == ApprovalAllow {
+		return nil, nil
+	}
+	return &ToolResult{
+		Content:    fmt.Sprintf("tool call %q was denied: %s", call.Function.Name, rec.Reason),
+		IsError:    true,
+		Structured: rec,
+	}, nil
+}
+
+// SlogAudit returns an ApprovalPolicy.Audit function that logs each decision
+// to l: allowed calls at Info, denied ones at Warn.
+func SlogAudit(l *slog.Logger) func(context.Context, ApprovalRecord) {
+	return func(ctx context.Context, rec ApprovalRecord) {
+		level := slog.LevelInfo
+		if rec.Decision != ApprovalAllow {
+			level = slog.LevelWarn
+		}
+		l.Log(ctx, level, "tool call "+rec.Decision.String(),
+			"tool", rec.Tool,
+			"tool_call_id", rec.ToolCallID,
+			"arguments", redactSecrets(rec.Arguments),
+			"rule", rec.Rule,
+			"asked", rec.Asked,
+			"reason", rec.Reason,
+		)
+	}
+}
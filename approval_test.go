@@ -0,0 +1,156 @@
+package gollama
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestApprovalPolicy(t *testing.T) {
+	var asked []string
+	var audit []ApprovalRecord
+	policy := &ApprovalPolicy{
+		Rules: []ApprovalRule{
+			{Tool: "shell", Args: map[string]*regexp.Regexp{"cmd": regexp.MustCompile(`\brm\s+-rf\b`)}, Decision: ApprovalDeny, Reason: "recursive deletes are not allowed"},
+			{Tool: "shell", Decision: ApprovalAsk},
+			{Tool: "deploy__*", Args: map[string]*regexp.Regexp{"options.env": regexp.MustCompile(`^prod$`)}, Decision: ApprovalAsk},
+		},
+		Ask: func(ctx context.Context, req ApprovalRequest) (bool, string, error) {
+			asked = append(asked, req.Call.Function.Name)
+			if req.Args["cmd"] == "ls" {
+				return true, "", nil
+			}
+			return false, "operator said no", nil
+		},
+		Audit: func(ctx context.Context, rec ApprovalRecord) { audit = append(audit, rec) },
+	}
+
+	cases := []struct {
+		call   ToolCall
+		allow  bool
+		reason string
+	}{
+		{agentToolCall("1", "shell", `{"cmd":"rm -rf /"}`), false, "recursive deletes are not allowed"},
+		{agentToolCall("2", "shell", `{"cmd":"ls"}`), true, ""},
+		{agentToolCall("3", "shell", `{"cmd":"reboot"}`), false, "operator said no"},
+		{agentToolCall("4", "deploy__app", `{"options":{"env":"prod"}}`), false, "operator said no"},
+		{agentToolCall("5", "deploy__app", `{"options":{"env":"staging"}}`), true, ""},
+		{agentToolCall("6", "read_file", `{}`), true, ""},
+	}
+	for _, c := range cases {
+		res, err := policy.BeforeToolCall(context.Background(), c.call)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.allow != (res == nil) {
+			t.Errorf("call %s: result %+v", c.call.ID, res)
+			continue
+		}
+		if !c.allow && (!res.IsError || !strings.HasSuffix(res.Content, c.reason)) {
+			t.Errorf("call %s: denial %q", c.call.ID, res.Content)
+		}
+	}
+	if strings.Join(asked, ",") != "shell,shell,deploy__app" {
+		t.Errorf("asked = %v", asked)
+	}
+	if len(audit) != len(cases) || audit[0].Rule != 0 || audit[1].Rule != 1 || !audit[1].Asked || audit[5].Rule != -1 || audit[5].Decision != ApprovalAllow {
+		t.Errorf("audit = %+v", audit)
+	}
+
+	// Without an approver, calls needing approval are denied.
+	policy.Ask = nil
+	res, _ := policy.BeforeToolCall(context.Background(), cases[1].call)
+	if res == nil || !strings.Contains(res.Content, "no approver") {
+		t.Errorf("unattended ask = %+v", res)
+	}
+}
+
+func TestApprovalInAgent(t *testing.T) {
+	ran := false
+	shell := &Tool{Name: "shell", Call: StringResultCall(func(context.Context, any) (string, error) {
+		ran = true
+		return "", nil
+	})}
+	turner := &scriptedTurner{next: func(turn int, opts RequestOptions) *ResponseMessageGenerate {
+		if turn == 0 {
+			return agentResponse("", "tool_use", agentToolCall("1", "shell", `{"cmd":"rm -rf /"}`))
+		}
+		return agentResponse("ok", "end_turn")
+	}}
+
+	var buf bytes.Buffer
+	policy := &ApprovalPolicy{Default: ApprovalDeny, Audit: SlogAudit(slog.New(slog.NewTextHandler(&buf, nil)))}
+	res, err := RunAgent(context.Background(), turner, RequestOptions{Messages: []Message{{Role: "user", Content: "clean up"}}},
+		[]*Tool{shell}, AgentOptions{BeforeToolCall: policy.BeforeToolCall})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ran || !strings.Contains(res.Messages[2].Content, "denied by policy") {
+		t.Errorf("ran = %v, tool message = %+v", ran, res.Messages[2])
+	}
+	if !strings.Contains(buf.String(), "level=WARN msg=\"tool call deny\" tool=shell") {
+		t.Errorf("audit log: %s", buf.String())
+	}
+}
+
+func TestApprovalRegistryNames(t *testing.T) {
+	ran := false
+	reg := NewToolRegistry()
+	if err := reg.Register("local", &Tool{Name: "shell", Call: StringResultCall(func(context.Context, any) (string, error) {
+		ran = true
+		return "", nil
+	})}); err != nil {
+		t.Fatal(err)
+	}
+	rules := []ApprovalRule{{Tool: "local__*", Decision: ApprovalDeny, Reason: "no local tools"}}
+
+	// Check resolves the base name through its Registry.
+	policy := &ApprovalPolicy{Rules: rules, Registry: reg}
+	for _, name := range []string{"shell", "local.shell", "local__shell"} {
+		rec, err := policy.Check(context.Background(), agentToolCall("1", name, `{}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec.Decision != ApprovalDeny || rec.Tool != "local__shell" {
+			t.Errorf("%s: record = %+v", name, rec)
+		}
+	}
+
+	// RunAgent passes the hook the qualified name.
+	turner := &scriptedTurner{next: func(turn int, opts RequestOptions) *ResponseMessageGenerate {
+		if turn == 0 {
+			return agentResponse("", "tool_use", agentToolCall("1", "shell", `{"cmd":"ls"}`))
+		}
+		return agentResponse("ok", "end_turn")
+	}}
+	policy = &ApprovalPolicy{Rules: rules}
+	res, err := RunAgent(context.Background(), turner, RequestOptions{Messages: []Message{{Role: "user", Content: "look"}}},
+		nil, AgentOptions{Registry: reg, BeforeToolCall: policy.BeforeToolCall})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ran || !strings.Contains(res.Messages[2].Content, "no local tools") {
+		t.Errorf("ran = %v, tool message = %+v", ran, res.Messages[2])
+	}
+}
+
+func TestApprovalAskError(t *testing.T) {
+	var audit []ApprovalRecord
+	policy := &ApprovalPolicy{
+		Default: ApprovalAsk,
+		Ask: func(ctx context.Context, req ApprovalRequest) (bool, string, error) {
+			return false, "", errors.New("approver unreachable")
+		},
+		Audit: func(ctx context.Context, rec ApprovalRecord) { audit = append(audit, rec) },
+	}
+	if _, err := policy.Check(context.Background(), agentToolCall("1", "shell", `{}`)); err == nil {
+		t.Fatal("no error")
+	}
+	if len(audit) != 1 || audit[0].Decision != ApprovalDeny || !audit[0].Asked || audit[0].Reason != "approver unreachable" {
+		t.Errorf("audit = %+v", audit)
+	}
+}
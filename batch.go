@@ -1,14 +1,18 @@
 package gollama
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strconv"
 )
 
 // ============== Message Batches API ==============
-// Anthropic Batch API types and methods
+// Batch types follow Anthropic's Message Batches API. Clients pointed at an
+// OpenAI-compatible endpoint are translated to the file-based OpenAI Batch API
+// (see batch_openai.go), so callers can use the same types for both.
 
 // BatchRequest represents a single request in a batch.
 type BatchRequest struct {
@@ -55,6 +59,15 @@ type Batch struct {
 	ArchivedAt        *string            `json:"archived_at,omitempty"`
 	CancelInitiatedAt *string            `json:"cancel_initiated_at,omitempty"`
 	ResultsURL        *string            `json:"results_url,omitempty"`
+
+	// File IDs backing an OpenAI batch; empty for Anthropic batches.
+	InputFileID  string `json:"input_file_id,omitempty"`
+	OutputFileID string `json:"output_file_id,omitempty"`
+	ErrorFileID  string `json:"error_file_id,omitempty"`
+
+	// Error describes the failure that stopped a LocalBatcher batch, such as
+	// being unable to persist its results; empty otherwise.
+	Error string `json:"error,omitempty"`
 }
 
 // BatchResult represents a single result from a batch.
@@ -132,40 +145,41 @@ type ListBatchesResponse struct {
 }
 
 // CreateBatch creates a new message batch.
+// Anthropic clients use the Message Batches API directly; any other client is
+// treated as OpenAI-compatible and the requests are uploaded as a JSONL file
+// and batched against /v1/chat/completions.
 func (c *Client) CreateBatch(req CreateBatchRequest) (*Batch, error) {
-	resp, err := c.prepareRequest(req, c.anthropicEndpoint("/messages/batches"))
-	if err != nil {
-		return nil, err
+	if c.usesOpenAIBatches() {
+		return c.createOpenAIBatch(req)
 	}
-	defer resp.Body.Close()
 
-	var batch Batch
-	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
-		return nil, fmt.Errorf("error decoding batch response: %w", err)
+	call, err := newCall("anthropic", "batch.create", http.MethodPost, c.anthropicEndpoint("/messages/batches"), nil, req)
+	if err != nil {
+		return nil, err
 	}
-
-	return &batch, nil
+	return do(context.Background(), c, call, decodeBatch)
 }
 
 // GetBatch retrieves the status and details of a specific batch.
 func (c *Client) GetBatch(batchID string) (*Batch, error) {
-	resp, err := c.prepareGet(c.anthropicEndpoint(fmt.Sprintf("/messages/batches/%s", batchID)))
-	if err != nil {
-		return nil, err
+	if c.usesOpenAIBatches() {
+		return c.getOpenAIBatch(batchID)
 	}
-	defer resp.Body.Close()
 
-	var batch Batch
-	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
-		return nil, fmt.Errorf("error decoding batch response: %w", err)
+	call, err := newCall("anthropic", "batch.get", http.MethodGet, c.anthropicEndpoint(fmt.Sprintf("/messages/batches/%s", batchID)), nil, nil)
+	if err != nil {
+		return nil, err
 	}
-
-	return &batch, nil
+	return do(context.Background(), c, call, decodeBatch)
 }
 
 // ListBatches lists all message batches with optional pagination.
 // Set limit to 0 to use the API default. Use beforeID or afterID for pagination.
 func (c *Client) ListBatches(limit int, beforeID, afterID string) (*ListBatchesResponse, error) {
+	if c.usesOpenAIBatches() {
+		return c.listOpenAIBatches(limit, beforeID, afterID)
+	}
+
 	endpoint := c.anthropicEndpoint("/messages/batches")
 
 	q := url.Values{}
@@ -182,28 +196,35 @@ func (c *Client) ListBatches(limit int, beforeID, afterID string) (*ListBatchesR
 		endpoint += "?" + encoded
 	}
 
-	resp, err := c.prepareGet(endpoint)
+	call, err := newCall("anthropic", "batch.list", http.MethodGet, endpoint, nil, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	var listResp ListBatchesResponse
-	if err := json.NewDecoder(resp.Body).Decode(&listResp); err != nil {
-		return nil, fmt.Errorf("error decoding list batches response: %w", err)
-	}
+	return do(context.Background(), c, call, func(resp *http.Response) (*ListBatchesResponse, error) {
+		var listResp ListBatchesResponse
+		if err := json.NewDecoder(resp.Body).Decode(&listResp); err != nil {
+			return nil, fmt.Errorf("error decoding list batches response: %w", err)
+		}
 
-	return &listResp, nil
+		return &listResp, nil
+	})
 }
 
 // CancelBatch cancels a message batch that is currently processing.
 func (c *Client) CancelBatch(batchID string) (*Batch, error) {
-	resp, err := c.prepareRequest(nil, c.anthropicEndpoint(fmt.Sprintf("/messages/batches/%s/cancel", batchID)))
+	if c.usesOpenAIBatches() {
+		return c.cancelOpenAIBatch(batchID)
+	}
+
+	call, err := newCall("anthropic", "batch.cancel", http.MethodPost, c.anthropicEndpoint(fmt.Sprintf("/messages/batches/%s/cancel", batchID)), nil, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	return do(context.Background(), c, call, decodeBatch)
+}
 
+// decodeBatch decodes a Message Batches API batch object from resp.
+func decodeBatch(resp *http.Response) (*Batch, error) {
 	var batch Batch
 	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
 		return nil, fmt.Errorf("error decoding batch response: %w", err)
@@ -214,26 +235,31 @@ func (c *Client) CancelBatch(batchID string) (*Batch, error) {
 
 // GetBatchResults retrieves the results of a completed batch.
 // Returns a slice of BatchResult parsed from the JSONL format response.
+// For OpenAI batches, both the output and error files are downloaded.
 func (c *Client) GetBatchResults(batchID string) ([]BatchResult, error) {
-	resp, err := c.prepareGet(c.anthropicEndpoint(fmt.Sprintf("/messages/batches/%s/results", batchID)))
+	if c.usesOpenAIBatches() {
+		return c.getOpenAIBatchResults(batchID)
+	}
+
+	call, err := newCall("anthropic", "batch.results", http.MethodGet, c.anthropicEndpoint(fmt.Sprintf("/messages/batches/%s/results", batchID)), nil, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	// Read the JSONL response
-	var results []BatchResult
-	decoder := json.NewDecoder(resp.Body)
-	lineNum := 0
-	for decoder.More() {
-		lineNum++
-		var result BatchResult
-		if err := decoder.Decode(&result); err != nil {
-			return nil, fmt.Errorf("error decoding batch result at line %d: %w", lineNum, err)
+	return do(context.Background(), c, call, func(resp *http.Response) ([]BatchResult, error) {
+		// Read the JSONL response
+		var results []BatchResult
+		decoder := json.NewDecoder(resp.Body)
+		lineNum := 0
+		for decoder.More() {
+			lineNum++
+			var result BatchResult
+			if err := decoder.Decode(&result); err != nil {
+				return nil, fmt.Errorf("error decoding batch result at line %d: %w", lineNum, err)
+			}
+
+			results = append(results, result)
 		}
 
-		results = append(results, result)
-	}
-
-	return results, nil
+		return results, nil
+	})
 }
@@ -0,0 +1,561 @@
+package gollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ============== Local batch emulation ==============
+// Backends such as Ollama, llama.cpp and vLLM have no batch endpoint.
+// LocalBatcher runs the same CreateBatchRequest workloads in-process through a
+// bounded worker pool and exposes them with the Batch / BatchResult types, so
+// pipelines can switch between a provider batch API and local execution.
+
+// Batcher is the batch interface shared by Client (Anthropic and OpenAI batch
+// APIs) and LocalBatcher.
+type Batcher interface {
+	CreateBatch(req CreateBatchRequest) (*Batch, error)
+	GetBatch(batchID string) (*Batch, error)
+	GetBatchResults(batchID string) ([]BatchResult, error)
+	CancelBatch(batchID string) (*Batch, error)
+}
+
+var (
+	_ Batcher = (*Client)(nil)
+	_ Batcher = (*LocalBatcher)(nil)
+)
+
+// Files written for each batch under LocalBatcher's directory.
+const (
+	localBatchStateFile    = "batch.json"
+	localBatchRequestsFile = "requests.jsonl"
+	localBatchResultsFile  = "results.jsonl"
+)
+
+// LocalBatcher executes batches locally against a Client using a bounded
+// worker pool. Each batch is persisted under dir/<batch id>/: the requests,
+// the current Batch state, and one JSONL result line per finished request, so
+// progress survives a restart and can be continued with ResumeBatch.
+type LocalBatcher struct {
+	client  *Client
+	dir     string
+	workers int
+
+	mu      sync.Mutex
+	running map[string]*localBatch
+}
+
+// localBatch tracks a batch that is being processed by this LocalBatcher.
+type localBatch struct {
+	mu      sync.Mutex
+	batch   Batch
+	results *os.File
+	cancel  context.CancelFunc
+	done    chan struct{}
+	err     error // first error persisting the batch; see fail
+}
+
+// fail records err, the first error persisting the batch, in its state and
+// stops processing. The requests this leaves unfinished are not recorded as
+// canceled, so ResumeBatch runs them again along with any whose results
+// could not be written. b.mu must be held.
+func (b *localBatch) fail(err error) {
+	if b.err != nil {
+		return
+	}
+	b.err = err
+	b.batch.Error = err.Error()
+	b.cancel()
+}
+
+// NewLocalBatcher creates a LocalBatcher that sends requests through client
+// with at most workers concurrent requests, persisting state under dir.
+// A workers value below 1 is treated as 1.
+func NewLocalBatcher(client *Client, dir string, workers int) (*LocalBatcher, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating batch directory: %w", err)
+	}
+	return &LocalBatcher{
+		client:  client,
+		dir:     dir,
+		workers: workers,
+		running: make(map[string]*localBatch),
+	}, nil
+}
+
+// CreateBatch persists the requests and starts processing them in the background.
+func (lb *LocalBatcher) CreateBatch(req CreateBatchRequest) (*Batch, error) {
+	id, err := newLocalBatchID()
+	if err != nil {
+		return nil, err
+	}
+	batchDir := filepath.Join(lb.dir, id)
+	if err := os.MkdirAll(batchDir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating batch directory: %w", err)
+	}
+
+	if err := writeJSONL(filepath.Join(batchDir, localBatchRequestsFile), req.Requests); err != nil {
+		return nil, err
+	}
+
+	batch := Batch{
+		ID:               id,
+		Type:             "message_batch",
+		ProcessingStatus: "in_progress",
+		RequestCounts:    BatchRequestCounts{Processing: len(req.Requests)},
+		CreatedAt:        time.Now().UTC().Format(time.RFC3339),
+	}
+	if err := lb.saveState(&batch); err != nil {
+		return nil, err
+	}
+
+	if err := lb.start(batch, req.Requests, nil); err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// ResumeBatch restarts processing of a persisted batch that did not finish,
+// e.g. because the process exited or its results could not be written.
+// Requests that already have a result are skipped.
+func (lb *LocalBatcher) ResumeBatch(batchID string) (*Batch, error) {
+	lb.mu.Lock()
+	b, running := lb.running[batchID]
+	lb.mu.Unlock()
+	if running {
+		select {
+		case <-b.done:
+			// Failed, and kept only because its final state was not saved.
+			lb.mu.Lock()
+			delete(lb.running, batchID)
+			lb.mu.Unlock()
+		default:
+			return nil, fmt.Errorf("batch %s is already running", batchID)
+		}
+	}
+
+	batch, err := lb.loadState(batchID)
+	if err != nil {
+		return nil, err
+	}
+	if batch.ProcessingStatus == "ended" && batch.Error == "" {
+		return batch, nil
+	}
+
+	var requests []BatchRequest
+	if err := readJSONL(filepath.Join(lb.dir, batchID, localBatchRequestsFile), &requests); err != nil {
+		return nil, err
+	}
+	resultsPath := filepath.Join(lb.dir, batchID, localBatchResultsFile)
+	if err := trimPartialLine(resultsPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	var results []BatchResult
+	if err := readJSONL(resultsPath, &results); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	finished := make(map[string]bool, len(results))
+	counts := BatchRequestCounts{}
+	for _, r := range results {
+		finished[r.CustomID] = true
+		countResult(&counts, r.Result.Type)
+	}
+	counts.Processing = len(requests) - len(results)
+	batch.RequestCounts = counts
+	batch.ProcessingStatus = "in_progress"
+	batch.CancelInitiatedAt = nil
+	batch.EndedAt = nil
+	batch.Error = ""
+
+	if err := lb.start(*batch, requests, finished); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// GetBatch returns the current state of a batch.
+func (lb *LocalBatcher) GetBatch(batchID string) (*Batch, error) {
+	lb.mu.Lock()
+	b, ok := lb.running[batchID]
+	lb.mu.Unlock()
+	if ok {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		batch := b.batch
+		return &batch, nil
+	}
+	return lb.loadState(batchID)
+}
+
+// GetBatchResults returns the results recorded so far, in completion order.
+// Unlike the provider APIs it may be called before the batch has ended.
+func (lb *LocalBatcher) GetBatchResults(batchID string) ([]BatchResult, error) {
+	lb.mu.Lock()
+	b, ok := lb.running[batchID]
+	lb.mu.Unlock()
+	if ok {
+		// Hold the batch lock so a result line is never read half-written.
+		b.mu.Lock()
+		defer b.mu.Unlock()
+	} else if _, err := lb.loadState(batchID); err != nil {
+		return nil, err
+	}
+
+	var results []BatchResult
+	err := readJSONL(filepath.Join(lb.dir, batchID, localBatchResultsFile), &results)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return results, nil
+}
+
+// CancelBatch stops a running batch. In-flight requests are aborted and every
+// unfinished request is recorded with result type "canceled".
+func (lb *LocalBatcher) CancelBatch(batchID string) (*Batch, error) {
+	lb.mu.Lock()
+	b, ok := lb.running[batchID]
+	lb.mu.Unlock()
+	if !ok {
+		return lb.GetBatch(batchID)
+	}
+
+	b.mu.Lock()
+	if b.batch.CancelInitiatedAt == nil {
+		now := time.Now().UTC().Format(time.RFC3339)
+		b.batch.CancelInitiatedAt = &now
+		b.batch.ProcessingStatus = "canceling"
+	}
+	batch := b.batch
+	err := lb.saveState(&batch)
+	b.mu.Unlock()
+
+	b.cancel()
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// Wait blocks until the batch finishes processing or ctx is done, and returns
+// the final batch state. If the batch failed, the state is returned with an
+// error carrying Batch.Error.
+func (lb *LocalBatcher) Wait(ctx context.Context, batchID string) (*Batch, error) {
+	lb.mu.Lock()
+	b, ok := lb.running[batchID]
+	lb.mu.Unlock()
+	if ok {
+		select {
+		case <-b.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	batch, err := lb.GetBatch(batchID)
+	if err != nil {
+		return nil, err
+	}
+	if batch.Error != "" {
+		return batch, fmt.Errorf("batch %s failed: %s", batchID, batch.Error)
+	}
+	return batch, nil
+}
+
+// start launches the worker pool for batch. Requests whose custom ID is in
+// skip already have results and are not sent again.
+func (lb *LocalBatcher) start(batch Batch, requests []BatchRequest, skip map[string]bool) error {
+	resultsPath := filepath.Join(lb.dir, batch.ID, localBatchResultsFile)
+	f, err := os.OpenFile(resultsPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("error opening batch results: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &localBatch{
+		batch:   batch,
+		results: f,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	lb.mu.Lock()
+	lb.running[batch.ID] = b
+	lb.mu.Unlock()
+
+	work := make(chan BatchRequest)
+	var wg sync.WaitGroup
+	for i := 0; i < lb.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range work {
+				lb.record(b, lb.run(ctx, r))
+			}
+		}()
+	}
+
+	go func() {
+		defer close(b.done)
+		for _, r := range requests {
+			if skip[r.CustomID] {
+				continue
+			}
+			select {
+			case work <- r:
+			case <-ctx.Done():
+				lb.record(b, BatchResult{CustomID: r.CustomID, Result: BatchResultDetail{Type: "canceled"}})
+			}
+		}
+		close(work)
+		wg.Wait()
+		cancel()
+		lb.finish(b)
+	}()
+
+	return nil
+}
+
+// run executes a single batch request and converts the outcome into a BatchResult.
+func (lb *LocalBatcher) run(ctx context.Context, r BatchRequest) BatchResult {
+	res := BatchResult{CustomID: r.CustomID}
+	if ctx.Err() != nil {
+		res.Result.Type = "canceled"
+		return res
+	}
+
+	resp, err := lb.client.TurnContext(ctx, r.Params.requestOptions())
+	switch {
+	case err != nil && ctx.Err() != nil:
+		res.Result.Type = "canceled"
+	case err != nil:
+		res.Result = BatchResultDetail{
+			Type:  "errored",
+			Error: &BatchError{Type: "error", Message: err.Error()},
+		}
+	default:
+		res.Result = BatchResultDetail{
+			Type:    "succeeded",
+			Message: batchMessageFromResponse("", resp),
+		}
+	}
+	return res
+}
+
+// record appends a result to the batch's results file and updates its counts.
+func (lb *LocalBatcher) record(b *localBatch, res BatchResult) {
+	line, err := json.Marshal(res)
+	if err != nil {
+		line, _ = json.Marshal(BatchResult{
+			CustomID: res.CustomID,
+			Result: BatchResultDetail{
+				Type:  "errored",
+				Error: &BatchError{Type: "error", Message: fmt.Sprintf("error encoding result: %v", err)},
+			},
+		})
+		res.Result.Type = "errored"
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if res.Result.Type == "canceled" && b.err != nil && b.batch.CancelInitiatedAt == nil {
+		// Stopped by fail rather than CancelBatch: leave it for ResumeBatch.
+		return
+	}
+	if _, err := b.results.Write(append(line, '\n')); err != nil {
+		b.fail(fmt.Errorf("error writing batch results: %w", err))
+	}
+	b.batch.RequestCounts.Processing--
+	countResult(&b.batch.RequestCounts, res.Result.Type)
+	if err := lb.saveState(&b.batch); err != nil {
+		b.fail(err)
+	}
+}
+
+// finish marks the batch as ended and stops tracking it. If the final state
+// cannot be saved the batch stays tracked, so GetBatch keeps reporting the
+// in-memory state rather than the stale one on disk.
+func (lb *LocalBatcher) finish(b *localBatch) {
+	b.mu.Lock()
+	now := time.Now().UTC().Format(time.RFC3339)
+	b.batch.ProcessingStatus = "ended"
+	b.batch.EndedAt = &now
+	if err := b.results.Close(); err != nil {
+		b.fail(fmt.Errorf("error writing batch results: %w", err))
+	}
+	saveErr := lb.saveState(&b.batch)
+	if saveErr != nil {
+		b.fail(saveErr)
+	}
+	b.mu.Unlock()
+
+	if saveErr == nil {
+		lb.mu.Lock()
+		delete(lb.running, b.batch.ID)
+		lb.mu.Unlock()
+	}
+}
+
+// saveState atomically writes the batch state file.
+func (lb *LocalBatcher) saveState(batch *Batch) error {
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("error encoding batch state: %w", err)
+	}
+	path := filepath.Join(lb.dir, batch.ID, localBatchStateFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error writing batch state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("error writing batch state: %w", err)
+	}
+	return nil
+}
+
+// loadState reads a persisted batch state file.
+func (lb *LocalBatcher) loadState(batchID string) (*Batch, error) {
+	if batchID == "" || filepath.Base(batchID) != batchID {
+		return nil, fmt.Errorf("invalid batch id %q", batchID)
+	}
+	data, err := os.ReadFile(filepath.Join(lb.dir, batchID, localBatchStateFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no such batch %q", batchID)
+		}
+		return nil, fmt.Errorf("error reading batch state: %w", err)
+	}
+	var batch Batch
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return nil, fmt.Errorf("error decoding batch state: %w", err)
+	}
+	return &batch, nil
+}
+
+// requestOptions converts batch request parameters into RequestOptions for Turn.
+// Messages are normalized for the target backend by the request builders, so
+// UseAnthropicFormat is cleared.
+func (p BatchRequestParams) requestOptions() RequestOptions {
+	messages := make([]Message, len(p.Messages))
+	for i, m := range p.Messages {
+		m.UseAnthropicFormat = false
+		messages[i] = m
+	}
+	return RequestOptions{
+		Model:    p.Model,
+		System:   p.System,
+		Messages: messages,
+		Options: &Options{
+			Temperature: p.Temperature,
+			TopP:        p.TopP,
+			TopK:        p.TopK,
+			MaxTokens:   p.MaxTokens,
+		},
+	}
+}
+
+// countResult increments the request count matching a result type.
+func countResult(counts *BatchRequestCounts, resultType string) {
+	switch resultType {
+	case "succeeded":
+		counts.Succeeded++
+	case "errored":
+		counts.Errored++
+	case "canceled":
+		counts.Canceled++
+	case "expired":
+		counts.Expired++
+	}
+}
+
+// newLocalBatchID returns a random batch identifier.
+func newLocalBatchID() (string, error) {
+	var b [12]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("error generating batch id: %w", err)
+	}
+	return "localbatch_" + hex.EncodeToString(b[:]), nil
+}
+
+// writeJSONL writes each element of items as a line of JSON to path.
+func writeJSONL[T any](path string, items []T) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", filepath.Base(path), err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, item := range items {
+		if err := enc.Encode(item); err != nil {
+			f.Close()
+			return fmt.Errorf("error encoding %s: %w", filepath.Base(path), err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("error writing %s: %w", filepath.Base(path), err)
+	}
+	return f.Close()
+}
+
+// readJSONL decodes every line of path into out. A final line without a
+// newline that does not decode is taken to be a write cut short by a crash
+// and ignored.
+func readJSONL[T any](path string, out *[]T) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for lineNum := 1; ; lineNum++ {
+		line, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("error reading %s: %w", filepath.Base(path), err)
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			var item T
+			if uerr := json.Unmarshal(line, &item); uerr != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return fmt.Errorf("error decoding %s at line %d: %w", filepath.Base(path), lineNum, uerr)
+			}
+			*out = append(*out, item)
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// trimPartialLine truncates path after its last newline, dropping a line
+// whose write was cut short so that lines appended later stay intact.
+func trimPartialLine(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 || data[len(data)-1] == '\n' {
+		return nil
+	}
+	if err := os.Truncate(path, int64(bytes.LastIndexByte(data, '\n')+1)); err != nil {
+		return fmt.Errorf("error truncating %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,339 @@
+package gollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// echoCompletionServer answers /chat/completions with the last user message
+// echoed back, failing any request whose model is "bad".
+func echoCompletionServer(t *testing.T, block <-chan struct{}) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openaiRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if block != nil {
+			select {
+			case <-block:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if req.Model == "bad" {
+			http.Error(w, `{"error":{"message":"unknown model"}}`, http.StatusBadRequest)
+			return
+		}
+		last := req.Messages[len(req.Messages)-1].Content
+		fmt.Fprintf(w, `{"model":%q,"choices":[{"index":0,"message":{"role":"assistant","content":%q},"finish_reason":"stop"}],"usage":{"prompt_tokens":2,"completion_tokens":1}}`, req.Model, "echo: "+last)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestLocalBatcher(t *testing.T) {
+	srv := echoCompletionServer(t, nil)
+	lb, err := NewLocalBatcher(NewClient(srv.URL), t.TempDir(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reqs []BatchRequest
+	for i := 0; i < 10; i++ {
+		model := "m"
+		if i == 4 {
+			model = "bad"
+		}
+		reqs = append(reqs, BatchRequest{
+			CustomID: fmt.Sprintf("req-%d", i),
+			Params:   BatchRequestParams{Model: model, MaxTokens: 10, Messages: []Message{{Role: "user", Content: fmt.Sprint(i)}}},
+		})
+	}
+	batch, err := lb.CreateBatch(CreateBatchRequest{Requests: reqs})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	final, err := lb.Wait(ctx, batch.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final.ProcessingStatus != "ended" || final.EndedAt == nil {
+		t.Fatalf("batch not ended: %+v", final)
+	}
+	if c := final.RequestCounts; c.Succeeded != 9 || c.Errored != 1 || c.Processing != 0 {
+		t.Fatalf("counts = %+v", c)
+	}
+
+	results, err := lb.GetBatchResults(batch.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 10 {
+		t.Fatalf("got %d results, want 10", len(results))
+	}
+	for _, r := range results {
+		switch {
+		case r.CustomID == "req-4":
+			if r.Result.Type != "errored" || r.Result.Error == nil {
+				t.Errorf("req-4 = %+v, want errored", r.Result)
+			}
+		case r.Result.Type != "succeeded":
+			t.Errorf("%s = %+v, want succeeded", r.CustomID, r.Result)
+		case r.Result.Message.Content[0].Text != "echo: "+r.CustomID[len("req-"):]:
+			t.Errorf("%s content = %q", r.CustomID, r.Result.Message.Content[0].Text)
+		}
+	}
+
+	// A fresh batcher over the same directory sees the persisted state.
+	lb2, _ := NewLocalBatcher(NewClient(srv.URL), lb.dir, 1)
+	persisted, err := lb2.GetBatch(batch.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if persisted.RequestCounts != final.RequestCounts {
+		t.Errorf("persisted counts = %+v, want %+v", persisted.RequestCounts, final.RequestCounts)
+	}
+}
+
+func TestLocalBatcherCancel(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	srv := echoCompletionServer(t, block)
+	lb, err := NewLocalBatcher(NewClient(srv.URL), t.TempDir(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reqs []BatchRequest
+	for i := 0; i < 5; i++ {
+		reqs = append(reqs, BatchRequest{
+			CustomID: fmt.Sprintf("req-%d", i),
+			Params:   BatchRequestParams{Model: "m", Messages: []Message{{Role: "user", Content: "hi"}}},
+		})
+	}
+	batch, err := lb.CreateBatch(CreateBatchRequest{Requests: reqs})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	canceling, err := lb.CancelBatch(batch.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canceling.CancelInitiatedAt == nil {
+		t.Errorf("cancel_initiated_at not set: %+v", canceling)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	final, err := lb.Wait(ctx, batch.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final.ProcessingStatus != "ended" || final.RequestCounts.Canceled != 5 {
+		t.Fatalf("final = %+v", final)
+	}
+
+}
+
+// TestLocalBatcherResume simulates a batch interrupted mid-way and checks that
+// ResumeBatch only sends the requests that have no recorded result.
+func TestLocalBatcherResume(t *testing.T) {
+	var calls atomic.Int32
+	srv := echoCompletionServer(t, nil)
+	counting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		srv.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer counting.Close()
+
+	lb, err := NewLocalBatcher(NewClient(counting.URL), t.TempDir(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const id = "localbatch_interrupted"
+	var reqs []BatchRequest
+	for i := 0; i < 4; i++ {
+		reqs = append(reqs, BatchRequest{
+			CustomID: fmt.Sprintf("req-%d", i),
+			Params:   BatchRequestParams{Model: "m", Messages: []Message{{Role: "user", Content: "hi"}}},
+		})
+	}
+	dir := filepath.Join(lb.dir, id)
+	os.MkdirAll(dir, 0o755)
+	if err := writeJSONL(filepath.Join(dir, localBatchRequestsFile), reqs); err != nil {
+		t.Fatal(err)
+	}
+	done := []BatchResult{{CustomID: "req-1", Result: BatchResultDetail{Type: "succeeded", Message: &BatchMessageResult{}}}}
+	if err := writeJSONL(filepath.Join(dir, localBatchResultsFile), done); err != nil {
+		t.Fatal(err)
+	}
+	if err := lb.saveState(&Batch{ID: id, ProcessingStatus: "in_progress"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := lb.ResumeBatch(id); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	final, err := lb.Wait(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("sent %d requests, want 3", calls.Load())
+	}
+	if final.RequestCounts.Succeeded != 4 || final.RequestCounts.Processing != 0 {
+		t.Errorf("counts = %+v", final.RequestCounts)
+	}
+	results, _ := lb.GetBatchResults(id)
+	if len(results) != 4 {
+		t.Errorf("got %d results, want 4", len(results))
+	}
+}
+
+// TestLocalBatcherPersistFailure checks that a results write error fails the
+// batch instead of silently dropping the result, and that the batch can be
+// resumed afterwards.
+func TestLocalBatcherPersistFailure(t *testing.T) {
+	block := make(chan struct{})
+	srv := echoCompletionServer(t, block)
+	lb, err := NewLocalBatcher(NewClient(srv.URL), t.TempDir(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reqs []BatchRequest
+	for i := 0; i < 3; i++ {
+		reqs = append(reqs, BatchRequest{
+			CustomID: fmt.Sprintf("req-%d", i),
+			Params:   BatchRequestParams{Model: "m", Messages: []Message{{Role: "user", Content: "hi"}}},
+		})
+	}
+	batch, err := lb.CreateBatch(CreateBatchRequest{Requests: reqs})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Break the results file before the first result is written.
+	lb.mu.Lock()
+	b := lb.running[batch.ID]
+	lb.mu.Unlock()
+	b.mu.Lock()
+	b.results.Close()
+	b.mu.Unlock()
+	close(block)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	final, err := lb.Wait(ctx, batch.ID)
+	if err == nil {
+		t.Fatal("Wait succeeded despite the write error")
+	}
+	if final.ProcessingStatus != "ended" || !strings.Contains(final.Error, "error writing batch results") {
+		t.Fatalf("final = %+v", final)
+	}
+	persisted, err := lb.loadState(batch.ID)
+	if err != nil || persisted.Error == "" {
+		t.Fatalf("persisted = %+v, %v", persisted, err)
+	}
+
+	// Nothing reached the results file, so resuming runs every request.
+	if _, err := lb.ResumeBatch(batch.ID); err != nil {
+		t.Fatal(err)
+	}
+	final, err = lb.Wait(ctx, batch.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final.Error != "" || final.RequestCounts.Succeeded != 3 {
+		t.Fatalf("resumed = %+v", final)
+	}
+}
+
+// TestLocalBatcherStateFailure checks that requests stopped by a state write
+// error are not recorded as canceled, so resuming runs them, and that a
+// result line cut short does not block the resume.
+func TestLocalBatcherStateFailure(t *testing.T) {
+	block := make(chan struct{})
+	srv := echoCompletionServer(t, block)
+	dir := t.TempDir()
+	lb, err := NewLocalBatcher(NewClient(srv.URL), dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reqs []BatchRequest
+	for i := 0; i < 3; i++ {
+		reqs = append(reqs, BatchRequest{
+			CustomID: fmt.Sprintf("req-%d", i),
+			Params:   BatchRequestParams{Model: "m", Messages: []Message{{Role: "user", Content: "hi"}}},
+		})
+	}
+	batch, err := lb.CreateBatch(CreateBatchRequest{Requests: reqs})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A directory in the way of the temporary state file makes saveState fail.
+	tmp := filepath.Join(dir, batch.ID, localBatchStateFile+".tmp")
+	if err := os.Mkdir(tmp, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	close(block)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	final, err := lb.Wait(ctx, batch.ID)
+	if err == nil || !strings.Contains(final.Error, "error writing batch state") {
+		t.Fatalf("final = %+v, %v", final, err)
+	}
+	results, err := lb.GetBatchResults(batch.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Result.Type != "succeeded" || final.RequestCounts.Canceled != 0 {
+		t.Fatalf("results = %+v, counts = %+v", results, final.RequestCounts)
+	}
+
+	// Simulate a crash in the middle of writing the next result.
+	f, err := os.OpenFile(filepath.Join(dir, batch.ID, localBatchResultsFile), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"custom_id":"req-1","res`)
+	f.Close()
+	if results, err := lb.GetBatchResults(batch.ID); err != nil || len(results) != 1 {
+		t.Fatalf("results = %+v, %v", results, err)
+	}
+
+	if err := os.Remove(tmp); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lb.ResumeBatch(batch.ID); err != nil {
+		t.Fatal(err)
+	}
+	final, err = lb.Wait(ctx, batch.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final.RequestCounts.Succeeded != 3 || final.RequestCounts.Canceled != 0 {
+		t.Fatalf("resumed = %+v", final)
+	}
+	if results, err := lb.GetBatchResults(batch.ID); err != nil || len(results) != 3 {
+		t.Fatalf("results = %+v, %v", results, err)
+	}
+}
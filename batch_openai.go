@@ -0,0 +1,475 @@
+package gollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ============== OpenAI Batch API ==============
+// OpenAI-compatible batches are file based: the requests are uploaded as a
+// JSONL file to /files, a batch is created against /v1/chat/completions, and
+// the results are downloaded from the batch's output and error files. The
+// methods in batch.go dispatch here for non-Anthropic clients and translate
+// to and from the shared Batch / BatchResult types.
+
+// openaiBatchEndpoint is the endpoint every request in an uploaded batch targets.
+const openaiBatchEndpoint = "/v1/chat/completions"
+
+// openaiFile is the response from uploading a file to /files.
+type openaiFile struct {
+	ID       string `json:"id"`
+	Object   string `json:"object"`
+	Bytes    int    `json:"bytes"`
+	Filename string `json:"filename"`
+	Purpose  string `json:"purpose"`
+}
+
+// openaiBatchLine is a single line of the JSONL input file.
+type openaiBatchLine struct {
+	CustomID string        `json:"custom_id"`
+	Method   string        `json:"method"`
+	URL      string        `json:"url"`
+	Body     openaiRequest `json:"body"`
+}
+
+// openaiCreateBatchRequest is the body sent to /batches.
+type openaiCreateBatchRequest struct {
+	InputFileID      string `json:"input_file_id"`
+	Endpoint         string `json:"endpoint"`
+	CompletionWindow string `json:"completion_window"`
+}
+
+// openaiBatch is the OpenAI batch object. Timestamps are unix seconds.
+type openaiBatch struct {
+	ID            string `json:"id"`
+	Object        string `json:"object"`
+	Endpoint      string `json:"endpoint"`
+	Status        string `json:"status"`
+	InputFileID   string `json:"input_file_id"`
+	OutputFileID  string `json:"output_file_id"`
+	ErrorFileID   string `json:"error_file_id"`
+	CreatedAt     int64  `json:"created_at"`
+	ExpiresAt     int64  `json:"expires_at"`
+	CompletedAt   int64  `json:"completed_at"`
+	FailedAt      int64  `json:"failed_at"`
+	ExpiredAt     int64  `json:"expired_at"`
+	CancellingAt  int64  `json:"cancelling_at"`
+	CancelledAt   int64  `json:"cancelled_at"`
+	RequestCounts struct {
+		Total     int `json:"total"`
+		Completed int `json:"completed"`
+		Failed    int `json:"failed"`
+	} `json:"request_counts"`
+}
+
+// openaiListBatchesResponse is the response from GET /batches.
+type openaiListBatchesResponse struct {
+	Data    []openaiBatch `json:"data"`
+	HasMore bool          `json:"has_more"`
+	FirstID *string       `json:"first_id,omitempty"`
+	LastID  *string       `json:"last_id,omitempty"`
+}
+
+// openaiBatchOutputLine is a single line of a batch output or error file.
+type openaiBatchOutputLine struct {
+	ID       string `json:"id"`
+	CustomID string `json:"custom_id"`
+	Response *struct {
+		StatusCode int             `json:"status_code"`
+		RequestID  string          `json:"request_id"`
+		Body       json.RawMessage `json:"body"`
+	} `json:"response"`
+	Error *openaiError `json:"error"`
+}
+
+// openaiError is the error object used in batch error lines and error response bodies.
+type openaiError struct {
+	Code    string `json:"code"`
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// usesOpenAIBatches reports whether batch calls should use the OpenAI
+// file-based flow rather than Anthropic's Message Batches API.
+func (c *Client) usesOpenAIBatches() bool {
+	return !c.IsAnthropicAPI() && !c.IsBedrockAPI()
+}
+
+// toOpenAIBatchLine converts a provider-neutral BatchRequest into a line of the
+// OpenAI batch input file. The system prompt becomes a leading system message
+// and messages are always marshaled in OpenAI format.
+func toOpenAIBatchLine(r BatchRequest) openaiBatchLine {
+	p := r.Params
+	var messages []Message
+	if p.System != "" {
+		messages = append(messages, Message{Role: "system", Content: p.System})
+	}
+	for _, m := range p.Messages {
+		m.UseAnthropicFormat = false
+		messages = append(messages, m)
+	}
+
+	body := openaiRequest{
+		Model:     p.Model,
+		Messages:  messages,
+		MaxTokens: p.MaxTokens,
+		Stop:      p.StopSequences,
+	}
+	if p.Temperature != 0 {
+		t := p.Temperature
+		body.Temperature = &t
+	}
+	if p.TopP != 0 {
+		tp := p.TopP
+		body.TopP = &tp
+	}
+
+	return openaiBatchLine{
+		CustomID: r.CustomID,
+		Method:   "POST",
+		URL:      openaiBatchEndpoint,
+		Body:     body,
+	}
+}
+
+// toBatch converts an OpenAI batch object into the shared Batch type.
+// OpenAI's statuses are collapsed onto Anthropic's processing_status values:
+// "in_progress", "canceling" and "ended".
+func (ob *openaiBatch) toBatch() *Batch {
+	b := &Batch{
+		ID:           ob.ID,
+		Type:         "message_batch",
+		CreatedAt:    unixToRFC3339(ob.CreatedAt),
+		ExpiresAt:    unixToRFC3339(ob.ExpiresAt),
+		InputFileID:  ob.InputFileID,
+		OutputFileID: ob.OutputFileID,
+		ErrorFileID:  ob.ErrorFileID,
+	}
+
+	counts := ob.RequestCounts
+	remaining := counts.Total - counts.Completed - counts.Failed
+	if remaining < 0 {
+		remaining = 0
+	}
+	b.RequestCounts = BatchRequestCounts{
+		Succeeded: counts.Completed,
+		Errored:   counts.Failed,
+	}
+
+	switch ob.Status {
+	case "cancelling":
+		b.ProcessingStatus = "canceling"
+		b.RequestCounts.Processing = remaining
+	case "completed", "failed", "expired", "cancelled":
+		b.ProcessingStatus = "ended"
+		switch ob.Status {
+		case "expired":
+			b.RequestCounts.Expired = remaining
+		case "cancelled":
+			b.RequestCounts.Canceled = remaining
+		case "failed":
+			b.RequestCounts.Errored += remaining
+		}
+	default: // validating, in_progress, finalizing
+		b.ProcessingStatus = "in_progress"
+		b.RequestCounts.Processing = remaining
+	}
+
+	for _, ts := range []int64{ob.CompletedAt, ob.FailedAt, ob.ExpiredAt, ob.CancelledAt} {
+		if ts != 0 {
+			s := unixToRFC3339(ts)
+			b.EndedAt = &s
+			break
+		}
+	}
+	if ob.CancellingAt != 0 {
+		s := unixToRFC3339(ob.CancellingAt)
+		b.CancelInitiatedAt = &s
+	}
+
+	return b
+}
+
+// unixToRFC3339 formats a unix timestamp in seconds; zero yields "".
+func unixToRFC3339(ts int64) string {
+	if ts == 0 {
+		return ""
+	}
+	return time.Unix(ts, 0).UTC().Format(time.RFC3339)
+}
+
+// toBatchResult converts a line from an OpenAI output or error file into the
+// shared BatchResult type.
+func (l *openaiBatchOutputLine) toBatchResult() BatchResult {
+	res := BatchResult{CustomID: l.CustomID}
+
+	if l.Error != nil {
+		res.Result = BatchResultDetail{
+			Type: "errored",
+			Error: &BatchError{
+				Type:    "error",
+				Message: l.Error.Message,
+				Error:   &NestedBatchError{Type: l.Error.Code, Message: l.Error.Message},
+			},
+		}
+		return res
+	}
+	if l.Response == nil {
+		res.Result = BatchResultDetail{
+			Type:  "errored",
+			Error: &BatchError{Type: "error", Message: "batch result has neither response nor error"},
+		}
+		return res
+	}
+
+	if l.Response.StatusCode != http.StatusOK {
+		var body struct {
+			Error openaiError `json:"error"`
+		}
+		_ = json.Unmarshal(l.Response.Body, &body)
+		msg := body.Error.Message
+		if msg == "" {
+			msg = string(l.Response.Body)
+		}
+		res.Result = BatchResultDetail{
+			Type: "errored",
+			Error: &BatchError{
+				Type:    "error",
+				Message: fmt.Sprintf("request returned status %d", l.Response.StatusCode),
+				Error:   &NestedBatchError{Type: body.Error.Type, Message: msg},
+			},
+		}
+		return res
+	}
+
+	var body struct {
+		ID string `json:"id"`
+		ResponseMessageGenerate
+	}
+	if err := json.Unmarshal(l.Response.Body, &body); err != nil {
+		res.Result = BatchResultDetail{
+			Type:  "errored",
+			Error: &BatchError{Type: "error", Message: fmt.Sprintf("error decoding response body: %v", err)},
+		}
+		return res
+	}
+
+	res.Result = BatchResultDetail{
+		Type:    "succeeded",
+		Message: batchMessageFromResponse(body.ID, &body.ResponseMessageGenerate),
+	}
+	return res
+}
+
+// batchMessageFromResponse converts a normalized chat response into the
+// Anthropic-shaped BatchMessageResult used by BatchResult.
+func batchMessageFromResponse(id string, r *ResponseMessageGenerate) *BatchMessageResult {
+	msg := &BatchMessageResult{
+		ID:         id,
+		Type:       "message",
+		Role:       "assistant",
+		Model:      r.Model,
+		StopReason: r.StopReason,
+		Usage: BatchUsage{
+			InputTokens:  r.Usage.PromptTokens,
+			OutputTokens: r.Usage.CompletionTokens,
+		},
+	}
+	if len(r.Choices) > 0 {
+		choice := r.Choices[0]
+		if msg.StopReason == "" {
+			msg.StopReason = choice.FinishReason
+		}
+		if choice.Message.Role != "" {
+			msg.Role = choice.Message.Role
+		}
+		if choice.Message.Content != "" {
+			msg.Content = append(msg.Content, BatchContentBlock{Type: "text", Text: choice.Message.Content})
+		}
+	}
+	return msg
+}
+
+// uploadFile uploads data as a multipart form to the /files endpoint.
+func (c *Client) uploadFile(purpose, filename string, data []byte) (*openaiFile, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if err := mw.WriteField("purpose", purpose); err != nil {
+		return nil, fmt.Errorf("error writing multipart field: %w", err)
+	}
+	fw, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, fmt.Errorf("error creating multipart file: %w", err)
+	}
+	if _, err := fw.Write(data); err != nil {
+		return nil, fmt.Errorf("error writing multipart file: %w", err)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("error closing multipart writer: %w", err)
+	}
+
+	call := &Call{
+		Provider:  "openai",
+		Operation: "file.upload",
+		Method:    http.MethodPost,
+		Endpoint:  "/files",
+		Body:      buf.Bytes(),
+		Header:    http.Header{"Content-Type": {mw.FormDataContentType()}},
+	}
+	return do(context.Background(), c, call, func(resp *http.Response) (*openaiFile, error) {
+		var f openaiFile
+		if err := json.NewDecoder(resp.Body).Decode(&f); err != nil {
+			return nil, fmt.Errorf("error decoding file upload response: %w", err)
+		}
+		return &f, nil
+	})
+}
+
+// createOpenAIBatch uploads the requests as a JSONL file and creates a batch for it.
+func (c *Client) createOpenAIBatch(req CreateBatchRequest) (*Batch, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range req.Requests {
+		if err := enc.Encode(toOpenAIBatchLine(r)); err != nil {
+			return nil, fmt.Errorf("error encoding batch request %q: %w", r.CustomID, err)
+		}
+	}
+
+	file, err := c.uploadFile("batch", "batch.jsonl", buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("error uploading batch input file: %w", err)
+	}
+
+	call, err := newCall("openai", "batch.create", http.MethodPost, "/batches", nil, openaiCreateBatchRequest{
+		InputFileID:      file.ID,
+		Endpoint:         openaiBatchEndpoint,
+		CompletionWindow: "24h",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return do(context.Background(), c, call, decodeOpenAIBatch)
+}
+
+// getOpenAIBatch retrieves an OpenAI batch by ID.
+func (c *Client) getOpenAIBatch(batchID string) (*Batch, error) {
+	call, err := newCall("openai", "batch.get", http.MethodGet, "/batches/"+url.PathEscape(batchID), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return do(context.Background(), c, call, decodeOpenAIBatch)
+}
+
+// cancelOpenAIBatch requests cancellation of an OpenAI batch.
+func (c *Client) cancelOpenAIBatch(batchID string) (*Batch, error) {
+	call, err := newCall("openai", "batch.cancel", http.MethodPost, "/batches/"+url.PathEscape(batchID)+"/cancel", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return do(context.Background(), c, call, decodeOpenAIBatch)
+}
+
+// listOpenAIBatches lists OpenAI batches. OpenAI only supports forward
+// pagination, so beforeID is rejected.
+func (c *Client) listOpenAIBatches(limit int, beforeID, afterID string) (*ListBatchesResponse, error) {
+	if beforeID != "" {
+		return nil, fmt.Errorf("listing batches before an ID is not supported by the OpenAI batch API")
+	}
+
+	endpoint := "/batches"
+	q := url.Values{}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	if afterID != "" {
+		q.Set("after", afterID)
+	}
+	if encoded := q.Encode(); encoded != "" {
+		endpoint += "?" + encoded
+	}
+
+	call, err := newCall("openai", "batch.list", http.MethodGet, endpoint, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return do(context.Background(), c, call, func(resp *http.Response) (*ListBatchesResponse, error) {
+		var listResp openaiListBatchesResponse
+		if err := json.NewDecoder(resp.Body).Decode(&listResp); err != nil {
+			return nil, fmt.Errorf("error decoding list batches response: %w", err)
+		}
+
+		out := &ListBatchesResponse{
+			HasMore: listResp.HasMore,
+			FirstID: listResp.FirstID,
+			LastID:  listResp.LastID,
+		}
+		for i := range listResp.Data {
+			out.Data = append(out.Data, *listResp.Data[i].toBatch())
+		}
+		return out, nil
+	})
+}
+
+// getOpenAIBatchResults downloads and parses the output and error files of a batch.
+func (c *Client) getOpenAIBatchResults(batchID string) ([]BatchResult, error) {
+	batch, err := c.getOpenAIBatch(batchID)
+	if err != nil {
+		return nil, err
+	}
+	if batch.OutputFileID == "" && batch.ErrorFileID == "" {
+		return nil, fmt.Errorf("batch %s has no results yet (status %s)", batchID, batch.ProcessingStatus)
+	}
+
+	var results []BatchResult
+	for _, fileID := range []string{batch.OutputFileID, batch.ErrorFileID} {
+		if fileID == "" {
+			continue
+		}
+		fileResults, err := c.downloadOpenAIBatchFile(fileID)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, fileResults...)
+	}
+
+	return results, nil
+}
+
+// downloadOpenAIBatchFile fetches a batch output or error file and parses its JSONL lines.
+func (c *Client) downloadOpenAIBatchFile(fileID string) ([]BatchResult, error) {
+	call, err := newCall("openai", "file.content", http.MethodGet, "/files/"+url.PathEscape(fileID)+"/content", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return do(context.Background(), c, call, func(resp *http.Response) ([]BatchResult, error) {
+		var results []BatchResult
+		decoder := json.NewDecoder(resp.Body)
+		lineNum := 0
+		for decoder.More() {
+			lineNum++
+			var line openaiBatchOutputLine
+			if err := decoder.Decode(&line); err != nil {
+				return nil, fmt.Errorf("error decoding batch file %s at line %d: %w", fileID, lineNum, err)
+			}
+			results = append(results, line.toBatchResult())
+		}
+
+		return results, nil
+	})
+}
+
+// decodeOpenAIBatch decodes an OpenAI batch object from resp into the shared Batch type.
+func decodeOpenAIBatch(resp *http.Response) (*Batch, error) {
+	var ob openaiBatch
+	if err := json.NewDecoder(resp.Body).Decode(&ob); err != nil {
+		return nil, fmt.Errorf("error decoding batch response: %w", err)
+	}
+	return ob.toBatch(), nil
+}
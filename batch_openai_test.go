@@ -0,0 +1,105 @@
+package gollama
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestOpenAIBatchFlow drives CreateBatch, GetBatch and GetBatchResults against
+// a fake OpenAI server and checks the uploaded JSONL and the translation of
+// output/error lines into the shared BatchResult shape.
+func TestOpenAIBatchFlow(t *testing.T) {
+	var uploaded string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /files", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("purpose") != "batch" {
+			t.Errorf("purpose = %q, want batch", r.FormValue("purpose"))
+		}
+		f, _, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("form file: %v", err)
+			return
+		}
+		b, _ := io.ReadAll(f)
+		uploaded = string(b)
+		w.Write([]byte(`{"id":"file-in","object":"file","purpose":"batch"}`))
+	})
+	mux.HandleFunc("POST /batches", func(w http.ResponseWriter, r *http.Request) {
+		var req openaiCreateBatchRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.InputFileID != "file-in" || req.Endpoint != "/v1/chat/completions" {
+			t.Errorf("create batch request = %+v", req)
+		}
+		w.Write([]byte(`{"id":"batch_1","status":"validating","input_file_id":"file-in","created_at":1700000000,"request_counts":{"total":2}}`))
+	})
+	mux.HandleFunc("GET /batches/batch_1", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"batch_1","status":"completed","input_file_id":"file-in","output_file_id":"file-out","error_file_id":"file-err",
+			"created_at":1700000000,"completed_at":1700000100,"request_counts":{"total":2,"completed":1,"failed":1}}`))
+	})
+	mux.HandleFunc("GET /files/file-out/content", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"r1","custom_id":"a","response":{"status_code":200,"body":{"id":"chatcmpl-1","model":"gpt-x","choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1}}}}` + "\n"))
+	})
+	mux.HandleFunc("GET /files/file-err/content", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"r2","custom_id":"b","response":{"status_code":400,"body":{"error":{"type":"invalid_request_error","message":"bad model"}}}}` + "\n"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	batch, err := c.CreateBatch(CreateBatchRequest{Requests: []BatchRequest{
+		{CustomID: "a", Params: BatchRequestParams{Model: "gpt-x", MaxTokens: 10, System: "be brief", Messages: []Message{{Role: "user", Content: "hi"}}}},
+		{CustomID: "b", Params: BatchRequestParams{Model: "nope", MaxTokens: 10, Messages: []Message{{Role: "user", Content: "hi"}}}},
+	}})
+	if err != nil {
+		t.Fatalf("CreateBatch: %v", err)
+	}
+	if batch.ID != "batch_1" || batch.ProcessingStatus != "in_progress" || batch.RequestCounts.Processing != 2 {
+		t.Fatalf("batch = %+v", batch)
+	}
+
+	lines := strings.Split(strings.TrimSpace(uploaded), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("uploaded %d lines, want 2: %s", len(lines), uploaded)
+	}
+	var first openaiBatchLine
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatal(err)
+	}
+	if first.CustomID != "a" || first.URL != "/v1/chat/completions" || first.Method != "POST" {
+		t.Errorf("first line = %+v", first)
+	}
+	if len(first.Body.Messages) != 2 || first.Body.Messages[0].Role != "system" {
+		t.Errorf("system prompt not prepended: %+v", first.Body.Messages)
+	}
+
+	got, err := c.GetBatch("batch_1")
+	if err != nil {
+		t.Fatalf("GetBatch: %v", err)
+	}
+	if got.ProcessingStatus != "ended" || got.EndedAt == nil || got.RequestCounts.Succeeded != 1 || got.RequestCounts.Errored != 1 {
+		t.Fatalf("ended batch = %+v", got)
+	}
+
+	results, err := c.GetBatchResults("batch_1")
+	if err != nil {
+		t.Fatalf("GetBatchResults: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("results = %d, want 2", len(results))
+	}
+	ok := results[0]
+	if ok.CustomID != "a" || ok.Result.Type != "succeeded" || ok.Result.Message == nil {
+		t.Fatalf("succeeded result = %+v", ok)
+	}
+	if m := ok.Result.Message; m.Content[0].Text != "hello" || m.StopReason != "stop" || m.Usage.InputTokens != 3 {
+		t.Errorf("message = %+v", m)
+	}
+	bad := results[1]
+	if bad.CustomID != "b" || bad.Result.Type != "errored" || bad.Result.Error.GetErrorMessage() != "bad model" {
+		t.Errorf("errored result = %+v", bad.Result)
+	}
+}
@@ -2,13 +2,12 @@ package gollama
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"sort"
@@ -44,20 +43,18 @@ type bedrockRequest struct {
 // NewBedrockClient creates a new client configured for AWS Bedrock.
 // The region determines the endpoint URL. Model is specified per-request in RequestOptions.Model
 // using Bedrock model IDs (e.g., "anthropic.claude-3-5-sonnet-20241022-v2:0").
-func NewBedrockClient(region, accessKey, secretKey, sessionToken string) *Client {
-	c := NewClient(fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", region))
-	c.bedrock = &BedrockConfig{
-		Region:    region,
-		AccessKey: accessKey,
-		SecretKey: secretKey,
-		Token:     sessionToken,
-	}
-	return c
+func NewBedrockClient(region, accessKey, secretKey, sessionToken string, opts ...Option) *Client {
+	opts = append([]Option{WithAWSAuth(region, accessKey, secretKey, sessionToken)}, opts...)
+	return NewClient(fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", region), opts...)
 }
 
 // SetAWSAuth configures AWS credentials for Bedrock API access.
 // This can be used with a client created via NewClient with a Bedrock endpoint URL.
+//
+// Prefer WithAWSAuth when constructing the client.
 func (c *Client) SetAWSAuth(region, accessKey, secretKey, sessionToken string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.bedrock = &BedrockConfig{
 		Region:    region,
 		AccessKey: accessKey,
@@ -68,16 +65,21 @@ func (c *Client) SetAWSAuth(region, accessKey, secretKey, sessionToken string) {
 
 // IsBedrockAPI checks if the client is configured to use AWS Bedrock.
 func (c *Client) IsBedrockAPI() bool {
-	return c.bedrock != nil
+	return c.awsConfig() != nil
 }
 
 // ChatCompletionBedrock sends a request using AWS Bedrock's invoke model endpoint.
 // It reuses the Anthropic request/response format, signing requests with AWS Signature V4.
 func (c *Client) ChatCompletionBedrock(opts RequestOptions) (*ResponseMessageGenerate, error) {
+	return c.chatCompletionBedrock(context.Background(), opts)
+}
+
+func (c *Client) chatCompletionBedrock(ctx context.Context, opts RequestOptions) (*ResponseMessageGenerate, error) {
 	antReq, err := buildAnthropicRequest(opts)
 	if err != nil {
 		return nil, fmt.Errorf("error building request: %w", err)
 	}
+	c.logArgumentRepairs(ctx, antReq.repairs)
 
 	// Convert to Bedrock request (no model field, add anthropic_version)
 	req := bedrockRequest{
@@ -88,54 +90,85 @@ func (c *Client) ChatCompletionBedrock(opts RequestOptions) (*ResponseMessageGen
 		Tools:            antReq.Tools,
 	}
 
-	body, err := json.Marshal(req)
-	if err != nil {
-		return nil, fmt.Errorf("error marshaling request: %w", err)
-	}
-
 	// Construct the invoke model endpoint URL.
 	// Bedrock model IDs may contain colons (e.g., "anthropic.claude-3-5-sonnet-20241022-v2:0")
 	// which must be percent-encoded in the URL path.
 	modelID := url.PathEscape(opts.Model)
 	endpoint := fmt.Sprintf("/model/%s/invoke", modelID)
-	fullURL := c.baseURL + endpoint
 
-	maxRetries := 5
-	baseDelay := 5 * time.Second
+	call, err := newCall("bedrock", "chat", http.MethodPost, endpoint, &opts, req)
+	if err != nil {
+		return nil, err
+	}
+	return invoke(ctx, c, call, c.invokeBedrock)
+}
+
+// invokeBedrock sends a signed Bedrock invoke request, retrying with
+// exponential backoff, or after the Retry-After delay, on 429, 503, and 529
+// errors.
+func (c *Client) invokeBedrock(ctx context.Context, call *Call) (*ResponseMessageGenerate, error) {
+	fullURL := c.baseURL + call.Endpoint
 
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		httpReq, err := http.NewRequest("POST", fullURL, bytes.NewReader(body))
+	meta := &ResponseMeta{}
+	meta.begin()
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		httpReq, err := http.NewRequestWithContext(ctx, call.Method, fullURL, bytes.NewReader(call.Body))
 		if err != nil {
 			return nil, fmt.Errorf("error creating request: %w", err)
 		}
 		httpReq.Header.Set("Content-Type", "application/json")
 		httpReq.Header.Set("Accept", "application/json")
+		for k, vs := range call.Header {
+			httpReq.Header[k] = vs
+		}
 
-		if err := c.signRequest(httpReq, body); err != nil {
+		if err := c.signRequest(httpReq, call.Body); err != nil {
 			return nil, fmt.Errorf("error signing request: %w", err)
 		}
 
+		c.logRequest(ctx, httpReq)
+		endAttempt := c.startAttemptSpan(ctx, httpReq, attempt)
+		sent := time.Now()
 		resp, err := c.httpClient.Do(httpReq)
 		if err != nil {
+			endAttempt(0, err)
 			return nil, fmt.Errorf("error sending request: %w", err)
 		}
+		endAttempt(resp.StatusCode, nil)
+		meta.record(resp, sent)
+		observeRateLimit(ctx, meta.RateLimit)
+		c.logResponse(ctx, resp)
 
 		if resp.StatusCode == http.StatusOK {
 			defer resp.Body.Close()
-			return parseAnthropicResponse(resp)
+			result, err := parseAnthropicResponse(resp)
+			if err != nil {
+				return nil, err
+			}
+			meta.finish()
+			result.Meta = meta
+			return result, nil
 		}
 
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
 
-		if isRetryableStatus(resp.StatusCode) && attempt < maxRetries {
-			delay := baseDelay * time.Duration(1<<attempt)
-			log.Printf("Bedrock API returned %d, retrying in %v (attempt %d/%d)", resp.StatusCode, delay, attempt+1, maxRetries)
-			time.Sleep(delay)
+		if isRetryableStatus(resp.StatusCode) && attempt < c.maxRetries {
+			delay := c.baseDelay * time.Duration(1<<attempt)
+			if ra := meta.RateLimit.RetryAfter; ra > 0 {
+				delay = ra
+			}
+			c.logRetry(ctx, httpReq, resp.StatusCode, delay, attempt+1)
+			if err := sleepContext(ctx, delay); err != nil {
+				return nil, err
+			}
+			meta.retried(delay)
 			continue
 		}
 
-		return nil, fmt.Errorf("Bedrock API returned non-200 status code %d (url: %s): %s", resp.StatusCode, fullURL, string(bodyBytes))
+		meta.finish()
+		apiErr := &APIError{StatusCode: resp.StatusCode, Body: string(bodyBytes), Meta: meta}
+		return nil, fmt.Errorf("Bedrock (url: %s): %w", fullURL, apiErr)
 	}
 
 	return nil, fmt.Errorf("max retries exceeded")
@@ -143,7 +176,7 @@ func (c *Client) ChatCompletionBedrock(opts RequestOptions) (*ResponseMessageGen
 
 // signRequest signs an HTTP request using AWS Signature Version 4.
 func (c *Client) signRequest(req *http.Request, payload []byte) error {
-	return signRequestWithTime(c.bedrock, req, payload, time.Now().UTC())
+	return signRequestWithTime(c.awsConfig(), req, payload, time.Now().UTC())
 }
 
 // signRequestWithTime signs an HTTP request using AWS Signature Version 4 with an explicit timestamp.
@@ -0,0 +1,198 @@
+package gollama
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BulkRequest is a single Turn submitted to RunBulk.
+type BulkRequest struct {
+	ID      string
+	Options RequestOptions
+}
+
+// BulkResult is the outcome of one BulkRequest. Results are delivered in
+// completion order; Seq is the request's zero-based position in the input
+// stream so callers can restore submission order.
+type BulkResult struct {
+	ID       string
+	Seq      int
+	Response *ResponseMessageGenerate
+	Err      error
+	Attempts int // number of Turn calls made, including retries
+}
+
+// BulkProgress is a snapshot of a RunBulk execution passed to OnProgress.
+type BulkProgress struct {
+	Submitted int   // requests read from the input stream so far
+	Done      int   // requests finished, successfully or not
+	Failed    int   // requests that failed after all retries
+	Usage     Usage // token usage summed over successful requests
+	// Contiguous is the number of leading requests (by Seq) that have all
+	// finished; results with Seq < Contiguous are final.
+	Contiguous int
+}
+
+// BulkOptions configures RunBulk.
+type BulkOptions struct {
+	// Concurrency is the number of Turns in flight at once (default 1).
+	Concurrency int
+	// MaxRetries is the number of additional attempts made for a request
+	// whose Turn fails. HTTP 429/503/529 responses are already retried inside
+	// each Turn; these retries cover the other failures ShouldRetry accepts.
+	MaxRetries int
+	// RetryDelay is the backoff before the first retry, doubled on each
+	// subsequent one (default 1s).
+	RetryDelay time.Duration
+	// ShouldRetry decides whether a failed request is retried. By default
+	// network errors and APIErrors with status 429 or 5xx are retried;
+	// other API errors, such as 400 or 401, and context cancellation are
+	// not.
+	ShouldRetry func(error) bool
+	// OnProgress, if set, is called after each request finishes. Calls are
+	// serialized.
+	OnProgress func(BulkProgress)
+}
+
+// isBulkRetryable is the default BulkOptions.ShouldRetry: it reports
+// whether err is transient, i.e. a network error or a 429 or 5xx response.
+func isBulkRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// RunBulk runs Turn for every request read from requests with up to
+// opts.Concurrency requests in flight, and delivers results on the returned
+// channel as they complete. The channel is closed once requests is closed (or
+// ctx is done) and all in-flight requests have finished; the caller must
+// drain it. Requests not yet read from the input when ctx is cancelled are
+// never started.
+func (c *Client) RunBulk(ctx context.Context, requests <-chan BulkRequest, opts BulkOptions) <-chan BulkResult {
+	concurrency := max(opts.Concurrency, 1)
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = time.Second
+	}
+	if opts.ShouldRetry == nil {
+		opts.ShouldRetry = isBulkRetryable
+	}
+
+	type job struct {
+		seq int
+		req BulkRequest
+	}
+
+	tracker := &bulkTracker{finished: make(map[int]bool), onProgress: opts.OnProgress}
+	jobs := make(chan job)
+	out := make(chan BulkResult, concurrency)
+
+	go func() {
+		defer close(jobs)
+		for seq := 0; ; seq++ {
+			var req BulkRequest
+			var ok bool
+			select {
+			case req, ok = <-requests:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+			tracker.submit()
+			select {
+			case jobs <- job{seq: seq, req: req}:
+			case <-ctx.Done():
+				tracker.finish(BulkResult{ID: req.ID, Seq: seq, Err: ctx.Err()})
+				out <- BulkResult{ID: req.ID, Seq: seq, Err: ctx.Err()}
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				res := c.runBulkRequest(ctx, j.seq, j.req, &opts)
+				tracker.finish(res)
+				out <- res
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
+// runBulkRequest executes one request, retrying failures as configured.
+func (c *Client) runBulkRequest(ctx context.Context, seq int, req BulkRequest, opts *BulkOptions) BulkResult {
+	res := BulkResult{ID: req.ID, Seq: seq}
+	for attempt := 0; attempt <= opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleepContext(ctx, opts.RetryDelay*time.Duration(1<<(attempt-1))); err != nil {
+				res.Err = err
+				return res
+			}
+		}
+		res.Attempts++
+		res.Response, res.Err = c.TurnContext(ctx, req.Options)
+		if res.Err == nil || ctx.Err() != nil || !opts.ShouldRetry(res.Err) {
+			break
+		}
+	}
+	return res
+}
+
+// bulkTracker aggregates progress across RunBulk workers.
+type bulkTracker struct {
+	mu         sync.Mutex
+	progress   BulkProgress
+	finished   map[int]bool // finished seqs at or above progress.Contiguous
+	onProgress func(BulkProgress)
+}
+
+func (t *bulkTracker) submit() {
+	t.mu.Lock()
+	t.progress.Submitted++
+	t.mu.Unlock()
+}
+
+func (t *bulkTracker) finish(res BulkResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.progress.Done++
+	if res.Err != nil {
+		t.progress.Failed++
+	} else if res.Response != nil {
+		t.progress.Usage.Add(res.Response.Usage)
+	}
+
+	t.finished[res.Seq] = true
+	for t.finished[t.progress.Contiguous] {
+		delete(t.finished, t.progress.Contiguous)
+		t.progress.Contiguous++
+	}
+
+	if t.onProgress != nil {
+		t.onProgress(t.progress)
+	}
+}
@@ -0,0 +1,156 @@
+package gollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRunBulk(t *testing.T) {
+	// Every third request fails once with a status the HTTP layer does not
+	// retry, so it must be retried at the bulk level.
+	var mu sync.Mutex
+	seen := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openaiRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		content := req.Messages[len(req.Messages)-1].Content
+		mu.Lock()
+		seen[content]++
+		first := seen[content] == 1
+		mu.Unlock()
+		if idx, _ := strconv.Atoi(content); idx%3 == 0 && first {
+			http.Error(w, "transient", http.StatusBadGateway)
+			return
+		}
+		fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":%q}}],"usage":{"prompt_tokens":2,"completion_tokens":1}}`, "echo: "+content)
+	}))
+	defer srv.Close()
+
+	const n = 30
+	in := make(chan BulkRequest)
+	go func() {
+		defer close(in)
+		for i := 0; i < n; i++ {
+			in <- BulkRequest{
+				ID: fmt.Sprintf("req-%d", i),
+				Options: RequestOptions{
+					Model:    "m",
+					Messages: []Message{{Role: "user", Content: fmt.Sprint(i)}},
+				},
+			}
+		}
+	}()
+
+	var progressCalls atomic.Int32
+	var last BulkProgress
+	out := NewClient(srv.URL).RunBulk(context.Background(), in, BulkOptions{
+		Concurrency: 4,
+		MaxRetries:  2,
+		RetryDelay:  time.Millisecond,
+		OnProgress: func(p BulkProgress) {
+			progressCalls.Add(1)
+			last = p
+		},
+	})
+
+	got := make([]BulkResult, n)
+	for res := range out {
+		got[res.Seq] = res
+	}
+	for i, res := range got {
+		if res.Err != nil {
+			t.Fatalf("%s: %v", res.ID, res.Err)
+		}
+		if res.ID != fmt.Sprintf("req-%d", i) {
+			t.Errorf("seq %d has id %s", i, res.ID)
+		}
+		wantAttempts := 1
+		if i%3 == 0 {
+			wantAttempts = 2
+		}
+		if res.Attempts != wantAttempts {
+			t.Errorf("%s attempts = %d, want %d", res.ID, res.Attempts, wantAttempts)
+		}
+		if want := fmt.Sprintf("echo: %d", i); res.Response.Choices[0].Message.Content != want {
+			t.Errorf("%s content = %q, want %q", res.ID, res.Response.Choices[0].Message.Content, want)
+		}
+	}
+	if progressCalls.Load() != n {
+		t.Errorf("OnProgress called %d times, want %d", progressCalls.Load(), n)
+	}
+	if last.Done != n || last.Submitted != n || last.Failed != 0 || last.Contiguous != n {
+		t.Errorf("final progress = %+v", last)
+	}
+	if last.Usage.PromptTokens != 2*n || last.Usage.CompletionTokens != n {
+		t.Errorf("usage = %+v", last.Usage)
+	}
+}
+
+func TestRunBulkCancel(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	srv := echoCompletionServer(t, block)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan BulkRequest)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case in <- BulkRequest{ID: fmt.Sprint(i), Options: RequestOptions{Model: "m", Messages: []Message{{Role: "user", Content: "hi"}}}}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	out := NewClient(srv.URL).RunBulk(ctx, in, BulkOptions{Concurrency: 3, MaxRetries: 5})
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	count := 0
+	for res := range out {
+		count++
+		if res.Err == nil {
+			t.Errorf("request %s succeeded after cancel", res.ID)
+		}
+		if res.Attempts > 1 {
+			t.Errorf("request %s retried %d times after cancel", res.ID, res.Attempts)
+		}
+	}
+	if count == 0 {
+		t.Error("expected the in-flight requests to be reported")
+	}
+}
+
+func TestIsBulkRetryable(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&APIError{StatusCode: 429}, true},
+		{&APIError{StatusCode: 502}, true},
+		{fmt.Errorf("error sending request: %w", &url.Error{Op: "Post", URL: "http://x", Err: syscall.ECONNREFUSED}), true},
+		{fmt.Errorf("error decoding response: %w", io.ErrUnexpectedEOF), true},
+		{&APIError{StatusCode: 400}, false},
+		{&APIError{StatusCode: 401}, false},
+		{&APIError{StatusCode: 404}, false},
+		{fmt.Errorf("error sending request: %w", &url.Error{Op: "Post", URL: "http://x", Err: context.Canceled}), false},
+		{context.DeadlineExceeded, false},
+		{fmt.Errorf("error parsing response"), false},
+	}
+	for _, c := range cases {
+		if got := isBulkRetryable(c.err); got != c.want {
+			t.Errorf("isBulkRetryable(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package gollama
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// CassetteMode selects whether a Cassette records or replays.
+type CassetteMode int
+
+const (
+	// CassetteReplay serves responses from the cassette file and never
+	// touches the network.
+	CassetteReplay CassetteMode = iota
+	// CassetteRecord sends requests to the real provider and records the
+	// exchanges; call Save to write them out.
+	CassetteRecord
+	// CassetteAuto replays if the cassette file exists and records otherwise.
+	CassetteAuto
+)
+
+// Interaction is one recorded HTTP exchange.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the request half of an Interaction.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is the response half of an Interaction.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+type cassetteFile struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Cassette is an http.RoundTripper that records provider HTTP exchanges to a
+// file and replays them later, so tests that talk to real APIs can run
+// offline and deterministically:
+//
+//	cas, err := gollama.NewCassette("testdata/agent.json", gollama.CassetteAuto)
+//	client := gollama.NewClient(url, gollama.WithTransport(cas))
+//	// ... run the session ...
+//	err = cas.Save()
+//
+// Recorded requests have credentials redacted and the Authorization header
+// (including AWS SigV4 signatures) stripped. On replay a request matches the
+// first unused interaction with the same method, path, query and body, with
+// JSON bodies compared after canonicalization, so identical requests replay
+// in the order they were recorded. Multipart bodies are compared with their
+// random boundary replaced by a fixed one.
+type Cassette struct {
+	path string
+	mode CassetteMode
+
+	// Transport sends requests while recording (default
+	// http.DefaultTransport).
+	Transport http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewCassette opens the cassette at path. In replay mode (or auto mode with
+// an existing file) the file is loaded.
+func NewCassette(path string, mode CassetteMode) (*Cassette, error) {
+	c := &Cassette{path: path, mode: mode}
+	if mode == CassetteAuto {
+		c.mode = CassetteRecord
+		if _, err := os.Stat(path); err == nil {
+			c.mode = CassetteReplay
+		}
+	}
+	if c.mode != CassetteReplay {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading cassette: %w", err)
+	}
+	var f cassetteFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("error decoding cassette %s: %w", path, err)
+	}
+	c.interactions = f.Interactions
+	c.used = make([]bool, len(f.Interactions))
+	return c, nil
+}
+
+// Recording reports whether the cassette is recording rather than replaying.
+func (c *Cassette) Recording() bool {
+	return c.mode == CassetteRecord
+}
+
+// RoundTrip implements http.RoundTripper.
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if c.mode == CassetteReplay {
+		return c.replay(req, body)
+	}
+	return c.record(req, body)
+}
+
+func (c *Cassette) record(req *http.Request, body []byte) (*http.Response, error) {
+	rt := c.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	respHeader := resp.Header.Clone()
+	respHeader.Del("Set-Cookie")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    redactSecrets(req.URL.String()),
+			Header: cassetteHeader(req.Header),
+			Body:   redactSecrets(string(body)),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     respHeader,
+			Body:       string(respBody),
+		},
+	})
+	c.used = append(c.used, true)
+	return resp, nil
+}
+
+func (c *Cassette) replay(req *http.Request, body []byte) (*http.Response, error) {
+	key := canonicalBody(req.Header.Get("Content-Type"), redactSecrets(string(body)))
+	target := redactSecrets(req.URL.RequestURI())
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, in := range c.interactions {
+		if c.used[i] || in.Request.Method != req.Method {
+			continue
+		}
+		if recordedRequestURI(in.Request.URL) != target || canonicalBody(in.Request.Header.Get("Content-Type"), in.Request.Body) != key {
+			continue
+		}
+		c.used[i] = true
+		return &http.Response{
+			StatusCode:    in.Response.StatusCode,
+			Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        in.Response.Header.Clone(),
+			Body:          io.NopCloser(strings.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("cassette %s has no unused interaction for %s %s", c.path, req.Method, req.URL.Path)
+}
+
+// Save writes the recorded interactions to the cassette file. It does
+// nothing when replaying.
+func (c *Cassette) Save() error {
+	if c.mode == CassetteReplay {
+		return nil
+	}
+	c.mu.Lock()
+	data, err := json.MarshalIndent(cassetteFile{Interactions: c.interactions}, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("error encoding cassette: %w", err)
+	}
+	return os.WriteFile(c.path, append(data, '\n'), 0o644)
+}
+
+// Unused returns the recorded interactions that have not been replayed,
+// which usually means the code under test made fewer calls than it did when
+// the cassette was recorded.
+func (c *Cassette) Unused() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []Interaction
+	for i, in := range c.interactions {
+		if !c.used[i] {
+			out = append(out, in)
+		}
+	}
+	return out
+}
+
+// strippedHeaders are dropped from recorded requests: credentials and the
+// per-request parts of an AWS SigV4 signature.
+var strippedHeaders = []string{"Authorization", "Proxy-Authorization", "X-Amz-Date", "X-Amz-Security-Token", "X-Amz-Content-Sha256", "Cookie"}
+
+// cassetteHeader returns the request header as recorded.
+func cassetteHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, k := range strippedHeaders {
+		h.Del(k)
+	}
+	return redactHeader(h)
+}
+
+// recordedRequestURI returns the path and query of a recorded URL.
+func recordedRequestURI(rawURL string) string {
+	if i := strings.Index(rawURL, "://"); i >= 0 {
+		rawURL = rawURL[i+3:]
+		if j := strings.IndexByte(rawURL, '/'); j >= 0 {
+			return rawURL[j:]
+		}
+		return "/"
+	}
+	return rawURL
+}
+
+// canonicalBody re-encodes a JSON body with sorted keys and no insignificant
+// whitespace so that equivalent bodies compare equal. In a multipart body,
+// whose contentType is given, the boundary is replaced by a fixed string.
+// Other bodies are returned unchanged.
+func canonicalBody(contentType, body string) string {
+	if mt, params, err := mime.ParseMediaType(contentType); err == nil && strings.HasPrefix(mt, "multipart/") && params["boundary"] != "" {
+		return strings.ReplaceAll(body, params["boundary"], "gollama-cassette-boundary")
+	}
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return body
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return body
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return string(data)
+}
@@ -0,0 +1,146 @@
+package gollama
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCassetteRecordReplay(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("request-id", fmt.Sprintf("req_%d", calls))
+		fmt.Fprintf(w, `{"role":"assistant","model":"claude","content":[{"type":"text","text":"answer %d"}],"usage":{"input_tokens":3,"output_tokens":1}}`, calls)
+	}))
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	opts := RequestOptions{Model: "claude", Messages: []Message{{Role: "user", Content: "hi"}}}
+
+	// Record two identical turns and a Bedrock call.
+	rec, err := NewCassette(path, CassetteAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rec.Recording() {
+		t.Fatal("auto mode should record when the file is missing")
+	}
+	c := NewClient(srv.URL, WithAnthropicMode(true), WithAPIKey("sk-ant-secretkey123"), WithTransport(rec))
+	for i := 1; i <= 2; i++ {
+		if _, err := c.Turn(opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bedrock := NewClient(srv.URL, WithAWSAuth("us-east-1", "AKIDEXAMPLE", "secret", "tok"), WithTransport(rec))
+	if _, err := bedrock.Turn(opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"secretkey123", "AKIDEXAMPLE", "AWS4-HMAC-SHA256", "X-Amz-Security-Token"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %q", secret)
+		}
+	}
+
+	// Replay offline, against a different base URL, in recorded order.
+	play, err := NewCassette(path, CassetteAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if play.Recording() {
+		t.Fatal("auto mode should replay when the file exists")
+	}
+	c = NewClient("http://replay.invalid", WithAnthropicMode(true), WithTransport(play))
+	for i := 1; i <= 2; i++ {
+		resp, err := c.Turn(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Choices[0].Message.Content; got != fmt.Sprintf("answer %d", i) {
+			t.Errorf("turn %d replayed %q", i, got)
+		}
+		if resp.Meta.RequestID != fmt.Sprintf("req_%d", i) {
+			t.Errorf("turn %d request ID %q", i, resp.Meta.RequestID)
+		}
+	}
+	if len(play.Unused()) != 1 {
+		t.Errorf("expected the Bedrock interaction to be unused, got %d", len(play.Unused()))
+	}
+	bedrock = NewClient("http://replay.invalid", WithAWSAuth("us-east-1", "OTHERKEY", "other", ""), WithTransport(play))
+	if _, err := bedrock.Turn(opts); err != nil {
+		t.Fatalf("bedrock replay: %v", err)
+	}
+
+	// Requests that were never recorded fail instead of going to the network.
+	if _, err := c.Turn(RequestOptions{Model: "claude", Messages: []Message{{Role: "user", Content: "something else"}}}); err == nil {
+		t.Error("expected an error for an unrecorded request")
+	}
+}
+
+func TestCanonicalBody(t *testing.T) {
+	a := canonicalBody("application/json", `{"b": 1, "a": [1.50, {"y": true, "x": null}]}`)
+	b := canonicalBody("application/json", `{"a":[1.50,{"x":null,"y":true}],"b":1}`)
+	if a != b {
+		t.Errorf("%s != %s", a, b)
+	}
+	if !json.Valid([]byte(a)) {
+		t.Errorf("invalid JSON %s", a)
+	}
+	if got := canonicalBody("text/plain", "not json"); got != "not json" {
+		t.Errorf("non-JSON body changed to %q", got)
+	}
+}
+
+// TestCassetteMultipart checks that a file upload, whose multipart boundary
+// is random, replays.
+func TestCassetteMultipart(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil || r.FormValue("purpose") != "batch" {
+			http.Error(w, "bad upload", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"id":"file-1","object":"file","purpose":"batch"}`)
+	}))
+	path := filepath.Join(t.TempDir(), "upload.json")
+
+	rec, err := NewCassette(path, CassetteRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewClient(srv.URL, WithTransport(rec)).uploadFile("batch", "in.jsonl", []byte("{}\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	play, err := NewCassette(path, CassetteReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient("http://replay.invalid", WithTransport(play))
+	if _, err := c.uploadFile("batch", "other.jsonl", []byte("{}\n")); err == nil {
+		t.Error("expected an error for a different upload")
+	}
+	f, err := c.uploadFile("batch", "in.jsonl", []byte("{}\n"))
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if f.ID != "file-1" {
+		t.Errorf("file = %+v", f)
+	}
+}
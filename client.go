@@ -3,57 +3,249 @@
 package gollama
 
 import (
+	"log/slog"
+	"maps"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
 // Client represents a multi-provider LLM API client.
 // It can interact with Ollama, OpenAI-compatible, Anthropic, and AWS Bedrock endpoints
 // depending on the baseURL and methods used.
+//
+// A Client is safe for concurrent use. Configure it with Options when it is
+// created, and derive per-request variants (extra headers, a different
+// timeout) with With rather than mutating a shared client.
 type Client struct {
-	baseURL       string
-	httpClient    *http.Client
+	baseURL    string
+	httpClient *http.Client
+	maxRetries int
+	baseDelay  time.Duration
+	middleware []Middleware
+	logger     *slog.Logger
+	tracer     Tracer
+	meter      Meter
+
+	// mu guards the fields below, which the legacy Set* methods may change
+	// after construction.
+	mu            sync.RWMutex
 	headers       map[string]string
 	bedrock       *BedrockConfig
-	anthropicMode *bool // nil = auto-detect from URL; non-nil = explicit override
+	anthropicMode *bool        // nil = auto-detect from URL; non-nil = explicit override
+	limiter       *RateLimiter // optional client-side rate limiting for chat requests
 }
 
+// Option configures a Client in NewClient, NewBedrockClient or With.
+type Option func(*Client)
+
 // NewClient creates a new LLM API client with the specified base URL.
 // The baseURL should point to your API endpoint (e.g., "http://localhost:11434" for Ollama).
-func NewClient(baseURL string) *Client {
-	return &Client{
+func NewClient(baseURL string, opts ...Option) *Client {
+	c := &Client{
 		baseURL: baseURL,
 		httpClient: &http.Client{
 			Timeout: 300 * time.Second,
 		},
-		headers: make(map[string]string),
+		maxRetries: defaultMaxRetries,
+		baseDelay:  defaultBaseDelay,
+		headers:    make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Clone returns an independent copy of the client. The copy shares the
+// underlying transport (and so its connection pool) and the RateLimiter, but
+// changes to its headers or timeout do not affect the original.
+func (c *Client) Clone() *Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	hc := *c.httpClient
+	clone := &Client{
+		baseURL:       c.baseURL,
+		httpClient:    &hc,
+		maxRetries:    c.maxRetries,
+		baseDelay:     c.baseDelay,
+		middleware:    c.middleware,
+		logger:        c.logger,
+		tracer:        c.tracer,
+		meter:         c.meter,
+		headers:       maps.Clone(c.headers),
+		anthropicMode: c.anthropicMode,
+		limiter:       c.limiter,
+	}
+	if c.bedrock != nil {
+		cfg := *c.bedrock
+		clone.bedrock = &cfg
+	}
+	return clone
+}
+
+// With returns a copy of the client with opts applied, leaving c unchanged.
+// Use it for per-request overrides such as extra headers or a shorter timeout:
+//
+//	resp, err := client.With(gollama.WithHeader("x-trace", id)).Turn(opts)
+func (c *Client) With(opts ...Option) *Client {
+	clone := c.Clone()
+	for _, opt := range opts {
+		opt(clone)
+	}
+	return clone
+}
+
+// WithHTTPClient uses hc to send requests instead of the default client.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithTimeout sets the overall timeout of each HTTP request.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		hc := *c.httpClient
+		hc.Timeout = d
+		c.httpClient = &hc
+	}
+}
+
+// WithTransport sets the RoundTripper used to send requests.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		hc := *c.httpClient
+		hc.Transport = rt
+		c.httpClient = &hc
+	}
+}
+
+// WithHeader sets a custom HTTP header sent with every request.
+func WithHeader(k, v string) Option {
+	return func(c *Client) {
+		c.headers[k] = v
+	}
+}
+
+// WithAPIKey sets the "x-api-key" header used by Anthropic's API.
+func WithAPIKey(k string) Option {
+	return WithHeader("x-api-key", k)
+}
+
+// WithBearerToken sets the "Authorization: Bearer" header used by OpenAI-compatible APIs.
+func WithBearerToken(k string) Option {
+	return WithHeader("Authorization", "Bearer "+k)
+}
+
+// WithAnthropicMode explicitly enables or disables Anthropic native API mode.
+// See SetAnthropicMode.
+func WithAnthropicMode(enabled bool) Option {
+	return func(c *Client) {
+		c.anthropicMode = &enabled
+	}
+}
+
+// WithAWSAuth configures AWS credentials for Bedrock API access. See SetAWSAuth.
+func WithAWSAuth(region, accessKey, secretKey, sessionToken string) Option {
+	return func(c *Client) {
+		c.bedrock = &BedrockConfig{
+			Region:    region,
+			AccessKey: accessKey,
+			SecretKey: secretKey,
+			Token:     sessionToken,
+		}
+	}
+}
+
+// WithRateLimiter enables client-side rate limiting. See SetRateLimiter.
+func WithRateLimiter(rl *RateLimiter) Option {
+	return func(c *Client) {
+		c.limiter = rl
+	}
+}
+
+// WithRetryPolicy sets how many times a request is retried after a 429, 503
+// or 529 response, and the initial backoff, which doubles on each retry.
+// The default is 5 retries starting at 5s.
+func WithRetryPolicy(maxRetries int, baseDelay time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.baseDelay = baseDelay
 	}
 }
 
 // SetAnthropicMode explicitly enables or disables Anthropic native API mode.
 // By default, the client auto-detects Anthropic endpoints from the base URL.
 // Use this when routing through a proxy or gateway on a custom domain.
+//
+// Prefer WithAnthropicMode when constructing the client.
 func (c *Client) SetAnthropicMode(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.anthropicMode = &enabled
 }
 
 // SetAPIKey sets the "x-api-key" header used by Anthropic's API.
 // For OpenAI-compatible APIs that use Bearer tokens, use SetBearerToken instead.
+//
+// Prefer WithAPIKey when constructing the client.
 func (c *Client) SetAPIKey(k string) {
 	c.SetHeader("x-api-key", k)
 }
 
 // SetBearerToken sets the "Authorization: Bearer" header used by OpenAI-compatible APIs.
+//
+// Prefer WithBearerToken when constructing the client.
 func (c *Client) SetBearerToken(k string) {
 	c.SetHeader("Authorization", "Bearer "+k)
 }
 
 // SetHeader sets a custom HTTP header for all requests made by this client.
+// It is safe to call concurrently with requests, but affects every user of
+// the client; use With(WithHeader(k, v)) for a per-request header.
 func (c *Client) SetHeader(k, v string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.headers[k] = v
 }
 
+// SetRateLimiter enables client-side rate limiting of chat requests (Turn and
+// ChatCompletion). Pass nil to disable it. A limiter may be shared between
+// clients that draw on the same quota.
+//
+// Prefer WithRateLimiter when constructing the client.
+func (c *Client) SetRateLimiter(rl *RateLimiter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.limiter = rl
+}
+
+// applyHeaders sets the client's custom headers on req.
+func (c *Client) applyHeaders(req *http.Request) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+}
+
+// rateLimiter returns the client's RateLimiter, or nil.
+func (c *Client) rateLimiter() *RateLimiter {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.limiter
+}
+
+// awsConfig returns the client's Bedrock configuration, or nil.
+func (c *Client) awsConfig() *BedrockConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.bedrock
+}
+
 // anthropicEndpoint returns the correct API path for Anthropic endpoints,
 // accounting for whether the baseURL already includes the /v1 prefix.
 func (c *Client) anthropicEndpoint(path string) string {
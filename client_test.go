@@ -0,0 +1,103 @@
+package gollama
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// multiBackendServer answers the chat endpoints of every backend: Anthropic
+// /v1/messages, Bedrock /model/{id}/invoke, and OpenAI-compatible
+// /chat/completions (also under /v1 as Ollama serves it).
+func multiBackendServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/messages":
+			if r.Header.Get("anthropic-version") == "" {
+				http.Error(w, "missing anthropic-version", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, anthropicOKBody)
+		case "/model/claude/invoke":
+			if r.Header.Get("Authorization") == "" {
+				http.Error(w, "unsigned", http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, anthropicOKBody)
+		case "/chat/completions", "/v1/chat/completions":
+			fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ok"}}],"usage":{"prompt_tokens":1,"completion_tokens":1}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestClientConcurrentTurns shares one client per backend across many
+// goroutines while other goroutines derive per-request clients and call the
+// legacy setters. Run with -race to check for data races.
+func TestClientConcurrentTurns(t *testing.T) {
+	srv := multiBackendServer(t)
+
+	tools := []ToolParam{(&Tool{Name: "noop", Params: ToolFunctionParams{Type: "object"}}).ApiDef()}
+	clients := map[string]*Client{
+		"openai":    NewClient(srv.URL, WithBearerToken("sk-test")),
+		"ollama":    NewClient(srv.URL + "/v1"),
+		"anthropic": NewClient(srv.URL, WithAnthropicMode(true), WithAPIKey("sk-ant-test")),
+		"bedrock":   NewClient(srv.URL, WithAWSAuth("us-east-1", "AKID", "secret", "")),
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 1000)
+	for name, c := range clients {
+		for i := 0; i < 25; i++ {
+			wg.Add(3)
+			go func() {
+				defer wg.Done()
+				_, err := c.Turn(RequestOptions{Model: "claude", Tools: tools, Messages: []Message{{Role: "user", Content: "hi"}}})
+				if err != nil {
+					errs <- fmt.Errorf("%s: %w", name, err)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				rc := c.With(WithHeader("x-request-tag", fmt.Sprint(i)), WithTimeout(10*time.Second))
+				if _, err := rc.Turn(RequestOptions{Model: "claude", Messages: []Message{{Role: "user", Content: "hi"}}}); err != nil {
+					errs <- fmt.Errorf("%s (derived): %w", name, err)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				c.SetHeader("x-legacy", fmt.Sprint(i))
+			}()
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestClientWithDoesNotMutateParent(t *testing.T) {
+	parent := NewClient("http://example.invalid", WithHeader("a", "1"), WithTimeout(time.Minute))
+	child := parent.With(WithHeader("b", "2"), WithTimeout(time.Second), WithAnthropicMode(true))
+
+	if _, ok := parent.headers["b"]; ok {
+		t.Error("child header leaked into parent")
+	}
+	if child.headers["a"] != "1" || child.headers["b"] != "2" {
+		t.Errorf("child headers = %v", child.headers)
+	}
+	if parent.httpClient.Timeout != time.Minute || child.httpClient.Timeout != time.Second {
+		t.Errorf("timeouts parent=%v child=%v", parent.httpClient.Timeout, child.httpClient.Timeout)
+	}
+	if parent.IsAnthropicAPI() || !child.IsAnthropicAPI() {
+		t.Error("anthropic mode not isolated to child")
+	}
+}
@@ -0,0 +1,207 @@
+package gollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNoHealthyBackend is returned by FallbackClient when every backend's
+// circuit breaker is open.
+var ErrNoHealthyBackend = errors.New("no healthy fallback backend")
+
+// FallbackBackend is one entry of a FallbackClient's chain.
+type FallbackBackend struct {
+	// Name identifies the backend in ResponseMessageGenerate.Backend, errors
+	// and Health. Defaults to the model, or "backend N" if Model is empty.
+	Name   string
+	Client *Client
+	// Model, if set, replaces RequestOptions.Model for this backend, since
+	// the same model usually has a different ID on each provider.
+	Model string
+}
+
+// FallbackOptions configures a FallbackClient.
+type FallbackOptions struct {
+	// ShouldFallback decides whether an error moves the request on to the
+	// next backend and counts against the backend's health. Defaults to
+	// DefaultShouldFallback.
+	ShouldFallback func(error) bool
+	// FailureThreshold is the number of consecutive failures after which a
+	// backend is skipped (default 3).
+	FailureThreshold int
+	// Cooldown is how long a failing backend is skipped before a single
+	// request is let through to probe it again (default 30s).
+	Cooldown time.Duration
+}
+
+// FallbackClient sends each Turn to the first healthy backend in its chain
+// and moves on to the next one when a backend fails with a retryable error,
+// e.g. from Anthropic to Bedrock to a local Ollama model. Each backend has a
+// circuit breaker: after FailureThreshold consecutive failures it is skipped
+// for Cooldown, after which one request probes it again.
+//
+// Each Client still retries 429/503/529 responses itself before giving up;
+// use WithRetryPolicy on the backend clients to fail over sooner.
+//
+// A FallbackClient is safe for concurrent use.
+type FallbackClient struct {
+	backends []*fallbackBackend
+	opts     FallbackOptions
+	now      func() time.Time
+}
+
+type fallbackBackend struct {
+	FallbackBackend
+
+	mu        sync.Mutex
+	failures  int       // consecutive failures
+	openUntil time.Time // skip the backend until then
+}
+
+// BackendHealth is a snapshot of a FallbackClient backend's circuit breaker.
+type BackendHealth struct {
+	Name                string
+	Healthy             bool // false while the backend is being skipped
+	ConsecutiveFailures int
+	OpenUntil           time.Time // zero unless the breaker is open
+}
+
+// NewFallbackClient creates a FallbackClient that tries backends in order.
+func NewFallbackClient(backends []FallbackBackend, opts FallbackOptions) *FallbackClient {
+	if opts.ShouldFallback == nil {
+		opts.ShouldFallback = DefaultShouldFallback
+	}
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 3
+	}
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = 30 * time.Second
+	}
+
+	f := &FallbackClient{opts: opts, now: time.Now}
+	for i, b := range backends {
+		if b.Name == "" {
+			b.Name = b.Model
+		}
+		if b.Name == "" {
+			b.Name = fmt.Sprintf("backend %d", i)
+		}
+		f.backends = append(f.backends, &fallbackBackend{FallbackBackend: b})
+	}
+	return f
+}
+
+// DefaultShouldFallback reports whether err suggests trying another backend:
+// timeouts, connection failures, and 408, 429 and 5xx responses (including
+// Anthropic's 529 overloaded). Other 4xx responses mean the request itself
+// is bad and are returned to the caller, as is context cancellation.
+func DefaultShouldFallback(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == 408 || apiErr.StatusCode == 429 || apiErr.StatusCode >= 500
+	}
+	return true
+}
+
+// Turn sends opts to the first healthy backend, falling back down the chain
+// on failure. ResponseMessageGenerate.Backend names the backend that served
+// the response. If every backend fails, the returned error joins each
+// backend's error.
+func (f *FallbackClient) Turn(opts RequestOptions) (*ResponseMessageGenerate, error) {
+	return f.TurnContext(context.Background(), opts)
+}
+
+// TurnContext is Turn with a context; cancelling ctx aborts the request
+// without trying further backends.
+func (f *FallbackClient) TurnContext(ctx context.Context, opts RequestOptions) (*ResponseMessageGenerate, error) {
+	var errs []error
+	for _, b := range f.backends {
+		if !b.allow(f.now(), f.opts.Cooldown) {
+			continue
+		}
+
+		bopts := opts
+		if b.Model != "" {
+			bopts.Model = b.Model
+		}
+		resp, err := b.Client.TurnContext(ctx, bopts)
+		if err == nil {
+			b.succeeded()
+			resp.Backend = b.Name
+			return resp, nil
+		}
+
+		err = fmt.Errorf("%s: %w", b.Name, err)
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		if !f.opts.ShouldFallback(err) {
+			// The backend answered; the request itself was rejected.
+			b.succeeded()
+			return nil, err
+		}
+		b.failed(f.now(), f.opts.FailureThreshold, f.opts.Cooldown)
+		errs = append(errs, err)
+	}
+
+	if len(errs) == 0 {
+		return nil, ErrNoHealthyBackend
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Health returns the circuit-breaker state of each backend, in chain order.
+func (f *FallbackClient) Health() []BackendHealth {
+	now := f.now()
+	out := make([]BackendHealth, len(f.backends))
+	for i, b := range f.backends {
+		b.mu.Lock()
+		out[i] = BackendHealth{
+			Name:                b.Name,
+			Healthy:             !now.Before(b.openUntil),
+			ConsecutiveFailures: b.failures,
+		}
+		if !out[i].Healthy {
+			out[i].OpenUntil = b.openUntil
+		}
+		b.mu.Unlock()
+	}
+	return out
+}
+
+// allow reports whether a request may be sent to the backend. Once an open
+// breaker's cooldown has passed, one request is let through as a probe and
+// the breaker stays open for everyone else until the probe finishes.
+func (b *fallbackBackend) allow(now time.Time, cooldown time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if now.Before(b.openUntil) {
+		return false
+	}
+	if !b.openUntil.IsZero() {
+		b.openUntil = now.Add(cooldown)
+	}
+	return true
+}
+
+func (b *fallbackBackend) succeeded() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.openUntil = time.Time{}
+}
+
+func (b *fallbackBackend) failed(now time.Time, threshold int, cooldown time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures >= threshold {
+		b.openUntil = now.Add(cooldown)
+	}
+}
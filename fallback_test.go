@@ -0,0 +1,104 @@
+package gollama
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFallbackClient(t *testing.T) {
+	var primaryStatus atomic.Int32
+	primaryStatus.Store(529)
+	var primaryHits atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryHits.Add(1)
+		if s := int(primaryStatus.Load()); s != http.StatusOK {
+			http.Error(w, `{"type":"error"}`, s)
+			return
+		}
+		fmt.Fprint(w, anthropicOKBody)
+	}))
+	defer primary.Close()
+
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openaiRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "local-model" {
+			t.Errorf("secondary got model %q", req.Model)
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer secondary.Close()
+
+	f := NewFallbackClient([]FallbackBackend{
+		{Name: "anthropic", Client: NewClient(primary.URL, WithAnthropicMode(true), WithRetryPolicy(0, 0))},
+		{Client: NewClient(secondary.URL), Model: "local-model"},
+	}, FallbackOptions{FailureThreshold: 2, Cooldown: time.Minute})
+	now := time.Unix(1700000000, 0)
+	f.now = func() time.Time { return now }
+
+	opts := RequestOptions{Model: "claude", Messages: []Message{{Role: "user", Content: "hi"}}}
+	turn := func() string {
+		t.Helper()
+		resp, err := f.Turn(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Backend
+	}
+
+	// Two failures open the primary's breaker; after that it is skipped.
+	for i := 0; i < 3; i++ {
+		if got := turn(); got != "local-model" {
+			t.Fatalf("turn %d served by %q", i, got)
+		}
+	}
+	if n := primaryHits.Load(); n != 2 {
+		t.Errorf("primary hit %d times, want 2", n)
+	}
+	h := f.Health()
+	if h[0].Healthy || h[0].ConsecutiveFailures != 2 || !h[0].OpenUntil.Equal(now.Add(time.Minute)) || !h[1].Healthy {
+		t.Errorf("health = %+v", h)
+	}
+
+	// After the cooldown a probe goes through and closes the breaker.
+	primaryStatus.Store(http.StatusOK)
+	now = now.Add(2 * time.Minute)
+	if got := turn(); got != "anthropic" {
+		t.Errorf("after cooldown served by %q", got)
+	}
+	if h := f.Health(); !h[0].Healthy || h[0].ConsecutiveFailures != 0 {
+		t.Errorf("health after recovery = %+v", h[0])
+	}
+
+	// A 400 is the caller's problem and does not fall back.
+	primaryStatus.Store(http.StatusBadRequest)
+	var apiErr *APIError
+	if _, err := f.Turn(opts); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("err = %v, want the primary's 400", err)
+	}
+}
+
+func TestFallbackClientAllFail(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, WithRetryPolicy(0, 0))
+	f := NewFallbackClient([]FallbackBackend{{Name: "a", Client: c}, {Name: "b", Client: c}}, FallbackOptions{FailureThreshold: 1})
+
+	_, err := f.Turn(RequestOptions{Model: "m"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("err = %v", err)
+	}
+	if _, err := f.Turn(RequestOptions{Model: "m"}); !errors.Is(err, ErrNoHealthyBackend) {
+		t.Errorf("err = %v, want ErrNoHealthyBackend", err)
+	}
+}
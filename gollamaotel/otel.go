@@ -0,0 +1,152 @@
+// Package gollamaotel adapts OpenTelemetry tracers and meters to the
+// gollama.Tracer and gollama.Meter interfaces:
+//
+//	client := gollama.NewClient(url,
+//		gollama.WithTracer(gollamaotel.NewTracer(otel.Tracer("gollama"))),
+//		gollama.WithMeter(gollamaotel.NewMeter(otel.Meter("gollama"))),
+//	)
+//
+// It lives in its own module so that gollama itself does not depend on
+// OpenTelemetry.
+package gollamaotel
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/whyrusleeping/gollama"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer adapts an OpenTelemetry trace.Tracer to gollama.Tracer.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer returns a gollama.Tracer that starts spans with t.
+func NewTracer(t trace.Tracer) *Tracer {
+	return &Tracer{tracer: t}
+}
+
+// Start starts a span. Tool executions are internal spans; everything else
+// is a client span, as the GenAI semantic conventions specify.
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...gollama.Attr) (context.Context, gollama.Span) {
+	kind := trace.SpanKindClient
+	for _, a := range attrs {
+		if a.Key == "gen_ai.operation.name" && a.Value == "execute_tool" {
+			kind = trace.SpanKindInternal
+		}
+	}
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(convert(attrs)...))
+	return ctx, otelSpan{span}
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s otelSpan) SetAttributes(attrs ...gollama.Attr) {
+	s.span.SetAttributes(convert(attrs)...)
+}
+
+func (s otelSpan) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s otelSpan) End() {
+	s.span.End()
+}
+
+// Meter adapts an OpenTelemetry metric.Meter to gollama.Meter, creating
+// instruments on first use.
+type Meter struct {
+	meter metric.Meter
+
+	mu         sync.Mutex
+	counters   map[string]metric.Int64Counter
+	histograms map[string]metric.Float64Histogram
+}
+
+// NewMeter returns a gollama.Meter that records to m.
+func NewMeter(m metric.Meter) *Meter {
+	return &Meter{
+		meter:      m,
+		counters:   make(map[string]metric.Int64Counter),
+		histograms: make(map[string]metric.Float64Histogram),
+	}
+}
+
+// units gives the unit of each gollama metric.
+var units = map[string]string{
+	gollama.MetricOperationDuration: "s",
+	gollama.MetricTokenUsage:        "{token}",
+	gollama.MetricRequests:          "{request}",
+	gollama.MetricTokens:            "{token}",
+	gollama.MetricRetries:           "{retry}",
+}
+
+// Add adds value to the Int64Counter named name, creating it with the
+// metric's unit on first use. If the instrument cannot be created the value
+// is dropped.
+func (m *Meter) Add(ctx context.Context, name string, value int64, attrs ...gollama.Attr) {
+	m.mu.Lock()
+	c, ok := m.counters[name]
+	if !ok {
+		var err error
+		c, err = m.meter.Int64Counter(name, metric.WithUnit(units[name]))
+		if err != nil {
+			m.mu.Unlock()
+			return
+		}
+		m.counters[name] = c
+	}
+	m.mu.Unlock()
+	c.Add(ctx, value, metric.WithAttributes(convert(attrs)...))
+}
+
+// Record records value in the Float64Histogram named name, creating it with
+// the metric's unit on first use. If the instrument cannot be created the
+// value is dropped.
+func (m *Meter) Record(ctx context.Context, name string, value float64, attrs ...gollama.Attr) {
+	m.mu.Lock()
+	h, ok := m.histograms[name]
+	if !ok {
+		var err error
+		h, err = m.meter.Float64Histogram(name, metric.WithUnit(units[name]))
+		if err != nil {
+			m.mu.Unlock()
+			return
+		}
+		m.histograms[name] = h
+	}
+	m.mu.Unlock()
+	h.Record(ctx, value, metric.WithAttributes(convert(attrs)...))
+}
+
+func convert(attrs []gollama.Attr) []attribute.KeyValue {
+	out := make([]attribute.KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		switch v := a.Value.(type) {
+		case string:
+			out = append(out, attribute.String(a.Key, v))
+		case bool:
+			out = append(out, attribute.Bool(a.Key, v))
+		case int:
+			out = append(out, attribute.Int(a.Key, v))
+		case int64:
+			out = append(out, attribute.Int64(a.Key, v))
+		case float64:
+			out = append(out, attribute.Float64(a.Key, v))
+		case []string:
+			out = append(out, attribute.StringSlice(a.Key, v))
+		default:
+			out = append(out, attribute.String(a.Key, fmt.Sprint(v)))
+		}
+	}
+	return out
+}
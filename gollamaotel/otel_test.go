@@ -0,0 +1,76 @@
+package gollamaotel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/whyrusleeping/gollama"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestAdapter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"role":"assistant","model":"claude","stop_reason":"end_turn","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":3,"output_tokens":1}}`)
+	}))
+	defer srv.Close()
+
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	c := gollama.NewClient(srv.URL,
+		gollama.WithAnthropicMode(true),
+		gollama.WithTracer(NewTracer(tp.Tracer("test"))),
+		gollama.WithMeter(NewMeter(mp.Meter("test"))),
+	)
+	if _, err := c.Turn(gollama.RequestOptions{Model: "claude", Messages: []gollama.Message{{Role: "user", Content: "hi"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exp.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want chat + POST", len(spans))
+	}
+	post, chat := spans[0], spans[1]
+	if chat.Name != "chat claude" || chat.SpanKind != trace.SpanKindClient {
+		t.Errorf("chat span = %s (%v)", chat.Name, chat.SpanKind)
+	}
+	if post.Parent.SpanID() != chat.SpanContext.SpanID() {
+		t.Error("POST span is not a child of the chat span")
+	}
+	want := attribute.Int("gen_ai.usage.input_tokens", 3)
+	found := false
+	for _, kv := range chat.Attributes {
+		if kv == want {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("chat span attributes %v missing %v", chat.Attributes, want)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			names[m.Name] = true
+		}
+	}
+	for _, n := range []string{gollama.MetricOperationDuration, gollama.MetricTokenUsage, gollama.MetricRequests, gollama.MetricTokens} {
+		if !names[n] {
+			t.Errorf("metric %s not recorded", n)
+		}
+	}
+}
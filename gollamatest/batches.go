@@ -0,0 +1,128 @@
+package gollamatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// fakeBatch is a Message Batches API batch. Batches are processed as soon as
+// they are created, each request consuming the next scripted reply, so they
+// are already "ended" when first returned.
+type fakeBatch struct {
+	obj     map[string]any
+	results []map[string]any
+}
+
+// serveBatches implements the Message Batches API under /v1/messages/batches;
+// rest is the remainder of the path.
+func (s *Server) serveBatches(w http.ResponseWriter, r *http.Request, rest string, body []byte) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(rest, "/"), "/")
+
+	switch {
+	case r.Method == http.MethodPost && id == "":
+		s.createBatch(w, body)
+	case r.Method == http.MethodGet && id == "":
+		s.mu.Lock()
+		var data []any
+		for i := 1; i <= s.batchSeq; i++ {
+			if b, ok := s.batches[batchID(i)]; ok {
+				data = append(data, b.obj)
+			}
+		}
+		s.mu.Unlock()
+		list := map[string]any{"data": data, "has_more": false}
+		if len(data) > 0 {
+			list["first_id"] = data[0].(map[string]any)["id"]
+			list["last_id"] = data[len(data)-1].(map[string]any)["id"]
+		}
+		writeJSON(w, list)
+	default:
+		s.mu.Lock()
+		b, ok := s.batches[id]
+		s.mu.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			writeJSON(w, anthropicError(http.StatusNotFound))
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && action == "":
+			writeJSON(w, b.obj)
+		case r.Method == http.MethodPost && action == "cancel":
+			// Batches end immediately, so there is never anything to cancel.
+			writeJSON(w, b.obj)
+		case r.Method == http.MethodGet && action == "results":
+			w.Header().Set("Content-Type", "application/x-jsonl")
+			for _, res := range b.results {
+				writeJSON(w, res)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func (s *Server) createBatch(w http.ResponseWriter, body []byte) {
+	var req struct {
+		Requests []struct {
+			CustomID string `json:"custom_id"`
+			Params   struct {
+				Model string `json:"model"`
+			} `json:"params"`
+		} `json:"requests"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, anthropicError(http.StatusBadRequest))
+		return
+	}
+
+	b := &fakeBatch{}
+	succeeded, errored := 0, 0
+	for _, r := range req.Requests {
+		rep := s.next()
+		if rep.Model == "" {
+			rep.Model = r.Params.Model
+		}
+		result := map[string]any{"type": "succeeded", "message": anthropicMessage(rep)}
+		if rep.Status != 0 && rep.Status != http.StatusOK {
+			result = map[string]any{"type": "errored", "error": anthropicError(rep.Status)}
+			errored++
+		} else {
+			succeeded++
+		}
+		b.results = append(b.results, map[string]any{"custom_id": r.CustomID, "result": result})
+	}
+
+	s.mu.Lock()
+	s.batchSeq++
+	id := batchID(s.batchSeq)
+	now := time.Now().UTC()
+	b.obj = map[string]any{
+		"id":                id,
+		"type":              "message_batch",
+		"processing_status": "ended",
+		"request_counts": map[string]int{
+			"processing": 0,
+			"succeeded":  succeeded,
+			"errored":    errored,
+			"canceled":   0,
+			"expired":    0,
+		},
+		"created_at":  now.Format(time.RFC3339),
+		"ended_at":    now.Format(time.RFC3339),
+		"expires_at":  now.Add(24 * time.Hour).Format(time.RFC3339),
+		"results_url": s.URL + "/v1/messages/batches/" + id + "/results",
+	}
+	s.batches[id] = b
+	s.mu.Unlock()
+
+	writeJSON(w, b.obj)
+}
+
+func batchID(n int) string {
+	return fmt.Sprintf("msgbatch_%d", n)
+}
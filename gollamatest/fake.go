@@ -0,0 +1,357 @@
+package gollamatest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/whyrusleeping/gollama"
+)
+
+// Fake is a scripted gollama.Turner for unit-testing agent code without an
+// HTTP server. Each turn is answered by the first matching rule registered
+// with When, otherwise by the next reply queued with Enqueue, otherwise by
+// the default set with SetDefault. A turn with nothing to answer it fails
+// the test. Every RequestOptions received is recorded for the Assert
+// helpers:
+//
+//	fake := gollamatest.NewFake(t)
+//	fake.Enqueue(gollamatest.ToolUse("search", map[string]any{"q": "go"}))
+//	fake.When(gollamatest.ToolResultContains("golang.org")).Respond(gollamatest.Text("found it"))
+//	runAgent(fake) // code under test depends on gollama.Turner
+//	fake.AssertCallCount(2)
+//	fake.AssertToolsOffered(0, "search")
+//
+// Replies are converted to responses the way the Anthropic backend would
+// produce them; Status yields a *gollama.APIError, Malformed a decode error,
+// and Delay is waited unless the context is cancelled first. Fake is safe
+// for concurrent use.
+type Fake struct {
+	t testing.TB
+
+	mu      sync.Mutex
+	rules   []*Rule
+	replies []Reply
+	def     *Reply
+	calls   []gollama.RequestOptions
+	toolID  int
+}
+
+var _ gollama.Turner = (*Fake)(nil)
+
+// NewFake returns a Fake that reports unscripted turns to t.
+func NewFake(t testing.TB) *Fake {
+	return &Fake{t: t}
+}
+
+// Matcher selects the turns a Rule answers.
+type Matcher func(opts gollama.RequestOptions) bool
+
+// Rule answers every turn its Matcher selects, up to an optional limit.
+type Rule struct {
+	match   Matcher
+	replies []Reply
+	limit   int
+	used    int
+}
+
+// Respond sets the replies given to matching turns, in order. Once they are
+// exhausted the last one is repeated.
+func (r *Rule) Respond(replies ...Reply) *Rule {
+	r.replies = replies
+	return r
+}
+
+// Times limits the rule to n matching turns, after which it is skipped.
+// AssertConsumed reports limited rules that were not used n times.
+func (r *Rule) Times(n int) *Rule {
+	r.limit = n
+	return r
+}
+
+// When registers a rule answering turns selected by m. Rules are tried in
+// the order they were registered, before any queued replies.
+func (f *Fake) When(m Matcher) *Rule {
+	r := &Rule{match: m}
+	f.mu.Lock()
+	f.rules = append(f.rules, r)
+	f.mu.Unlock()
+	return r
+}
+
+// Enqueue appends replies answering turns that no rule matches.
+func (f *Fake) Enqueue(replies ...Reply) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.replies = append(f.replies, replies...)
+}
+
+// SetDefault sets the reply used when no rule matches and no replies are
+// queued. Without a default such turns fail the test.
+func (f *Fake) SetDefault(r Reply) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.def = &r
+}
+
+// Pending returns the number of queued replies not yet consumed.
+func (f *Fake) Pending() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.replies)
+}
+
+// Turn implements gollama.Turner.
+func (f *Fake) Turn(opts gollama.RequestOptions) (*gollama.ResponseMessageGenerate, error) {
+	return f.TurnContext(context.Background(), opts)
+}
+
+// TurnContext implements gollama.Turner.
+func (f *Fake) TurnContext(ctx context.Context, opts gollama.RequestOptions) (*gollama.ResponseMessageGenerate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	rep, ok := f.next(opts)
+	if !ok {
+		f.t.Errorf("gollamatest: unscripted turn %d, last message %s", len(f.Calls())-1, describeLast(opts))
+		return nil, errors.New("gollamatest: no scripted reply")
+	}
+	if rep.Delay > 0 {
+		select {
+		case <-time.After(rep.Delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if rep.Model == "" {
+		rep.Model = opts.Model
+	}
+	meta := &gollama.ResponseMeta{StatusCode: http.StatusOK, Header: rep.Header}
+
+	switch {
+	case rep.Status != 0 && rep.Status != http.StatusOK:
+		body, _ := json.Marshal(anthropicError(rep.Status))
+		meta.StatusCode = rep.Status
+		return nil, &gollama.APIError{StatusCode: rep.Status, Body: string(body), Meta: meta}
+	case rep.Malformed:
+		return nil, errors.New("error decoding Anthropic response: unexpected EOF")
+	}
+	return fakeResponse(rep, meta), nil
+}
+
+// next records opts and picks the reply for it.
+func (f *Fake) next(opts gollama.RequestOptions) (Reply, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, opts)
+
+	var rep Reply
+	found := false
+	for _, r := range f.rules {
+		if (r.limit > 0 && r.used >= r.limit) || len(r.replies) == 0 || !r.match(opts) {
+			continue
+		}
+		rep = r.replies[min(r.used, len(r.replies)-1)]
+		r.used++
+		found = true
+		break
+	}
+	if !found && len(f.replies) > 0 {
+		rep = f.replies[0]
+		f.replies = f.replies[1:]
+		found = true
+	}
+	if !found && f.def != nil {
+		rep = *f.def
+		found = true
+	}
+
+	rep.ToolCalls = append([]ToolCall(nil), rep.ToolCalls...)
+	for i := range rep.ToolCalls {
+		if rep.ToolCalls[i].ID == "" {
+			f.toolID++
+			rep.ToolCalls[i].ID = fmt.Sprintf("toolu_%d", f.toolID)
+		}
+	}
+	return rep, found
+}
+
+// fakeResponse converts rep into the response the Anthropic backend would
+// return for it.
+func fakeResponse(rep Reply, meta *gollama.ResponseMeta) *gollama.ResponseMessageGenerate {
+	msg := gollama.Message{Role: "assistant", Content: rep.Text, Thinking: rep.Thinking}
+	if rep.Thinking != "" {
+		msg.ThinkingBlocks = []gollama.ThinkingBlock{{Thinking: rep.Thinking, Signature: "fake-signature"}}
+	}
+	for _, tc := range rep.ToolCalls {
+		args, _ := json.Marshal(toolInput(tc))
+		msg.ToolCalls = append(msg.ToolCalls, gollama.ToolCall{
+			ID:       tc.ID,
+			Type:     "function",
+			Function: gollama.ToolCallFunction{Name: tc.Name, Arguments: string(args)},
+		})
+	}
+	stop := anthropicStopReason(rep)
+	return &gollama.ResponseMessageGenerate{
+		Model:      rep.Model,
+		Choices:    []gollama.GenChoice{{Message: msg, FinishReason: stop}},
+		Done:       true,
+		StopReason: stop,
+		Usage: gollama.Usage{
+			PromptTokens:     rep.Usage.InputTokens,
+			CompletionTokens: rep.Usage.OutputTokens,
+			TotalTokens:      rep.Usage.InputTokens + rep.Usage.OutputTokens,
+		},
+		Meta: meta,
+	}
+}
+
+// ============== matchers ==============
+
+// Any matches every turn.
+func Any() Matcher {
+	return func(gollama.RequestOptions) bool { return true }
+}
+
+// LastUserContains matches turns whose last message is from the user and
+// contains s.
+func LastUserContains(s string) Matcher {
+	return func(opts gollama.RequestOptions) bool {
+		n := len(opts.Messages)
+		return n > 0 && opts.Messages[n-1].Role == "user" && strings.Contains(messageText(opts.Messages[n-1]), s)
+	}
+}
+
+// ToolResultContains matches turns that end with tool results, one of which
+// contains s.
+func ToolResultContains(s string) Matcher {
+	return func(opts gollama.RequestOptions) bool {
+		for i := len(opts.Messages) - 1; i >= 0 && opts.Messages[i].Role == "tool"; i-- {
+			if strings.Contains(messageText(opts.Messages[i]), s) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// HasTool matches turns that offer a tool with the given name.
+func HasTool(name string) Matcher {
+	return func(opts gollama.RequestOptions) bool {
+		return offersTool(opts, name)
+	}
+}
+
+// messageText returns the text of m, including text content blocks.
+func messageText(m gollama.Message) string {
+	if len(m.MultiContent) == 0 {
+		return m.Content
+	}
+	var sb strings.Builder
+	for _, b := range m.MultiContent {
+		sb.WriteString(b.Text)
+	}
+	return sb.String()
+}
+
+func offersTool(opts gollama.RequestOptions, name string) bool {
+	for _, tp := range opts.Tools {
+		if tp.Function != nil && tp.Function.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func describeLast(opts gollama.RequestOptions) string {
+	if len(opts.Messages) == 0 {
+		return "(none)"
+	}
+	m := opts.Messages[len(opts.Messages)-1]
+	return fmt.Sprintf("%s %q", m.Role, messageText(m))
+}
+
+// ============== assertions ==============
+
+// Calls returns the RequestOptions of every turn so far.
+func (f *Fake) Calls() []gollama.RequestOptions {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]gollama.RequestOptions(nil), f.calls...)
+}
+
+// Call returns the RequestOptions of turn i, failing the test if there was
+// no such turn.
+func (f *Fake) Call(i int) gollama.RequestOptions {
+	f.t.Helper()
+	calls := f.Calls()
+	if i < 0 || i >= len(calls) {
+		f.t.Fatalf("gollamatest: turn %d requested, but only %d turns were taken", i, len(calls))
+	}
+	return calls[i]
+}
+
+// AssertCallCount checks that exactly n turns were taken.
+func (f *Fake) AssertCallCount(n int) {
+	f.t.Helper()
+	if got := len(f.Calls()); got != n {
+		f.t.Errorf("gollamatest: %d turns taken, want %d", got, n)
+	}
+}
+
+// AssertToolsOffered checks that turn i offered each of the named tools.
+func (f *Fake) AssertToolsOffered(i int, names ...string) {
+	f.t.Helper()
+	opts := f.Call(i)
+	for _, name := range names {
+		if !offersTool(opts, name) {
+			f.t.Errorf("gollamatest: turn %d did not offer tool %q", i, name)
+		}
+	}
+}
+
+// AssertLastMessage checks that the last message of turn i has the given
+// role and contains substr.
+func (f *Fake) AssertLastMessage(i int, role, substr string) {
+	f.t.Helper()
+	opts := f.Call(i)
+	if len(opts.Messages) == 0 {
+		f.t.Errorf("gollamatest: turn %d had no messages", i)
+		return
+	}
+	m := opts.Messages[len(opts.Messages)-1]
+	if m.Role != role || !strings.Contains(messageText(m), substr) {
+		f.t.Errorf("gollamatest: turn %d last message is %s, want %s containing %q", i, describeLast(opts), role, substr)
+	}
+}
+
+// AssertCall runs check against the RequestOptions of turn i and reports
+// its error.
+func (f *Fake) AssertCall(i int, check func(gollama.RequestOptions) error) {
+	f.t.Helper()
+	if err := check(f.Call(i)); err != nil {
+		f.t.Errorf("gollamatest: turn %d: %v", i, err)
+	}
+}
+
+// AssertConsumed checks that every queued reply was used and every rule
+// limited with Times was matched as often as its limit.
+func (f *Fake) AssertConsumed() {
+	f.t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.replies) > 0 {
+		f.t.Errorf("gollamatest: %d queued replies not used", len(f.replies))
+	}
+	for i, r := range f.rules {
+		if r.limit > 0 && r.used < r.limit {
+			f.t.Errorf("gollamatest: rule %d matched %d of %d times", i, r.used, r.limit)
+		}
+	}
+}
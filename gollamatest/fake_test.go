@@ -0,0 +1,96 @@
+package gollamatest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/whyrusleeping/gollama"
+	"github.com/whyrusleeping/gollama/gollamatest"
+)
+
+func TestFake(t *testing.T) {
+	fake := gollamatest.NewFake(t)
+	fake.Enqueue(gollamatest.ToolUse("search", map[string]any{"q": "go"}))
+	fake.When(gollamatest.ToolResultContains("golang.org")).Respond(gollamatest.Text("found it")).Times(1)
+
+	var turner gollama.Turner = fake
+	req := gollama.RequestOptions{
+		Model:    "fake-model",
+		Messages: []gollama.Message{{Role: "user", Content: "find go"}},
+		Tools:    []gollama.ToolParam{{Type: "function", Function: &gollama.ToolFunction{Name: "search"}}},
+	}
+	resp, err := turner.Turn(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := resp.Choices[0].Message.ToolCalls[0]
+	if resp.StopReason != "tool_use" || call.Function.Name != "search" || call.Function.Arguments != `{"q":"go"}` || call.ID != "toolu_1" {
+		t.Errorf("first turn = %+v", resp)
+	}
+
+	req.Messages = append(req.Messages, resp.Choices[0].Message,
+		gollama.Message{Role: "tool", ToolCallID: call.ID, Content: "see https://golang.org"})
+	resp, err = turner.TurnContext(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Choices[0].Message.Content != "found it" || resp.StopReason != "end_turn" || resp.Model != "fake-model" {
+		t.Errorf("second turn = %+v", resp)
+	}
+
+	fake.AssertCallCount(2)
+	fake.AssertToolsOffered(0, "search")
+	fake.AssertLastMessage(0, "user", "find go")
+	fake.AssertLastMessage(1, "tool", "golang.org")
+	fake.AssertCall(1, func(opts gollama.RequestOptions) error {
+		if len(opts.Messages) != 3 {
+			return fmt.Errorf("%d messages", len(opts.Messages))
+		}
+		return nil
+	})
+	fake.AssertConsumed()
+}
+
+func TestFakeErrors(t *testing.T) {
+	fake := gollamatest.NewFake(t)
+	fake.Enqueue(gollamatest.Error(529), gollamatest.Malformed())
+	fake.SetDefault(gollamatest.Text("default"))
+
+	var apiErr *gollama.APIError
+	if _, err := fake.Turn(opts); !errors.As(err, &apiErr) || apiErr.StatusCode != 529 {
+		t.Errorf("err = %v", err)
+	}
+	if _, err := fake.Turn(opts); err == nil {
+		t.Error("expected a decode error for a malformed reply")
+	}
+	if resp, err := fake.Turn(opts); err != nil || resp.Choices[0].Message.Content != "default" {
+		t.Errorf("default turn = %+v, %v", resp, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fake.TurnContext(ctx, opts); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled turn err = %v", err)
+	}
+}
+
+func TestFakeUnscripted(t *testing.T) {
+	rec := &recordingTB{TB: t}
+	fake := gollamatest.NewFake(rec)
+	if _, err := fake.Turn(opts); err == nil {
+		t.Error("expected an error for an unscripted turn")
+	}
+	if !rec.failed {
+		t.Error("unscripted turn did not fail the test")
+	}
+}
+
+// recordingTB records failures instead of reporting them.
+type recordingTB struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recordingTB) Errorf(string, ...any) { r.failed = true }
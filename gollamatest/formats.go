@@ -0,0 +1,384 @@
+package gollamatest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"time"
+)
+
+// format renders replies in one provider's wire format.
+type format struct {
+	body      func(Reply) any
+	stream    func(http.ResponseWriter, Reply)
+	errorBody func(status int) any
+}
+
+var (
+	anthropicFormat = format{
+		body:      anthropicMessage,
+		stream:    anthropicStream,
+		errorBody: anthropicError,
+	}
+	openaiFormat = format{
+		body:      openaiCompletion,
+		stream:    openaiStream,
+		errorBody: openaiError,
+	}
+	ollamaChatFormat = format{
+		body:      ollamaChat,
+		stream:    ollamaStream(ollamaChat),
+		errorBody: ollamaError,
+	}
+	ollamaGenerateFormat = format{
+		body:      ollamaGenerate,
+		stream:    ollamaStream(ollamaGenerate),
+		errorBody: ollamaError,
+	}
+	bedrockFormat = format{
+		body:      anthropicMessage,
+		stream:    bedrockStream,
+		errorBody: bedrockError,
+	}
+)
+
+// ============== Anthropic ==============
+
+func anthropicStopReason(r Reply) string {
+	switch {
+	case r.StopReason != "":
+		return r.StopReason
+	case len(r.ToolCalls) > 0:
+		return "tool_use"
+	default:
+		return "end_turn"
+	}
+}
+
+// anthropicBlocks returns the content blocks of r.
+func anthropicBlocks(r Reply) []map[string]any {
+	var blocks []map[string]any
+	if r.Thinking != "" {
+		blocks = append(blocks, map[string]any{"type": "thinking", "thinking": r.Thinking, "signature": "fake-signature"})
+	}
+	if r.Text != "" || len(r.ToolCalls) == 0 {
+		blocks = append(blocks, map[string]any{"type": "text", "text": r.Text})
+	}
+	for _, tc := range r.ToolCalls {
+		blocks = append(blocks, map[string]any{"type": "tool_use", "id": tc.ID, "name": tc.Name, "input": toolInput(tc)})
+	}
+	return blocks
+}
+
+func anthropicMessage(r Reply) any {
+	return map[string]any{
+		"id":            "msg_fake",
+		"type":          "message",
+		"role":          "assistant",
+		"model":         r.Model,
+		"content":       anthropicBlocks(r),
+		"stop_reason":   anthropicStopReason(r),
+		"stop_sequence": nil,
+		"usage":         map[string]any{"input_tokens": r.Usage.InputTokens, "output_tokens": r.Usage.OutputTokens},
+	}
+}
+
+// anthropicEvents returns the server-sent events streaming r, as
+// (event type, data) pairs.
+func anthropicEvents(r Reply) [][2]any {
+	start := anthropicMessage(r).(map[string]any)
+	start["content"] = []any{}
+	start["stop_reason"] = nil
+	start["usage"] = map[string]any{"input_tokens": r.Usage.InputTokens, "output_tokens": 0}
+
+	events := [][2]any{{"message_start", map[string]any{"type": "message_start", "message": start}}}
+	for i, b := range anthropicBlocks(r) {
+		var empty, delta map[string]any
+		switch b["type"] {
+		case "thinking":
+			empty = map[string]any{"type": "thinking", "thinking": ""}
+			delta = map[string]any{"type": "thinking_delta", "thinking": b["thinking"]}
+		case "text":
+			empty = map[string]any{"type": "text", "text": ""}
+			delta = map[string]any{"type": "text_delta", "text": b["text"]}
+		case "tool_use":
+			empty = map[string]any{"type": "tool_use", "id": b["id"], "name": b["name"], "input": map[string]any{}}
+			input, _ := json.Marshal(b["input"])
+			delta = map[string]any{"type": "input_json_delta", "partial_json": string(input)}
+		}
+		events = append(events,
+			[2]any{"content_block_start", map[string]any{"type": "content_block_start", "index": i, "content_block": empty}},
+			[2]any{"content_block_delta", map[string]any{"type": "content_block_delta", "index": i, "delta": delta}},
+		)
+		if b["type"] == "thinking" {
+			events = append(events, [2]any{"content_block_delta", map[string]any{"type": "content_block_delta", "index": i,
+				"delta": map[string]any{"type": "signature_delta", "signature": b["signature"]}}})
+		}
+		events = append(events, [2]any{"content_block_stop", map[string]any{"type": "content_block_stop", "index": i}})
+	}
+	return append(events,
+		[2]any{"message_delta", map[string]any{"type": "message_delta",
+			"delta": map[string]any{"stop_reason": anthropicStopReason(r), "stop_sequence": nil},
+			"usage": map[string]any{"output_tokens": r.Usage.OutputTokens}}},
+		[2]any{"message_stop", map[string]any{"type": "message_stop"}},
+	)
+}
+
+func anthropicStream(w http.ResponseWriter, r Reply) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, ev := range anthropicEvents(r) {
+		data, _ := json.Marshal(ev[1])
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev[0], data)
+		flush(w)
+	}
+}
+
+func anthropicError(status int) any {
+	types := map[int]string{
+		400: "invalid_request_error",
+		401: "authentication_error",
+		403: "permission_error",
+		404: "not_found_error",
+		413: "request_too_large",
+		429: "rate_limit_error",
+		529: "overloaded_error",
+	}
+	t, ok := types[status]
+	if !ok {
+		t = "api_error"
+	}
+	return map[string]any{"type": "error", "error": map[string]any{"type": t, "message": http.StatusText(status)}}
+}
+
+// ============== OpenAI ==============
+
+func openaiFinishReason(r Reply) string {
+	switch {
+	case r.StopReason != "":
+		return r.StopReason
+	case len(r.ToolCalls) > 0:
+		return "tool_calls"
+	default:
+		return "stop"
+	}
+}
+
+func openaiToolCalls(r Reply, withIndex bool) []map[string]any {
+	var calls []map[string]any
+	for i, tc := range r.ToolCalls {
+		args, _ := json.Marshal(toolInput(tc))
+		call := map[string]any{
+			"id":       tc.ID,
+			"type":     "function",
+			"function": map[string]any{"name": tc.Name, "arguments": string(args)},
+		}
+		if withIndex {
+			call["index"] = i
+		}
+		calls = append(calls, call)
+	}
+	return calls
+}
+
+func openaiUsage(r Reply) map[string]any {
+	return map[string]any{
+		"prompt_tokens":     r.Usage.InputTokens,
+		"completion_tokens": r.Usage.OutputTokens,
+		"total_tokens":      r.Usage.InputTokens + r.Usage.OutputTokens,
+	}
+}
+
+func openaiCompletion(r Reply) any {
+	msg := map[string]any{"role": "assistant", "content": r.Text}
+	if r.Thinking != "" {
+		msg["reasoning_content"] = r.Thinking
+	}
+	if calls := openaiToolCalls(r, false); len(calls) > 0 {
+		msg["tool_calls"] = calls
+	}
+	return map[string]any{
+		"id":      "chatcmpl-fake",
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   r.Model,
+		"choices": []any{map[string]any{"index": 0, "message": msg, "finish_reason": openaiFinishReason(r)}},
+		"usage":   openaiUsage(r),
+	}
+}
+
+func openaiStream(w http.ResponseWriter, r Reply) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	chunk := func(delta map[string]any, finish any, usage any) {
+		data, _ := json.Marshal(map[string]any{
+			"id":      "chatcmpl-fake",
+			"object":  "chat.completion.chunk",
+			"created": time.Now().Unix(),
+			"model":   r.Model,
+			"choices": []any{map[string]any{"index": 0, "delta": delta, "finish_reason": finish}},
+			"usage":   usage,
+		})
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		flush(w)
+	}
+
+	chunk(map[string]any{"role": "assistant", "content": ""}, nil, nil)
+	if r.Thinking != "" {
+		chunk(map[string]any{"reasoning_content": r.Thinking}, nil, nil)
+	}
+	if r.Text != "" {
+		chunk(map[string]any{"content": r.Text}, nil, nil)
+	}
+	if calls := openaiToolCalls(r, true); len(calls) > 0 {
+		chunk(map[string]any{"tool_calls": calls}, nil, nil)
+	}
+	chunk(map[string]any{}, openaiFinishReason(r), openaiUsage(r))
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	flush(w)
+}
+
+func openaiError(status int) any {
+	t := "invalid_request_error"
+	switch {
+	case status == 429:
+		t = "rate_limit_exceeded"
+	case status >= 500:
+		t = "server_error"
+	}
+	return map[string]any{"error": map[string]any{"message": http.StatusText(status), "type": t, "code": nil}}
+}
+
+// ============== Ollama ==============
+
+func ollamaStopReason(r Reply) string {
+	if r.StopReason != "" {
+		return r.StopReason
+	}
+	return "stop"
+}
+
+func ollamaChat(r Reply) any {
+	msg := map[string]any{"role": "assistant", "content": r.Text}
+	if r.Thinking != "" {
+		msg["thinking"] = r.Thinking
+	}
+	var calls []any
+	for _, tc := range r.ToolCalls {
+		calls = append(calls, map[string]any{"function": map[string]any{"name": tc.Name, "arguments": toolInput(tc)}})
+	}
+	if len(calls) > 0 {
+		msg["tool_calls"] = calls
+	}
+	return map[string]any{
+		"model":             r.Model,
+		"created_at":        time.Now().UTC().Format(time.RFC3339Nano),
+		"message":           msg,
+		"done":              true,
+		"done_reason":       ollamaStopReason(r),
+		"prompt_eval_count": r.Usage.InputTokens,
+		"eval_count":        r.Usage.OutputTokens,
+	}
+}
+
+func ollamaGenerate(r Reply) any {
+	return map[string]any{
+		"model":             r.Model,
+		"created_at":        time.Now().UTC().Format(time.RFC3339Nano),
+		"response":          r.Text,
+		"done":              true,
+		"done_reason":       ollamaStopReason(r),
+		"prompt_eval_count": r.Usage.InputTokens,
+		"eval_count":        r.Usage.OutputTokens,
+	}
+}
+
+// ollamaStream streams r as newline-delimited JSON: one chunk with the
+// content and a final empty chunk with done set.
+func ollamaStream(body func(Reply) any) func(http.ResponseWriter, Reply) {
+	return func(w http.ResponseWriter, r Reply) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		first := body(r).(map[string]any)
+		first["done"] = false
+		delete(first, "done_reason")
+		delete(first, "prompt_eval_count")
+		delete(first, "eval_count")
+		writeJSON(w, first)
+		flush(w)
+
+		last := body(Reply{Model: r.Model, StopReason: r.StopReason, Usage: r.Usage}).(map[string]any)
+		writeJSON(w, last)
+		flush(w)
+	}
+}
+
+func ollamaError(status int) any {
+	return map[string]any{"error": http.StatusText(status)}
+}
+
+// ============== Bedrock ==============
+
+// bedrockStream streams r in the AWS event stream encoding used by
+// invoke-with-response-stream: each Anthropic streaming event is a "chunk"
+// message whose payload carries the event JSON base64-encoded.
+func bedrockStream(w http.ResponseWriter, r Reply) {
+	w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+	for _, ev := range anthropicEvents(r) {
+		data, _ := json.Marshal(ev[1])
+		payload, _ := json.Marshal(map[string]string{"bytes": base64.StdEncoding.EncodeToString(data)})
+		w.Write(eventStreamMessage(map[string]string{
+			":event-type":   "chunk",
+			":content-type": "application/json",
+			":message-type": "event",
+		}, payload))
+		flush(w)
+	}
+}
+
+// eventStreamMessage encodes one AWS event stream message.
+func eventStreamMessage(headers map[string]string, payload []byte) []byte {
+	var hdr bytes.Buffer
+	for _, k := range []string{":event-type", ":content-type", ":message-type"} {
+		v, ok := headers[k]
+		if !ok {
+			continue
+		}
+		hdr.WriteByte(byte(len(k)))
+		hdr.WriteString(k)
+		hdr.WriteByte(7) // string value
+		binary.Write(&hdr, binary.BigEndian, uint16(len(v)))
+		hdr.WriteString(v)
+	}
+
+	var msg bytes.Buffer
+	total := 12 + hdr.Len() + len(payload) + 4
+	binary.Write(&msg, binary.BigEndian, uint32(total))
+	binary.Write(&msg, binary.BigEndian, uint32(hdr.Len()))
+	binary.Write(&msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	msg.Write(hdr.Bytes())
+	msg.Write(payload)
+	binary.Write(&msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	return msg.Bytes()
+}
+
+func bedrockError(status int) any {
+	return map[string]any{"message": http.StatusText(status)}
+}
+
+// ============== helpers ==============
+
+// toolInput returns a tool call's input, defaulting to an empty object.
+func toolInput(tc ToolCall) any {
+	if tc.Input == nil {
+		return map[string]any{}
+	}
+	return tc.Input
+}
+
+func flush(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,283 @@
+// Package gollamatest provides an in-process fake LLM server for testing code
+// built on gollama.Client without network access or hand-written fixtures.
+//
+// A single Server answers the endpoints of every provider gollama supports:
+// Anthropic /v1/messages and the Message Batches API, OpenAI-compatible
+// /chat/completions and /models (with or without a /v1 prefix), Ollama
+// /api/chat and /api/generate, and Bedrock /model/{id}/invoke and
+// invoke-with-response-stream. Responses are scripted with Enqueue, errors
+// can be injected, and every request is captured:
+//
+//	srv := gollamatest.NewServer(t)
+//	srv.Enqueue(gollamatest.Error(529), gollamatest.ToolUse("search", map[string]any{"q": "go"}))
+//	client := gollama.NewClient(srv.URL, gollama.WithAnthropicMode(true))
+//	// ... exercise the code under test ...
+//	var body map[string]any
+//	srv.LastRequest().JSON(&body)
+package gollamatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Reply scripts one response from the Server. The zero Reply is an empty
+// text response.
+type Reply struct {
+	Text      string
+	Thinking  string
+	ToolCalls []ToolCall
+	// StopReason overrides the provider's stop reason, e.g. "max_tokens".
+	// By default it is the provider's "finished" or "tool use" reason.
+	StopReason string
+	Model      string // defaults to the requested model
+	Usage      Usage
+
+	// Status, if not 200, makes the server answer with a provider-style
+	// error body and this status code.
+	Status int
+	// Malformed makes the server answer 200 with a body that is not valid
+	// JSON.
+	Malformed bool
+	// Header is added to the response.
+	Header http.Header
+	// Delay is waited before responding, or until the client goes away.
+	Delay time.Duration
+}
+
+// ToolCall is a tool invocation in a scripted Reply.
+type ToolCall struct {
+	ID    string // defaults to "toolu_<n>"
+	Name  string
+	Input any
+}
+
+// Usage is the token usage reported with a Reply.
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+}
+
+// Text returns a Reply with the given text.
+func Text(s string) Reply {
+	return Reply{Text: s}
+}
+
+// ToolUse returns a Reply calling a single tool.
+func ToolUse(name string, input any) Reply {
+	return Reply{ToolCalls: []ToolCall{{Name: name, Input: input}}}
+}
+
+// Error returns a Reply failing with the given HTTP status, such as 429 or
+// 529.
+func Error(status int) Reply {
+	return Reply{Status: status}
+}
+
+// Malformed returns a Reply whose body is not valid JSON.
+func Malformed() Reply {
+	return Reply{Malformed: true}
+}
+
+// Request is a request captured by the Server.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// JSON decodes the request body into v.
+func (r Request) JSON(v any) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// Server is a fake multi-provider LLM server. Scripted replies are consumed in
+// order by whichever model endpoint is called next; when none are queued,
+// Default is used. It is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	// Models is listed by /models.
+	Models []string
+
+	mu       sync.Mutex
+	replies  []Reply
+	def      Reply
+	requests []Request
+	toolID   int
+	batches  map[string]*fakeBatch
+	batchSeq int
+}
+
+// NewServer starts a Server that is closed when the test finishes.
+func NewServer(t testing.TB) *Server {
+	s := &Server{
+		Models:  []string{"fake-model"},
+		def:     Text("ok"),
+		batches: make(map[string]*fakeBatch),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Enqueue appends scripted replies.
+func (s *Server) Enqueue(replies ...Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies = append(s.replies, replies...)
+}
+
+// SetDefault sets the reply used when no scripted replies are queued
+// (initially Text("ok")).
+func (s *Server) SetDefault(r Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.def = r
+}
+
+// Pending returns the number of queued replies not yet consumed.
+func (s *Server) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.replies)
+}
+
+// Requests returns every request received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// LastRequest returns the most recent request, or a zero Request if there
+// was none.
+func (s *Server) LastRequest() Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) == 0 {
+		return Request{}
+	}
+	return s.requests[len(s.requests)-1]
+}
+
+// next pops the next scripted reply and fills in tool call IDs.
+func (s *Server) next() Reply {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.def
+	if len(s.replies) > 0 {
+		r = s.replies[0]
+		s.replies = s.replies[1:]
+	}
+	r.ToolCalls = append([]ToolCall(nil), r.ToolCalls...)
+	for i := range r.ToolCalls {
+		if r.ToolCalls[i].ID == "" {
+			s.toolID++
+			r.ToolCalls[i].ID = fmt.Sprintf("toolu_%d", s.toolID)
+		}
+	}
+	return r
+}
+
+// modelRequest holds the fields of a chat request the server looks at.
+type modelRequest struct {
+	Model  string `json:"model"`
+	Stream bool   `json:"stream"`
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Header: r.Header.Clone(), Body: body})
+	s.mu.Unlock()
+
+	path := r.URL.Path
+	var req modelRequest
+	json.Unmarshal(body, &req)
+
+	switch {
+	case r.Method == http.MethodPost && path == "/v1/messages":
+		s.reply(w, r, anthropicFormat, req.Model, req.Stream)
+	case strings.HasPrefix(path, "/v1/messages/batches"):
+		s.serveBatches(w, r, strings.TrimPrefix(path, "/v1/messages/batches"), body)
+	case r.Method == http.MethodPost && (path == "/chat/completions" || path == "/v1/chat/completions"):
+		s.reply(w, r, openaiFormat, req.Model, req.Stream)
+	case r.Method == http.MethodGet && (path == "/models" || path == "/v1/models"):
+		s.serveModels(w)
+	case r.Method == http.MethodPost && path == "/api/chat":
+		s.reply(w, r, ollamaChatFormat, req.Model, req.Stream)
+	case r.Method == http.MethodPost && path == "/api/generate":
+		s.reply(w, r, ollamaGenerateFormat, req.Model, req.Stream)
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "/model/"):
+		model, action, _ := strings.Cut(strings.TrimPrefix(path, "/model/"), "/")
+		switch action {
+		case "invoke":
+			s.reply(w, r, bedrockFormat, model, false)
+		case "invoke-with-response-stream":
+			s.reply(w, r, bedrockFormat, model, true)
+		default:
+			http.NotFound(w, r)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// reply answers a model request with the next scripted reply in format f.
+func (s *Server) reply(w http.ResponseWriter, r *http.Request, f format, model string, stream bool) {
+	rep := s.next()
+	if rep.Model == "" {
+		rep.Model = model
+	}
+	if rep.Delay > 0 {
+		select {
+		case <-time.After(rep.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	for k, vs := range rep.Header {
+		w.Header()[k] = vs
+	}
+
+	switch {
+	case rep.Status != 0 && rep.Status != http.StatusOK:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(rep.Status)
+		writeJSON(w, f.errorBody(rep.Status))
+	case rep.Malformed:
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"malformed": `)
+	case stream:
+		f.stream(w, rep)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, f.body(rep))
+	}
+}
+
+func (s *Server) serveModels(w http.ResponseWriter) {
+	type model struct {
+		ID      string `json:"id"`
+		Object  string `json:"object"`
+		OwnedBy string `json:"owned_by"`
+	}
+	var data []model
+	for _, m := range s.Models {
+		data = append(data, model{ID: m, Object: "model", OwnedBy: "gollamatest"})
+	}
+	writeJSON(w, map[string]any{"object": "list", "data": data})
+}
+
+func writeJSON(w io.Writer, v any) {
+	json.NewEncoder(w).Encode(v)
+}
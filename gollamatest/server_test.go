@@ -0,0 +1,216 @@
+package gollamatest_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/whyrusleeping/gollama"
+	"github.com/whyrusleeping/gollama/gollamatest"
+)
+
+var opts = gollama.RequestOptions{Model: "fake-model", Messages: []gollama.Message{{Role: "user", Content: "hi"}}}
+
+func TestProviders(t *testing.T) {
+	srv := gollamatest.NewServer(t)
+	clients := map[string]*gollama.Client{
+		"anthropic": gollama.NewClient(srv.URL, gollama.WithAnthropicMode(true)),
+		"openai":    gollama.NewClient(srv.URL + "/v1"),
+		"bedrock":   gollama.NewClient(srv.URL, gollama.WithAWSAuth("us-east-1", "AKID", "secret", "")),
+	}
+	for name, c := range clients {
+		t.Run(name, func(t *testing.T) {
+			srv.Enqueue(
+				gollamatest.Reply{Text: "let me look", ToolCalls: []gollamatest.ToolCall{{ID: "t1", Name: "search", Input: map[string]any{"q": "go"}}}, Usage: gollamatest.Usage{InputTokens: 7, OutputTokens: 3}},
+				gollamatest.Text("done"),
+			)
+			resp, err := c.Turn(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg := resp.Choices[0].Message
+			if msg.Content != "let me look" || len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Function.Name != "search" ||
+				msg.ToolCalls[0].ID != "t1" || msg.ToolCalls[0].Function.Arguments != `{"q":"go"}` {
+				t.Errorf("message = %+v", msg)
+			}
+			if resp.Usage.PromptTokens != 7 || resp.Usage.CompletionTokens != 3 {
+				t.Errorf("usage = %+v", resp.Usage)
+			}
+
+			resp, err = c.Turn(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Choices[0].Message.Content != "done" {
+				t.Errorf("second turn = %q", resp.Choices[0].Message.Content)
+			}
+
+			var body map[string]any
+			if err := srv.LastRequest().JSON(&body); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := body["messages"]; !ok {
+				t.Errorf("captured body = %v", body)
+			}
+		})
+	}
+}
+
+func TestOllama(t *testing.T) {
+	srv := gollamatest.NewServer(t)
+	c := gollama.NewClient(srv.URL)
+
+	srv.Enqueue(gollamatest.Text("chat reply"), gollamatest.Text("generated"))
+	chat, err := c.Chat(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chat.Message.Content != "chat reply" {
+		t.Errorf("chat = %+v", chat)
+	}
+	gen, err := c.Generate(gollama.RequestOptions{Model: "fake-model", Prompt: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gen.Response != "generated" {
+		t.Errorf("generate = %+v", gen)
+	}
+
+	models, err := c.ListModels()
+	if err != nil || len(models) != 1 || models[0].ID != "fake-model" {
+		t.Errorf("models = %v, %v", models, err)
+	}
+}
+
+func TestInjectedErrors(t *testing.T) {
+	srv := gollamatest.NewServer(t)
+	c := gollama.NewClient(srv.URL, gollama.WithAnthropicMode(true), gollama.WithRetryPolicy(2, time.Millisecond))
+
+	srv.Enqueue(gollamatest.Error(429), gollamatest.Error(529), gollamatest.Text("finally"))
+	resp, err := c.Turn(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Choices[0].Message.Content != "finally" || resp.Meta.Retries != 2 {
+		t.Errorf("content %q after %d retries", resp.Choices[0].Message.Content, resp.Meta.Retries)
+	}
+
+	srv.Enqueue(gollamatest.Error(400))
+	var apiErr *gollama.APIError
+	if _, err := c.Turn(opts); !errors.As(err, &apiErr) || !strings.Contains(apiErr.Body, "invalid_request_error") {
+		t.Errorf("err = %v", err)
+	}
+
+	srv.Enqueue(gollamatest.Malformed())
+	if _, err := c.Turn(opts); err == nil {
+		t.Error("expected a decode error for a malformed body")
+	}
+	if srv.Pending() != 0 {
+		t.Errorf("%d replies left", srv.Pending())
+	}
+}
+
+func TestBatches(t *testing.T) {
+	srv := gollamatest.NewServer(t)
+	c := gollama.NewClient(srv.URL, gollama.WithAnthropicMode(true))
+
+	srv.Enqueue(gollamatest.Text("first"), gollamatest.Error(529))
+	batch, err := c.CreateBatch(gollama.CreateBatchRequest{Requests: []gollama.BatchRequest{
+		{CustomID: "a", Params: gollama.BatchRequestParams{Model: "fake-model", MaxTokens: 10, Messages: opts.Messages}},
+		{CustomID: "b", Params: gollama.BatchRequestParams{Model: "fake-model", MaxTokens: 10, Messages: opts.Messages}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.ProcessingStatus != "ended" || batch.RequestCounts.Succeeded != 1 || batch.RequestCounts.Errored != 1 {
+		t.Errorf("batch = %+v", batch)
+	}
+	if got, err := c.GetBatch(batch.ID); err != nil || got.ID != batch.ID {
+		t.Errorf("GetBatch = %+v, %v", got, err)
+	}
+	list, err := c.ListBatches(0, "", "")
+	if err != nil || len(list.Data) != 1 {
+		t.Errorf("ListBatches = %+v, %v", list, err)
+	}
+	results, err := c.GetBatchResults(batch.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Result.Message.Content[0].Text != "first" ||
+		results[1].Result.Type != "errored" || results[1].Result.Error.GetErrorMessage() == "" {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestStreaming(t *testing.T) {
+	srv := gollamatest.NewServer(t)
+	post := func(path, body string) *http.Response {
+		t.Helper()
+		resp, err := http.Post(srv.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	srv.Enqueue(gollamatest.Text("hello"))
+	resp := post("/v1/messages", `{"model":"m","stream":true}`)
+	data, _ := io.ReadAll(resp.Body)
+	if resp.Header.Get("Content-Type") != "text/event-stream" ||
+		!strings.Contains(string(data), "event: message_start") ||
+		!strings.Contains(string(data), `"delta":{"text":"hello","type":"text_delta"}`) ||
+		!strings.HasSuffix(string(data), "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n") {
+		t.Errorf("anthropic stream:\n%s", data)
+	}
+
+	srv.Enqueue(gollamatest.Text("hello"))
+	data, _ = io.ReadAll(post("/chat/completions", `{"model":"m","stream":true}`).Body)
+	if !strings.Contains(string(data), `"content":"hello"`) || !strings.HasSuffix(string(data), "data: [DONE]\n\n") {
+		t.Errorf("openai stream:\n%s", data)
+	}
+
+	srv.Enqueue(gollamatest.Text("hello"))
+	sc := bufio.NewScanner(post("/api/chat", `{"model":"m","stream":true}`).Body)
+	var chunks []map[string]any
+	for sc.Scan() {
+		var m map[string]any
+		json.Unmarshal(sc.Bytes(), &m)
+		chunks = append(chunks, m)
+	}
+	if len(chunks) != 2 || chunks[0]["done"] != false || chunks[1]["done"] != true {
+		t.Errorf("ollama stream = %v", chunks)
+	}
+
+	srv.Enqueue(gollamatest.Text("hello"))
+	data, _ = io.ReadAll(post("/model/claude/invoke-with-response-stream", `{}`).Body)
+	var events []string
+	for r := bytes.NewReader(data); r.Len() > 0; {
+		var total, hdrLen, crc uint32
+		binary.Read(r, binary.BigEndian, &total)
+		binary.Read(r, binary.BigEndian, &hdrLen)
+		binary.Read(r, binary.BigEndian, &crc)
+		r.Seek(int64(hdrLen), io.SeekCurrent)
+		payload := make([]byte, total-hdrLen-16)
+		r.Read(payload)
+		r.Seek(4, io.SeekCurrent)
+
+		var chunk struct{ Bytes string }
+		json.Unmarshal(payload, &chunk)
+		ev, _ := base64.StdEncoding.DecodeString(chunk.Bytes)
+		var typed struct{ Type string }
+		json.Unmarshal(ev, &typed)
+		events = append(events, typed.Type)
+	}
+	if len(events) == 0 || events[0] != "message_start" || events[len(events)-1] != "message_stop" {
+		t.Errorf("bedrock stream events = %v", events)
+	}
+}
@@ -0,0 +1,137 @@
+package gollamatools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/whyrusleeping/gollama"
+)
+
+// FetchOptions configures the fetch_url tool.
+type FetchOptions struct {
+	// Timeout bounds each fetch, including redirects (default 30s).
+	Timeout time.Duration
+	// MaxOutput caps the response body returned, in bytes (default
+	// DefaultMaxOutput).
+	MaxOutput int
+	// AllowHosts, if set, restricts fetches to these hosts (and their
+	// subdomains).
+	AllowHosts []string
+	// AllowPrivate permits connecting to loopback, private and link-local
+	// addresses, which are refused by default so the model cannot reach
+	// internal services.
+	AllowPrivate bool
+	// Header is added to every request, e.g. a User-Agent.
+	Header http.Header
+}
+
+type fetchArgs struct {
+	URL string `json:"url" description:"http or https URL to fetch"`
+}
+
+// ErrBlockedAddress is returned when fetch_url would connect to an address
+// FetchOptions does not allow.
+var ErrBlockedAddress = errors.New("address not allowed")
+
+// Fetch returns the fetch_url tool, which GETs a URL and returns the status,
+// content type and body. The address check is made on every connection,
+// after DNS resolution, so redirects and DNS tricks cannot reach a blocked
+// address.
+func Fetch(opts FetchOptions) *gollama.Tool {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	opts.MaxOutput = orDefault(opts.MaxOutput, DefaultMaxOutput)
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !opts.AllowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || blockedIP(ip) {
+				return fmt.Errorf("%s: %w", host, ErrBlockedAddress)
+			}
+			return nil
+		}
+	}
+	client := &http.Client{
+		Timeout:   opts.Timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, Proxy: nil},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			return checkFetchURL(req.URL, opts.AllowHosts)
+		},
+	}
+
+	return gollama.NewTypedTool("fetch_url", "Fetch a web page or other resource over HTTP(S).",
+		func(ctx context.Context, args fetchArgs) (string, error) {
+			u, err := url.Parse(args.URL)
+			if err != nil {
+				return "", fmt.Errorf("invalid URL: %w", err)
+			}
+			if err := checkFetchURL(u, opts.AllowHosts); err != nil {
+				return "", err
+			}
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+			if err != nil {
+				return "", err
+			}
+			for k, vs := range opts.Header {
+				req.Header[k] = vs
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return "", err
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(io.LimitReader(resp.Body, int64(opts.MaxOutput)+1))
+			if err != nil {
+				return "", fmt.Errorf("error reading response: %w", err)
+			}
+			text := string(body)
+			if len(body) > opts.MaxOutput {
+				// The rest of the body was never read, so its size is unknown.
+				text = cutUTF8(text, opts.MaxOutput) +
+					fmt.Sprintf("\n[truncated: response body exceeds %d bytes]", opts.MaxOutput)
+			}
+			return fmt.Sprintf("status: %s\ncontent-type: %s\n\n%s", resp.Status, resp.Header.Get("Content-Type"), text), nil
+		})
+}
+
+// checkFetchURL checks the scheme and, if allowHosts is set, the host of u.
+func checkFetchURL(u *url.URL, allowHosts []string) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	if len(allowHosts) == 0 {
+		return nil
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, h := range allowHosts {
+		h = strings.ToLower(h)
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %q: %w", host, ErrBlockedAddress)
+}
+
+// blockedIP reports whether ip is loopback, private, link-local or
+// otherwise not a public unicast address.
+func blockedIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
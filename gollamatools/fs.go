@@ -0,0 +1,369 @@
+package gollamatools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/whyrusleeping/gollama"
+)
+
+// FSOptions configures the filesystem tools.
+type FSOptions struct {
+	// MaxOutput caps the output of each call in bytes (default
+	// DefaultMaxOutput).
+	MaxOutput int
+	// MaxEntries caps the entries listed by list_dir and the matches
+	// reported by grep (default 1000).
+	MaxEntries int
+	// MaxFileSize is the most read_file reads of a file, and the largest
+	// file grep searches; bigger files are skipped (default 1 MiB).
+	MaxFileSize int64
+}
+
+// FS provides filesystem tools confined to a root directory. Paths given by
+// the model are relative to the root; absolute paths are accepted only if
+// they lie inside it. A path that leaves the root, directly with ".." or by
+// following a symlink, is rejected.
+type FS struct {
+	root string // absolute, symlinks resolved
+	opts FSOptions
+}
+
+// NewFS returns filesystem tools confined to root, which must be an existing
+// directory.
+func NewFS(root string, opts FSOptions) (*FS, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving root: %w", err)
+	}
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving root: %w", err)
+	}
+	if st, err := os.Stat(real); err != nil || !st.IsDir() {
+		return nil, fmt.Errorf("root %q is not a directory", root)
+	}
+	opts.MaxOutput = orDefault(opts.MaxOutput, DefaultMaxOutput)
+	opts.MaxEntries = orDefault(opts.MaxEntries, 1000)
+	if opts.MaxFileSize <= 0 {
+		opts.MaxFileSize = 1 << 20
+	}
+	return &FS{root: real, opts: opts}, nil
+}
+
+// Tools returns read_file, write_file, list_dir and grep.
+func (f *FS) Tools() []*gollama.Tool {
+	return []*gollama.Tool{f.ReadFile(), f.WriteFile(), f.ListDir(), f.Grep()}
+}
+
+// ReadOnlyTools returns read_file, list_dir and grep.
+func (f *FS) ReadOnlyTools() []*gollama.Tool {
+	return []*gollama.Tool{f.ReadFile(), f.ListDir(), f.Grep()}
+}
+
+// ErrOutsideRoot is returned for paths that lead outside an FS root.
+var ErrOutsideRoot = errors.New("path is outside the root directory")
+
+// resolve maps a path given by the model to an absolute path inside the
+// root.
+func (f *FS) resolve(p string) (string, error) {
+	if p == "" {
+		p = "."
+	}
+	p = filepath.FromSlash(p)
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(f.root, p)
+	}
+	p = filepath.Clean(p)
+	if !f.inRoot(p) {
+		return "", fmt.Errorf("%q: %w", p, ErrOutsideRoot)
+	}
+
+	// Walk the path from the root, resolving each symlink on the way, so a
+	// link pointing outside the root cannot be used to escape it. A dangling
+	// link is refused outright: creating a file through it would write
+	// wherever it points.
+	rel, err := filepath.Rel(f.root, p)
+	if err != nil {
+		return "", err
+	}
+	if rel == "." {
+		return f.root, nil
+	}
+	cur := f.root
+	parts := strings.Split(rel, string(filepath.Separator))
+	for i, part := range parts {
+		next := filepath.Join(cur, part)
+		st, err := os.Lstat(next)
+		if errors.Is(err, fs.ErrNotExist) {
+			return filepath.Join(append([]string{next}, parts[i+1:]...)...), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if st.Mode()&fs.ModeSymlink == 0 {
+			cur = next
+			continue
+		}
+		real, err := filepath.EvalSymlinks(next)
+		if err != nil {
+			return "", fmt.Errorf("%q: refusing to follow dangling symlink %q", p, f.display(next))
+		}
+		if !f.inRoot(real) {
+			return "", fmt.Errorf("%q: %w", p, ErrOutsideRoot)
+		}
+		cur = real
+	}
+	return cur, nil
+}
+
+func (f *FS) inRoot(p string) bool {
+	rel, err := filepath.Rel(f.root, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// display returns p relative to the root, as shown to the model.
+func (f *FS) display(p string) string {
+	rel, err := filepath.Rel(f.root, p)
+	if err != nil {
+		return p
+	}
+	return filepath.ToSlash(rel)
+}
+
+type readFileArgs struct {
+	Path   string `json:"path" description:"File path, relative to the workspace root"`
+	Offset int    `json:"offset,omitempty" description:"1-based line to start reading from" min:"1"`
+	Limit  int    `json:"limit,omitempty" description:"Maximum number of lines to read" min:"1"`
+}
+
+// ReadFile returns the read_file tool. At most FSOptions.MaxFileSize bytes
+// of a file are read.
+func (f *FS) ReadFile() *gollama.Tool {
+	return gollama.NewTypedTool("read_file", "Read a text file. Use offset and limit to read part of a large file.",
+		func(ctx context.Context, args readFileArgs) (string, error) {
+			p, err := f.resolve(args.Path)
+			if err != nil {
+				return "", err
+			}
+			file, err := os.Open(p)
+			if err != nil {
+				return "", err
+			}
+			defer file.Close()
+			st, err := file.Stat()
+			if err != nil {
+				return "", err
+			}
+			if st.IsDir() {
+				return "", fmt.Errorf("%s is a directory", f.display(p))
+			}
+
+			ranged := args.Offset > 1 || args.Limit > 0
+			limit := f.opts.MaxFileSize
+			if !ranged {
+				limit = min(limit, int64(f.opts.MaxOutput))
+			}
+			data, err := io.ReadAll(io.LimitReader(file, limit))
+			if err != nil {
+				return "", err
+			}
+			partial := int64(len(data)) < st.Size()
+
+			if !ranged {
+				if !partial {
+					return string(data), nil
+				}
+				head := cutUTF8(string(data), f.opts.MaxOutput)
+				return head + fmt.Sprintf("\n[truncated: showing %d of %d bytes]", len(head), st.Size()), nil
+			}
+			lines := strings.SplitAfter(string(data), "\n")
+			start := min(max(args.Offset, 1)-1, len(lines))
+			end := len(lines)
+			if args.Limit > 0 {
+				end = min(start+args.Limit, end)
+			}
+			text := strings.Join(lines[start:end], "")
+			if partial && end == len(lines) {
+				text += fmt.Sprintf("\n[truncated: only the first %d of %d bytes of the file can be read]", len(data), st.Size())
+			}
+			return truncate(text, f.opts.MaxOutput), nil
+		})
+}
+
+type writeFileArgs struct {
+	Path    string `json:"path" description:"File path, relative to the workspace root"`
+	Content string `json:"content" description:"Text to write"`
+	Append  bool   `json:"append,omitempty" description:"Append to the file instead of replacing it"`
+}
+
+// WriteFile returns the write_file tool. Missing parent directories are
+// created.
+func (f *FS) WriteFile() *gollama.Tool {
+	return gollama.NewTypedTool("write_file", "Create or overwrite a text file, or append to it.",
+		func(ctx context.Context, args writeFileArgs) (string, error) {
+			p, err := f.resolve(args.Path)
+			if err != nil {
+				return "", err
+			}
+			if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+				return "", err
+			}
+			flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+			if args.Append {
+				flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+			}
+			file, err := os.OpenFile(p, flags, 0o644)
+			if err != nil {
+				return "", err
+			}
+			if _, err := file.WriteString(args.Content); err != nil {
+				file.Close()
+				return "", err
+			}
+			if err := file.Close(); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("wrote %d bytes to %s", len(args.Content), f.display(p)), nil
+		})
+}
+
+type listDirArgs struct {
+	Path      string `json:"path,omitempty" description:"Directory path, relative to the workspace root (default: the root)"`
+	Recursive bool   `json:"recursive,omitempty" description:"List subdirectories recursively"`
+}
+
+// ListDir returns the list_dir tool. Directories are listed with a trailing
+// slash, files with their size.
+func (f *FS) ListDir() *gollama.Tool {
+	return gollama.NewTypedTool("list_dir", "List the files and directories in a directory.",
+		func(ctx context.Context, args listDirArgs) (string, error) {
+			dir, err := f.resolve(args.Path)
+			if err != nil {
+				return "", err
+			}
+			var sb strings.Builder
+			n := 0
+			err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if p == dir {
+					return nil
+				}
+				if n >= f.opts.MaxEntries {
+					fmt.Fprintf(&sb, "[truncated: listing stopped after %d entries]\n", n)
+					return filepath.SkipAll
+				}
+				n++
+				name := filepath.ToSlash(mustRel(dir, p))
+				if d.IsDir() {
+					fmt.Fprintf(&sb, "%s/\n", name)
+					if !args.Recursive {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if info, err := d.Info(); err == nil {
+					fmt.Fprintf(&sb, "%s (%d bytes)\n", name, info.Size())
+				} else {
+					fmt.Fprintf(&sb, "%s\n", name)
+				}
+				return nil
+			})
+			if err != nil {
+				return "", err
+			}
+			if n == 0 {
+				return "(empty directory)", nil
+			}
+			return truncate(sb.String(), f.opts.MaxOutput), nil
+		})
+}
+
+type grepArgs struct {
+	Pattern string `json:"pattern" description:"Regular expression (RE2 syntax) to search for"`
+	Path    string `json:"path,omitempty" description:"File or directory to search, relative to the workspace root (default: the root)"`
+	Glob    string `json:"glob,omitempty" description:"Only search files whose name matches this glob, e.g. *.go"`
+}
+
+// Grep returns the grep tool, which reports matching lines as
+// "path:line: text". Binary files and files over FSOptions.MaxFileSize are
+// skipped.
+func (f *FS) Grep() *gollama.Tool {
+	return gollama.NewTypedTool("grep", "Search files for lines matching a regular expression.",
+		func(ctx context.Context, args grepArgs) (string, error) {
+			re, err := regexp.Compile(args.Pattern)
+			if err != nil {
+				return "", fmt.Errorf("invalid pattern: %w", err)
+			}
+			start, err := f.resolve(args.Path)
+			if err != nil {
+				return "", err
+			}
+
+			var sb strings.Builder
+			matches := 0
+			err = filepath.WalkDir(start, func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				if d.IsDir() || !d.Type().IsRegular() {
+					return nil
+				}
+				if args.Glob != "" {
+					if ok, _ := filepath.Match(args.Glob, d.Name()); !ok {
+						return nil
+					}
+				}
+				if info, err := d.Info(); err != nil || info.Size() > f.opts.MaxFileSize {
+					return nil
+				}
+				data, err := os.ReadFile(p)
+				if err != nil || bytes.IndexByte(data, 0) >= 0 {
+					return nil
+				}
+				sc := bufio.NewScanner(bytes.NewReader(data))
+				sc.Buffer(nil, len(data)+1)
+				for line := 1; sc.Scan(); line++ {
+					if !re.Match(sc.Bytes()) {
+						continue
+					}
+					if matches >= f.opts.MaxEntries {
+						fmt.Fprintf(&sb, "[truncated: stopped after %d matches]\n", matches)
+						return filepath.SkipAll
+					}
+					matches++
+					fmt.Fprintf(&sb, "%s:%d: %s\n", f.display(p), line, sc.Text())
+				}
+				return nil
+			})
+			if err != nil {
+				return "", err
+			}
+			if matches == 0 {
+				return "no matches", nil
+			}
+			return truncate(sb.String(), f.opts.MaxOutput), nil
+		})
+}
+
+func mustRel(base, p string) string {
+	rel, err := filepath.Rel(base, p)
+	if err != nil {
+		return p
+	}
+	return rel
+}
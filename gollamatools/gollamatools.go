@@ -0,0 +1,59 @@
+// Package gollamatools provides ready-made gollama Tools for the jobs agents
+// most often need: reading, writing, listing and searching files, running
+// shell commands and fetching URLs.
+//
+// The tools are sandboxed. Filesystem tools are confined to a root
+// directory, rejecting paths (including through symlinks) that lead outside
+// it; shell commands run with a timeout; URL fetches refuse private and
+// loopback addresses unless allowed. Every output is capped, and a cut is
+// marked explicitly so the model knows it is not seeing everything:
+//
+//	fs, err := gollamatools.NewFS("/srv/workspace", gollamatools.FSOptions{})
+//	if err != nil {
+//		return err
+//	}
+//	tools := append(fs.Tools(), gollamatools.Shell(gollamatools.ShellOptions{Dir: "/srv/workspace"}))
+//	res, err := gollama.RunAgent(ctx, client, req, tools, gollama.AgentOptions{})
+//
+// The tools are plain *gollama.Tool values and work with HandleToolCall,
+// RunAgent and ToolRegistry alike.
+package gollamatools
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// DefaultMaxOutput is the default cap, in bytes, on the output of a tool
+// call.
+const DefaultMaxOutput = 64 << 10
+
+// truncate cuts s to at most max bytes, on a UTF-8 boundary, and appends a
+// marker saying how much was left out.
+func truncate(s string, max int) string {
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	head := cutUTF8(s, max)
+	return head + fmt.Sprintf("\n[truncated: showing %d of %d bytes]", len(head), len(s))
+}
+
+// cutUTF8 returns the longest prefix of s of at most max bytes that does not
+// split a UTF-8 sequence.
+func cutUTF8(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
+
+func orDefault(n, def int) int {
+	if n <= 0 {
+		return def
+	}
+	return n
+}
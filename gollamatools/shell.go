@@ -0,0 +1,131 @@
+package gollamatools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/whyrusleeping/gollama"
+)
+
+// ShellOptions configures the shell tool.
+type ShellOptions struct {
+	// Dir is the working directory of commands (default: the process's).
+	Dir string
+	// Env, if set, replaces the environment of commands, e.g. to keep
+	// credentials out of reach.
+	Env []string
+	// Shell runs each command as Shell -c command (default "/bin/sh").
+	Shell string
+	// Timeout is the default time limit of a command (default 30s).
+	Timeout time.Duration
+	// MaxTimeout caps the timeout the model may request (default 10m).
+	MaxTimeout time.Duration
+	// MaxOutput caps the combined stdout and stderr in bytes (default
+	// DefaultMaxOutput).
+	MaxOutput int
+}
+
+type shellArgs struct {
+	Command string `json:"command" description:"Shell command to run"`
+	Timeout int    `json:"timeout_seconds,omitempty" description:"Time limit in seconds" min:"1"`
+}
+
+// Shell returns the shell tool. It reports the command's combined output
+// and exit status; a command that fails or times out is reported the same
+// way rather than as a tool error, so the model can react to it. Output
+// beyond MaxOutput is discarded while the command runs, keeping the head
+// and tail. On Unix a command that times out is killed along with every
+// process it started.
+//
+// Shell is Sequential: commands may depend on each other's side effects.
+func Shell(opts ShellOptions) *gollama.Tool {
+	if opts.Shell == "" {
+		opts.Shell = "/bin/sh"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	if opts.MaxTimeout <= 0 {
+		opts.MaxTimeout = 10 * time.Minute
+	}
+	opts.MaxOutput = orDefault(opts.MaxOutput, DefaultMaxOutput)
+
+	tool := gollama.NewTypedTool("shell", "Run a shell command and return its output and exit status.",
+		func(ctx context.Context, args shellArgs) (string, error) {
+			timeout := opts.Timeout
+			if args.Timeout > 0 {
+				timeout = min(time.Duration(args.Timeout)*time.Second, opts.MaxTimeout)
+			}
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			cmd := exec.CommandContext(ctx, opts.Shell, "-c", args.Command)
+			cmd.Dir = opts.Dir
+			cmd.Env = opts.Env
+			killProcessGroup(cmd)
+			// Don't wait forever for children that inherited the pipes.
+			cmd.WaitDelay = time.Second
+			out := &cappedBuffer{max: opts.MaxOutput}
+			cmd.Stdout = out
+			cmd.Stderr = out
+
+			err := cmd.Run()
+			var status string
+			var exitErr *exec.ExitError
+			switch {
+			case ctx.Err() == context.DeadlineExceeded:
+				status = fmt.Sprintf("timed out after %s", timeout)
+			case errors.As(err, &exitErr):
+				status = fmt.Sprintf("exit status %d", exitErr.ExitCode())
+			case err != nil && !errors.Is(err, exec.ErrWaitDelay):
+				return "", err
+			default:
+				status = "exit status 0"
+			}
+
+			output := out.String()
+			if output == "" {
+				output = "(no output)"
+			}
+			return fmt.Sprintf("%s\n[%s]", strings.TrimRight(output, "\n"), status), nil
+		})
+	tool.Sequential = true
+	return tool
+}
+
+// cappedBuffer keeps the first and last max/2 bytes written to it.
+type cappedBuffer struct {
+	max     int
+	head    bytes.Buffer
+	tail    []byte
+	dropped int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if room := b.max/2 - b.head.Len(); room > 0 {
+		take := min(room, len(p))
+		b.head.Write(p[:take])
+		p = p[take:]
+	}
+	if len(p) > 0 {
+		b.tail = append(b.tail, p...)
+		if over := len(b.tail) - b.max/2; over > 0 {
+			b.dropped += over
+			b.tail = b.tail[over:]
+		}
+	}
+	return n, nil
+}
+
+func (b *cappedBuffer) String() string {
+	if b.dropped == 0 {
+		return b.head.String() + string(b.tail)
+	}
+	return fmt.Sprintf("%s\n[truncated: %d bytes omitted]\n%s", b.head.String(), b.dropped, b.tail)
+}
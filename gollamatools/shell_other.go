@@ -0,0 +1,9 @@
+//go:build !unix
+
+package gollamatools
+
+import "os/exec"
+
+// killProcessGroup is a no-op where process groups are not available; only
+// the shell itself is killed when a command times out.
+func killProcessGroup(cmd *exec.Cmd) {}
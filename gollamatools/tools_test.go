@@ -0,0 +1,341 @@
+package gollamatools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/whyrusleeping/gollama"
+)
+
+func call(t *testing.T, tools []*gollama.Tool, name string, args map[string]any) (*gollama.ToolResult, error) {
+	t.Helper()
+	b, err := json.Marshal(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gollama.HandleToolCall(context.Background(), tools, gollama.ToolCall{
+		ID:       "call_1",
+		Type:     "function",
+		Function: gollama.ToolCallFunction{Name: name, Arguments: string(b)},
+	})
+}
+
+func mustCall(t *testing.T, tools []*gollama.Tool, name string, args map[string]any) string {
+	t.Helper()
+	res, err := call(t, tools, name, args)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if res.IsError {
+		t.Fatalf("%s: error result: %s", name, res.Content)
+	}
+	return res.Content
+}
+
+func newTestFS(t *testing.T, opts FSOptions) (*FS, string) {
+	t.Helper()
+	dir := t.TempDir()
+	f, err := NewFS(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, dir
+}
+
+func TestFSReadWriteList(t *testing.T) {
+	f, dir := newTestFS(t, FSOptions{})
+	tools := f.Tools()
+
+	out := mustCall(t, tools, "write_file", map[string]any{"path": "sub/a.txt", "content": "one\ntwo\n"})
+	if !strings.Contains(out, "wrote 8 bytes to sub/a.txt") {
+		t.Fatalf("write output = %q", out)
+	}
+	mustCall(t, tools, "write_file", map[string]any{"path": "sub/a.txt", "content": "three\n", "append": true})
+	data, err := os.ReadFile(filepath.Join(dir, "sub", "a.txt"))
+	if err != nil || string(data) != "one\ntwo\nthree\n" {
+		t.Fatalf("file = %q, %v", data, err)
+	}
+
+	if got := mustCall(t, tools, "read_file", map[string]any{"path": "sub/a.txt"}); got != "one\ntwo\nthree\n" {
+		t.Fatalf("read = %q", got)
+	}
+	if got := mustCall(t, tools, "read_file", map[string]any{"path": "sub/a.txt", "offset": 2, "limit": 1}); got != "two\n" {
+		t.Fatalf("read range = %q", got)
+	}
+
+	if got := mustCall(t, tools, "list_dir", map[string]any{}); got != "sub/\n" {
+		t.Fatalf("list = %q", got)
+	}
+	if got := mustCall(t, tools, "list_dir", map[string]any{"recursive": true}); got != "sub/\nsub/a.txt (14 bytes)\n" {
+		t.Fatalf("recursive list = %q", got)
+	}
+
+	if ro := f.ReadOnlyTools(); len(ro) != 3 {
+		t.Fatalf("read-only tools = %d", len(ro))
+	}
+}
+
+func TestFSGrep(t *testing.T) {
+	f, dir := newTestFS(t, FSOptions{})
+	os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\nfunc Hello() {}\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "b.txt"), []byte("Hello there\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "bin"), []byte("Hello\x00"), 0o644)
+
+	got := mustCall(t, f.Tools(), "grep", map[string]any{"pattern": "Hel+o"})
+	if got != "a.go:2: func Hello() {}\nb.txt:1: Hello there\n" {
+		t.Fatalf("grep = %q", got)
+	}
+	got = mustCall(t, f.Tools(), "grep", map[string]any{"pattern": "Hello", "glob": "*.txt"})
+	if got != "b.txt:1: Hello there\n" {
+		t.Fatalf("grep glob = %q", got)
+	}
+	if got := mustCall(t, f.Tools(), "grep", map[string]any{"pattern": "nope"}); got != "no matches" {
+		t.Fatalf("grep = %q", got)
+	}
+}
+
+func TestFSConfinement(t *testing.T) {
+	f, dir := newTestFS(t, FSOptions{})
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "secret.txt")
+	os.WriteFile(secret, []byte("top secret"), 0o644)
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	cases := []struct {
+		tool string
+		args map[string]any
+	}{
+		{"read_file", map[string]any{"path": "../" + filepath.Base(outside) + "/secret.txt"}},
+		{"read_file", map[string]any{"path": secret}},
+		{"read_file", map[string]any{"path": "link/secret.txt"}},
+		{"write_file", map[string]any{"path": "link/new.txt", "content": "x"}},
+		{"write_file", map[string]any{"path": "../escape.txt", "content": "x"}},
+		{"list_dir", map[string]any{"path": "link"}},
+		{"grep", map[string]any{"pattern": "secret", "path": ".."}},
+	}
+	for _, c := range cases {
+		_, err := call(t, f.Tools(), c.tool, c.args)
+		if !errors.Is(err, ErrOutsideRoot) {
+			t.Errorf("%s %v: err = %v, want ErrOutsideRoot", c.tool, c.args, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outside, "new.txt")); err == nil {
+		t.Fatal("write escaped the root")
+	}
+
+	// A dangling link must not let write_file create its target.
+	if err := os.Symlink(filepath.Join(outside, "planted.txt"), filepath.Join(dir, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"dangling", "./dangling"} {
+		if _, err := call(t, f.Tools(), "write_file", map[string]any{"path": p, "content": "x"}); err == nil {
+			t.Errorf("write through dangling link %q succeeded", p)
+		}
+	}
+	if _, err := os.Lstat(filepath.Join(outside, "planted.txt")); err == nil {
+		t.Fatal("write through dangling link escaped the root")
+	}
+
+	// Absolute paths inside the root are fine.
+	os.WriteFile(filepath.Join(dir, "ok.txt"), []byte("ok"), 0o644)
+	if got := mustCall(t, f.Tools(), "read_file", map[string]any{"path": filepath.Join(dir, "ok.txt")}); got != "ok" {
+		t.Fatalf("read = %q", got)
+	}
+	// grep does not follow the symlink out of the root.
+	if got := mustCall(t, f.Tools(), "grep", map[string]any{"pattern": "secret"}); got != "no matches" {
+		t.Fatalf("grep = %q", got)
+	}
+}
+
+func TestFSTruncation(t *testing.T) {
+	f, dir := newTestFS(t, FSOptions{MaxOutput: 10, MaxEntries: 2})
+	os.WriteFile(filepath.Join(dir, "big.txt"), []byte(strings.Repeat("x", 100)), 0o644)
+	os.WriteFile(filepath.Join(dir, "c.txt"), nil, 0o644)
+	os.WriteFile(filepath.Join(dir, "d.txt"), nil, 0o644)
+
+	got := mustCall(t, f.Tools(), "read_file", map[string]any{"path": "big.txt"})
+	if got != strings.Repeat("x", 10)+"\n[truncated: showing 10 of 100 bytes]" {
+		t.Fatalf("read = %q", got)
+	}
+
+	// Reads stop at MaxFileSize.
+	f.opts.MaxOutput = DefaultMaxOutput
+	f.opts.MaxFileSize = 20
+	os.WriteFile(filepath.Join(dir, "lines.txt"), []byte(strings.Repeat("line\n", 10)), 0o644)
+	got = mustCall(t, f.Tools(), "read_file", map[string]any{"path": "lines.txt", "offset": 3})
+	if got != "line\nline\n\n[truncated: only the first 20 of 50 bytes of the file can be read]" {
+		t.Fatalf("read range = %q", got)
+	}
+	if got := mustCall(t, f.Tools(), "read_file", map[string]any{"path": "lines.txt", "limit": 2}); got != "line\nline\n" {
+		t.Fatalf("read range = %q", got)
+	}
+	got = mustCall(t, f.Tools(), "read_file", map[string]any{"path": "lines.txt"})
+	if got != strings.Repeat("line\n", 4)+"\n[truncated: showing 20 of 50 bytes]" {
+		t.Fatalf("read = %q", got)
+	}
+
+	got = mustCall(t, f.Tools(), "list_dir", map[string]any{})
+	if !strings.HasSuffix(got, "[truncated: listing stopped after 2 entries]\n") {
+		t.Fatalf("list = %q", got)
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	got := truncate("héllo", 2)
+	if got != "h\n[truncated: showing 1 of 6 bytes]" {
+		t.Fatalf("truncate = %q", got)
+	}
+}
+
+func TestShell(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("no /bin/sh")
+	}
+	dir := t.TempDir()
+	tools := []*gollama.Tool{Shell(ShellOptions{Dir: dir})}
+
+	got := mustCall(t, tools, "shell", map[string]any{"command": "pwd; echo oops >&2"})
+	real, _ := filepath.EvalSymlinks(dir)
+	if got != real+"\noops\n[exit status 0]" {
+		t.Fatalf("shell = %q", got)
+	}
+	if got := mustCall(t, tools, "shell", map[string]any{"command": "exit 3"}); got != "(no output)\n[exit status 3]" {
+		t.Fatalf("shell = %q", got)
+	}
+	if !tools[0].Sequential {
+		t.Fatal("shell should be sequential")
+	}
+}
+
+func TestShellTimeout(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("no /bin/sh")
+	}
+	tools := []*gollama.Tool{Shell(ShellOptions{Timeout: 100 * time.Millisecond})}
+	start := time.Now()
+	got := mustCall(t, tools, "shell", map[string]any{"command": "echo started; sleep 10"})
+	if !strings.HasPrefix(got, "started\n") || !strings.HasSuffix(got, "[timed out after 100ms]") {
+		t.Fatalf("shell = %q", got)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("timeout took %s", d)
+	}
+}
+
+func TestShellTimeoutKillsChildren(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no process groups")
+	}
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("no /bin/sh")
+	}
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	tools := []*gollama.Tool{Shell(ShellOptions{Timeout: 100 * time.Millisecond})}
+	mustCall(t, tools, "shell", map[string]any{"command": "sleep 30 >/dev/null 2>&1 & echo $! > " + pidFile + "; wait"})
+
+	b, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The killed child may linger briefly as a zombie until init reaps it.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		proc, err := os.FindProcess(pid)
+		if err != nil || proc.Signal(syscall.Signal(0)) != nil || isZombie(pid) {
+			return
+		}
+		if time.Now().After(deadline) {
+			proc.Kill()
+			t.Fatalf("child %d survived the timeout", pid)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// isZombie reports whether pid has exited but not been reaped, where /proc
+// tells.
+func isZombie(pid int) bool {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	i := bytes.LastIndexByte(b, ')')
+	return i >= 0 && i+2 < len(b) && b[i+2] == 'Z'
+}
+
+func TestShellOutputCap(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("no /bin/sh")
+	}
+	tools := []*gollama.Tool{Shell(ShellOptions{MaxOutput: 8})}
+	got := mustCall(t, tools, "shell", map[string]any{"command": "printf 'abcdefghijklmnop'"})
+	if got != "abcd\n[truncated: 8 bytes omitted]\nmnop\n[exit status 0]" {
+		t.Fatalf("shell = %q", got)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, "hello from "+r.Header.Get("User-Agent"))
+	}))
+	defer srv.Close()
+
+	tools := []*gollama.Tool{Fetch(FetchOptions{
+		AllowPrivate: true,
+		Header:       http.Header{"User-Agent": {"gollama-test"}},
+	})}
+	got := mustCall(t, tools, "fetch_url", map[string]any{"url": srv.URL + "/redirect"})
+	if got != "status: 200 OK\ncontent-type: text/plain\n\nhello from gollama-test" {
+		t.Fatalf("fetch = %q", got)
+	}
+
+	capped := []*gollama.Tool{Fetch(FetchOptions{AllowPrivate: true, MaxOutput: 5})}
+	got = mustCall(t, capped, "fetch_url", map[string]any{"url": srv.URL})
+	if !strings.HasSuffix(got, "\n\nhello\n[truncated: response body exceeds 5 bytes]") {
+		t.Fatalf("fetch = %q", got)
+	}
+}
+
+func TestFetchBlocked(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached the server")
+	}))
+	defer srv.Close()
+
+	tools := []*gollama.Tool{Fetch(FetchOptions{})}
+	if _, err := call(t, tools, "fetch_url", map[string]any{"url": srv.URL}); !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("loopback: err = %v, want ErrBlockedAddress", err)
+	}
+	if _, err := call(t, tools, "fetch_url", map[string]any{"url": "file:///etc/passwd"}); err == nil {
+		t.Fatal("file URL was fetched")
+	}
+
+	hosts := []*gollama.Tool{Fetch(FetchOptions{AllowPrivate: true, AllowHosts: []string{"example.com"}})}
+	if _, err := call(t, hosts, "fetch_url", map[string]any{"url": srv.URL}); !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("host allowlist: err = %v, want ErrBlockedAddress", err)
+	}
+}
@@ -0,0 +1,230 @@
+package gollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// HedgeTarget is one backend a HedgedClient may send a request to. The same
+// Client may appear more than once to hedge against itself.
+type HedgeTarget struct {
+	// Name identifies the target in ResponseMessageGenerate.Backend and
+	// errors. Defaults to the model, or "target N" if Model is empty.
+	Name   string
+	Client *Client
+	// Model, if set, replaces RequestOptions.Model for this target.
+	Model string
+}
+
+// HedgeOptions configures a HedgedClient.
+type HedgeOptions struct {
+	// Delay is how long to wait for a response before sending the request to
+	// the next target. Zero races every target from the start.
+	Delay time.Duration
+}
+
+// HedgeStats describes the cost of hedging: requests that were sent but
+// whose responses were not used.
+type HedgeStats struct {
+	Started int // requests sent, including the winner
+	// Wasted is the usage of losing requests that completed before they could
+	// be cancelled.
+	Wasted Usage
+	// Cancelled is the number of losing requests aborted in flight. The
+	// provider may still bill their input; EstimatedCancelledInputTokens
+	// estimates it with EstimateInputTokens.
+	Cancelled                     int
+	EstimatedCancelledInputTokens int
+	// Failed is the number of requests that failed other than by being
+	// cancelled.
+	Failed int
+	// Pending is the number of losing requests that had not finished yet:
+	// the winner is returned without waiting for them. Once they finish they
+	// are counted in HedgedClient.Stats as wasted, cancelled or failed.
+	Pending int
+}
+
+func (s *HedgeStats) add(o HedgeStats) {
+	s.Started += o.Started
+	s.Wasted.Add(o.Wasted)
+	s.Cancelled += o.Cancelled
+	s.EstimatedCancelledInputTokens += o.EstimatedCancelledInputTokens
+	s.Failed += o.Failed
+	s.Pending += o.Pending
+}
+
+// loser counts the outcome of a request whose response was not used.
+func (s *HedgeStats) loser(resp *ResponseMessageGenerate, err error, opts RequestOptions) {
+	switch {
+	case err == nil:
+		s.Wasted.Add(resp.Usage)
+	case errors.Is(err, context.Canceled):
+		s.Cancelled++
+		s.EstimatedCancelledInputTokens += EstimateInputTokens(opts)
+	default:
+		s.Failed++
+	}
+}
+
+// HedgedClient cuts tail latency by sending a request to more than one
+// target: it starts with the first target and, if no response has arrived
+// after Delay (or the request fails), sends it to the next one as well. With
+// a zero Delay all targets race from the start. The first successful
+// response is returned as soon as it arrives, and the others are cancelled
+// through their context and accounted for in the background.
+//
+// A HedgedClient is safe for concurrent use.
+type HedgedClient struct {
+	targets []HedgeTarget
+	opts    HedgeOptions
+
+	mu    sync.Mutex
+	stats HedgeStats
+}
+
+// NewHedgedClient creates a HedgedClient over targets, in the order they are
+// tried.
+func NewHedgedClient(targets []HedgeTarget, opts HedgeOptions) *HedgedClient {
+	h := &HedgedClient{opts: opts}
+	for i, t := range targets {
+		if t.Name == "" {
+			t.Name = t.Model
+		}
+		if t.Name == "" {
+			t.Name = fmt.Sprintf("target %d", i)
+		}
+		h.targets = append(h.targets, t)
+	}
+	return h
+}
+
+// Stats returns the hedging cost accumulated over all calls so far.
+func (h *HedgedClient) Stats() HedgeStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.stats
+}
+
+// Turn sends opts with hedging. ResponseMessageGenerate.Backend names the
+// winning target and ResponseMessageGenerate.Hedge reports the cost of the
+// losing requests that had finished by then; the rest are counted as
+// Pending. If every target fails, the returned error joins their
+// errors.
+func (h *HedgedClient) Turn(opts RequestOptions) (*ResponseMessageGenerate, error) {
+	return h.TurnContext(context.Background(), opts)
+}
+
+// TurnContext is Turn with a context; cancelling ctx aborts every request.
+func (h *HedgedClient) TurnContext(ctx context.Context, opts RequestOptions) (*ResponseMessageGenerate, error) {
+	if len(h.targets) == 0 {
+		return nil, errors.New("hedged client has no targets")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		target int
+		resp   *ResponseMessageGenerate
+		err    error
+	}
+	results := make(chan result, len(h.targets))
+	started := 0
+	launch := func() {
+		i := started
+		started++
+		t := h.targets[i]
+		topts := opts
+		if t.Model != "" {
+			topts.Model = t.Model
+		}
+		go func() {
+			resp, err := t.Client.TurnContext(ctx, topts)
+			results <- result{i, resp, err}
+		}()
+	}
+
+	launch()
+	for h.opts.Delay <= 0 && started < len(h.targets) {
+		launch()
+	}
+	var timer *time.Timer
+	var hedge <-chan time.Time
+	if started < len(h.targets) {
+		timer = time.NewTimer(h.opts.Delay)
+		defer timer.Stop()
+		hedge = timer.C
+	}
+
+	var (
+		winner *result
+		stats  HedgeStats
+		errs   []error
+	)
+	pending := started
+	for pending > 0 && winner == nil {
+		select {
+		case <-hedge:
+			launch()
+			pending++
+			if started < len(h.targets) {
+				timer.Reset(h.opts.Delay)
+			} else {
+				hedge = nil
+			}
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				winner = &r
+				cancel()
+				break
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", h.targets[r.target].Name, r.err))
+			if !errors.Is(r.err, context.Canceled) {
+				stats.Failed++
+			}
+			// Don't wait out the delay when a request has already failed.
+			if ctx.Err() == nil && started < len(h.targets) {
+				launch()
+				pending++
+				if started < len(h.targets) {
+					timer.Reset(h.opts.Delay)
+				} else {
+					hedge = nil
+				}
+			}
+		}
+	}
+	stats.Started = started
+	stats.Pending = pending
+
+	h.mu.Lock()
+	h.stats.add(stats)
+	h.mu.Unlock()
+
+	// The losers still in flight have been cancelled; account for them as
+	// they finish rather than holding the winner back.
+	if pending > 0 {
+		go func() {
+			for ; pending > 0; pending-- {
+				r := <-results
+				var late HedgeStats
+				late.loser(r.resp, r.err, opts)
+				late.Pending = -1
+				h.mu.Lock()
+				h.stats.add(late)
+				h.mu.Unlock()
+			}
+		}()
+	}
+
+	if winner == nil {
+		return nil, errors.Join(errs...)
+	}
+	winner.resp.Backend = h.targets[winner.target].Name
+	winner.resp.Hedge = &stats
+	return winner.resp, nil
+}
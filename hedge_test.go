@@ -0,0 +1,152 @@
+package gollama
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// hedgeServer answers chat requests after delay, or with status if it is not
+// 200. cancelled counts requests whose client went away first.
+func hedgeServer(t *testing.T, delay time.Duration, status int, cancelled *atomic.Int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices a client going away once the body is read.
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			if cancelled != nil {
+				cancelled.Add(1)
+			}
+			return
+		}
+		if status != http.StatusOK {
+			http.Error(w, "nope", status)
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ok"}}],"usage":{"prompt_tokens":5,"completion_tokens":2}}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+var hedgeOpts = RequestOptions{Model: "m", Messages: []Message{{Role: "user", Content: "hello there"}}}
+
+func TestHedgeAfterDelay(t *testing.T) {
+	var cancelled atomic.Int32
+	slow := hedgeServer(t, 10*time.Second, http.StatusOK, &cancelled)
+	fast := hedgeServer(t, 0, http.StatusOK, nil)
+
+	h := NewHedgedClient([]HedgeTarget{
+		{Name: "slow", Client: NewClient(slow.URL)},
+		{Name: "fast", Client: NewClient(fast.URL)},
+	}, HedgeOptions{Delay: 20 * time.Millisecond})
+
+	start := time.Now()
+	resp, err := h.Turn(hedgeOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Backend != "fast" {
+		t.Errorf("winner = %q", resp.Backend)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("took %v; the slow request was not hedged", d)
+	}
+	if resp.Hedge.Started != 2 || resp.Hedge.Pending != 1 {
+		t.Errorf("hedge stats = %+v", resp.Hedge)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for cancelled.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if cancelled.Load() != 1 {
+		t.Error("slow request was not cancelled")
+	}
+	if s := settledHedgeStats(t, h); s.Cancelled != 1 || s.EstimatedCancelledInputTokens == 0 || s.Failed != 0 {
+		t.Errorf("total stats = %+v", s)
+	}
+}
+
+// settledHedgeStats waits for h's losing requests to be accounted for.
+func settledHedgeStats(t *testing.T, h *HedgedClient) HedgeStats {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for h.Stats().Pending > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	s := h.Stats()
+	if s.Pending != 0 {
+		t.Fatalf("losers never finished: %+v", s)
+	}
+	return s
+}
+
+func TestHedgeNotNeeded(t *testing.T) {
+	fast := hedgeServer(t, 0, http.StatusOK, nil)
+	var otherHits atomic.Int32
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { otherHits.Add(1) }))
+	defer other.Close()
+
+	h := NewHedgedClient([]HedgeTarget{{Client: NewClient(fast.URL)}, {Client: NewClient(other.URL)}}, HedgeOptions{Delay: time.Second})
+	resp, err := h.Turn(hedgeOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Hedge.Started != 1 || otherHits.Load() != 0 {
+		t.Errorf("hedged a fast request: %+v", resp.Hedge)
+	}
+}
+
+func TestHedgeOnError(t *testing.T) {
+	broken := hedgeServer(t, 0, http.StatusBadRequest, nil)
+	fast := hedgeServer(t, 0, http.StatusOK, nil)
+
+	c := NewClient(fast.URL)
+	h := NewHedgedClient([]HedgeTarget{{Name: "broken", Client: NewClient(broken.URL)}, {Name: "ok", Client: c}}, HedgeOptions{Delay: time.Hour})
+	resp, err := h.Turn(hedgeOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Backend != "ok" {
+		t.Errorf("winner = %q", resp.Backend)
+	}
+	if resp.Hedge.Failed != 1 || resp.Hedge.Cancelled != 0 {
+		t.Errorf("hedge stats = %+v", resp.Hedge)
+	}
+}
+
+func TestHedgeStatsLoser(t *testing.T) {
+	var s HedgeStats
+	s.loser(&ResponseMessageGenerate{Usage: Usage{PromptTokens: 5}}, nil, hedgeOpts)
+	s.loser(nil, fmt.Errorf("error sending request: %w", context.Canceled), hedgeOpts)
+	s.loser(nil, &APIError{StatusCode: 500}, hedgeOpts)
+	if s.Wasted.PromptTokens != 5 || s.Cancelled != 1 || s.EstimatedCancelledInputTokens == 0 || s.Failed != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestHedgeRace(t *testing.T) {
+	a := hedgeServer(t, 0, http.StatusOK, nil)
+	b := hedgeServer(t, 0, http.StatusOK, nil)
+
+	h := NewHedgedClient([]HedgeTarget{{Client: NewClient(a.URL)}, {Client: NewClient(b.URL)}, {Client: NewClient(a.URL)}}, HedgeOptions{})
+	for i := 0; i < 3; i++ {
+		resp, err := h.Turn(hedgeOpts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := resp.Hedge; s.Started != 3 || s.Pending+s.Cancelled+s.Wasted.PromptTokens/5 != 2 {
+			t.Errorf("hedge stats = %+v", s)
+		}
+	}
+	if s := settledHedgeStats(t, h); s.Started != 9 || s.Cancelled+s.Wasted.PromptTokens/5 != 6 {
+		t.Errorf("total stats = %+v", s)
+	}
+}
@@ -2,17 +2,20 @@ package gollama
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"time"
 )
 
 const (
-	maxRetries = 5
-	baseDelay  = 5 * time.Second
+	defaultMaxRetries = 5
+	defaultBaseDelay  = 5 * time.Second
+
+	// defaultAnthropicVersion is sent as the anthropic-version header on
+	// Anthropic requests unless the caller set one explicitly.
+	defaultAnthropicVersion = "2023-06-01"
 )
 
 // isRetryableStatus returns true for status codes that should trigger a retry.
@@ -20,24 +23,49 @@ func isRetryableStatus(code int) bool {
 	return code == 429 || code == 529 || code == 503
 }
 
-// doWithRetry executes an HTTP request with exponential backoff on retryable errors.
+// doWithRetry executes an HTTP request with exponential backoff on retryable
+// errors, waiting instead for the Retry-After delay when the response has one.
 // The newReq function is called on each attempt to produce a fresh *http.Request
-// (necessary for POST bodies, which are consumed on each attempt).
-func (c *Client) doWithRetry(newReq func() (*http.Request, error)) (*http.Response, error) {
-	for attempt := 0; attempt <= maxRetries; attempt++ {
+// (necessary for POST bodies, which are consumed on each attempt). Cancelling ctx
+// aborts both an in-flight request and any pending backoff. header is applied
+// after the client's own headers. If meta is non-nil it is filled in with the
+// request ID, status, rate limits, retries and timings.
+func (c *Client) doWithRetry(ctx context.Context, meta *ResponseMeta, header http.Header, newReq func() (*http.Request, error)) (*http.Response, error) {
+	if meta == nil {
+		meta = &ResponseMeta{}
+	}
+	meta.begin()
+
+	anthropic := c.IsAnthropicAPI()
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
 		req, err := newReq()
 		if err != nil {
 			return nil, fmt.Errorf("error creating request: %w", err)
 		}
+		req = req.WithContext(ctx)
 
-		for k, v := range c.headers {
-			req.Header.Set(k, v)
+		c.applyHeaders(req)
+		for k, vs := range header {
+			req.Header[k] = vs
+		}
+		// The native Anthropic API requires an anthropic-version header.
+		// Default it per request so callers don't have to.
+		if anthropic && req.Header.Get("anthropic-version") == "" {
+			req.Header.Set("anthropic-version", defaultAnthropicVersion)
 		}
 
+		c.logRequest(ctx, req)
+		endAttempt := c.startAttemptSpan(ctx, req, attempt)
+		sent := time.Now()
 		resp, err := c.httpClient.Do(req)
 		if err != nil {
+			endAttempt(0, err)
 			return nil, fmt.Errorf("error sending request: %w", err)
 		}
+		endAttempt(resp.StatusCode, nil)
+		meta.record(resp, sent)
+		observeRateLimit(ctx, meta.RateLimit)
+		c.logResponse(ctx, resp)
 
 		if resp.StatusCode == http.StatusOK {
 			return resp, nil
@@ -46,45 +74,42 @@ func (c *Client) doWithRetry(newReq func() (*http.Request, error)) (*http.Respon
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
 
-		if isRetryableStatus(resp.StatusCode) && attempt < maxRetries {
-			delay := baseDelay * time.Duration(1<<attempt) // exponential: 5s, 10s, 20s, 40s, 80s
-			log.Printf("API returned %d, retrying in %v (attempt %d/%d)", resp.StatusCode, delay, attempt+1, maxRetries)
-			time.Sleep(delay)
+		if isRetryableStatus(resp.StatusCode) && attempt < c.maxRetries {
+			delay := c.baseDelay * time.Duration(1<<attempt) // exponential: 5s, 10s, 20s, 40s, 80s by default
+			if ra := meta.RateLimit.RetryAfter; ra > 0 {
+				delay = ra // the server said when to come back
+			}
+			c.logRetry(ctx, req, resp.StatusCode, delay, attempt+1)
+			if err := sleepContext(ctx, delay); err != nil {
+				return nil, err
+			}
+			meta.retried(delay)
 			continue
 		}
 
-		return nil, fmt.Errorf("API returned non-200 status code %d: %s", resp.StatusCode, string(bodyBytes))
+		meta.finish()
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(bodyBytes), Meta: meta}
 	}
 
 	return nil, fmt.Errorf("max retries exceeded")
 }
 
-// prepareRequest creates and sends a POST request to the specified endpoint.
-// It marshals the body, sets headers, and validates the response status.
-// Retries with exponential backoff on 429, 503, and 529 errors.
-func (c *Client) prepareRequest(body any, endpoint string) (*http.Response, error) {
-	data, err := json.Marshal(body)
-	if err != nil {
-		return nil, fmt.Errorf("error marshaling request: %w", err)
+// bodyReader returns a reader over body, or nil if there is no body.
+func bodyReader(body []byte) io.Reader {
+	if body == nil {
+		return nil
 	}
-
-	url := c.baseURL + endpoint
-	return c.doWithRetry(func() (*http.Request, error) {
-		req, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
-		if err != nil {
-			return nil, err
-		}
-		req.Header.Set("Content-Type", "application/json")
-		return req, nil
-	})
+	return bytes.NewReader(body)
 }
 
-// prepareGet creates and sends a GET request to the specified endpoint.
-// It sets headers and validates the response status.
-// Retries with exponential backoff on 429, 503, and 529 errors.
-func (c *Client) prepareGet(endpoint string) (*http.Response, error) {
-	url := c.baseURL + endpoint
-	return c.doWithRetry(func() (*http.Request, error) {
-		return http.NewRequest("GET", url, nil)
-	})
+// sleepContext waits for d or until ctx is done, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
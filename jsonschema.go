@@ -0,0 +1,567 @@
+package gollama
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ============== Schema generation ==============
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+)
+
+// schemaForType returns the JSON schema describing how encoding/json encodes
+// values of type t, using the struct tags documented on NewTypedTool.
+func schemaForType(t reflect.Type) (map[string]any, error) {
+	return schemaFor(t, map[reflect.Type]bool{})
+}
+
+func schemaFor(t reflect.Type, seen map[reflect.Type]bool) (map[string]any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}, nil
+	case rawMessageType:
+		return map[string]any{}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]any{"type": "integer", "minimum": 0.0}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Interface:
+		return map[string]any{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}, nil
+		}
+		items, err := schemaFor(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		values, err := schemaFor(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		if seen[t] {
+			return nil, fmt.Errorf("recursive type %s", t)
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		props := map[string]any{}
+		required := []string{}
+		if err := structProperties(t, seen, props, &required); err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "properties": props, "required": required}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+// structProperties adds the properties of struct type t to props, flattening
+// embedded structs as encoding/json does.
+func structProperties(t reflect.Type, seen map[reflect.Type]bool, props map[string]any, required *[]string) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := structProperties(ft, seen, props, required); err != nil {
+					return err
+				}
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop, err := schemaFor(f.Type, seen)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		if err := applyFieldTags(prop, f); err != nil {
+			return fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		if f.Type.Kind() == reflect.Pointer {
+			allowNull(prop)
+		}
+		props[name] = prop
+
+		req := f.Type.Kind() != reflect.Pointer && !strings.Contains(","+opts+",", ",omitempty,")
+		if v, ok := f.Tag.Lookup("required"); ok {
+			req = v == "true"
+		}
+		if req {
+			*required = append(*required, name)
+		}
+	}
+	return nil
+}
+
+// applyFieldTags adds the description, enum and bound tags of f to prop.
+func applyFieldTags(prop map[string]any, f reflect.StructField) error {
+	if d := f.Tag.Get("description"); d != "" {
+		prop["description"] = d
+	}
+	if e, ok := f.Tag.Lookup("enum"); ok {
+		var values []any
+		for _, s := range strings.Split(e, ",") {
+			v, err := parseTagValue(prop["type"], strings.TrimSpace(s))
+			if err != nil {
+				return fmt.Errorf("enum: %w", err)
+			}
+			values = append(values, v)
+		}
+		prop["enum"] = values
+	}
+
+	minKey, maxKey := "minimum", "maximum"
+	switch prop["type"] {
+	case "string":
+		minKey, maxKey = "minLength", "maxLength"
+	case "array":
+		minKey, maxKey = "minItems", "maxItems"
+	}
+	for tag, key := range map[string]string{"min": minKey, "max": maxKey} {
+		if s, ok := f.Tag.Lookup(tag); ok {
+			n, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return fmt.Errorf("%s: %w", tag, err)
+			}
+			prop[key] = n
+		}
+	}
+	return nil
+}
+
+// allowNull widens prop, the schema of a pointer field, to accept the null
+// that encoding/json writes for a nil pointer.
+func allowNull(prop map[string]any) {
+	if t, ok := prop["type"].(string); ok {
+		prop["type"] = []any{t, "null"}
+	}
+	if e, ok := prop["enum"].([]any); ok {
+		prop["enum"] = append(e, nil)
+	}
+}
+
+// parseTagValue parses an enum value for a property of the given type.
+func parseTagValue(typ any, s string) (any, error) {
+	switch typ {
+	case "integer":
+		return strconv.ParseInt(s, 10, 64)
+	case "number":
+		return strconv.ParseFloat(s, 64)
+	case "boolean":
+		return strconv.ParseBool(s)
+	default:
+		return s, nil
+	}
+}
+
+// ============== Validation ==============
+
+// ArgumentError reports tool call arguments that do not match the tool's
+// parameter schema. Its message lists every problem in a form the model can
+// act on.
+type ArgumentError struct {
+	Tool     string
+	Problems []ArgumentProblem
+}
+
+// ArgumentProblem is one schema violation. Path locates the offending value
+// in the arguments, e.g. "$.items[2].name".
+type ArgumentProblem struct {
+	Path    string
+	Message string
+}
+
+func (e *ArgumentError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "invalid arguments for tool %q:", e.Tool)
+	for _, p := range e.Problems {
+		fmt.Fprintf(&sb, "\n- %s: %s", p.Path, p.Message)
+	}
+	return sb.String()
+}
+
+// Result returns the tool result reporting e to the model: an IsError
+// result listing the problems, with e itself in Structured.
+func (e *ArgumentError) Result() *ToolResult {
+	return &ToolResult{
+		Content:    e.Error() + "\nCorrect the arguments and call the tool again.",
+		IsError:    true,
+		Structured: e,
+	}
+}
+
+// validateToolArgs checks args against the tool's Params schema. Tools
+// without a schema, or with one that cannot be encoded as JSON, accept any
+// arguments.
+func validateToolArgs(tool *Tool, args any) *ArgumentError {
+	if tool.Params == nil {
+		return nil
+	}
+	schema, err := normalizeSchema(tool.Params)
+	if err != nil || schema == nil {
+		return nil
+	}
+	return validateArgs(tool.Name, schema, args)
+}
+
+// validateArgs checks the decoded JSON value v against schema, returning an
+// ArgumentError listing every problem found, or nil. Local references
+// ("#/...", e.g. into $defs) are resolved against schema.
+func validateArgs(tool string, schema map[string]any, v any) *ArgumentError {
+	sv := &schemaValidator{root: schema}
+	sv.validate(schema, v, "$", 0)
+	if len(sv.problems) > 0 {
+		return &ArgumentError{Tool: tool, Problems: sv.problems}
+	}
+	return nil
+}
+
+// maxSchemaDepth bounds $ref expansion so recursive schemas cannot loop.
+const maxSchemaDepth = 64
+
+// schemaValidator validates values against a JSON schema in decoded JSON
+// form (see normalizeSchema). It implements the subset of JSON Schema used
+// for tool parameters: type, enum, const, properties, required,
+// additionalProperties, items, the numeric, length and item-count bounds,
+// pattern, allOf, anyOf, oneOf, not and local $ref.
+type schemaValidator struct {
+	root     map[string]any
+	problems []ArgumentProblem
+}
+
+func (sv *schemaValidator) add(path, format string, args ...any) {
+	sv.problems = append(sv.problems, ArgumentProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// valid reports whether v matches schema without recording problems.
+func (sv *schemaValidator) valid(schema map[string]any, v any, depth int) bool {
+	sub := &schemaValidator{root: sv.root}
+	sub.validate(schema, v, "$", depth)
+	return len(sub.problems) == 0
+}
+
+func (sv *schemaValidator) validate(schema map[string]any, v any, path string, depth int) {
+	if depth > maxSchemaDepth {
+		return
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		target, ok := sv.resolve(ref)
+		if !ok {
+			return // unresolvable references are not the model's fault
+		}
+		sv.validate(target, v, path, depth+1)
+	}
+
+	if t, ok := schema["type"]; ok && !matchesType(t, v) {
+		if v != nil || schema["nullable"] != true {
+			sv.add(path, "expected %s, got %s", describeType(t), jsonType(v))
+		}
+		return
+	}
+	if e, ok := schema["enum"].([]any); ok && !containsJSON(e, v) {
+		sv.add(path, "must be one of %s", formatJSONList(e))
+	}
+	if c, ok := schema["const"]; ok && !reflect.DeepEqual(c, v) {
+		sv.add(path, "must be %s", formatJSONList([]any{c}))
+	}
+
+	if all, ok := schema["allOf"].([]any); ok {
+		for _, sub := range all {
+			if m, ok := sub.(map[string]any); ok {
+				sv.validate(m, v, path, depth+1)
+			}
+		}
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		alts, ok := schema[key].([]any)
+		if !ok {
+			continue
+		}
+		matched := 0
+		for _, sub := range alts {
+			if m, ok := sub.(map[string]any); ok && sv.valid(m, v, depth+1) {
+				matched++
+			}
+		}
+		switch {
+		case matched == 0:
+			sv.add(path, "does not match any of the allowed schemas")
+		case key == "oneOf" && matched > 1:
+			sv.add(path, "matches more than one of the allowed schemas")
+		}
+	}
+	if not, ok := schema["not"].(map[string]any); ok && sv.valid(not, v, depth+1) {
+		sv.add(path, "matches a disallowed schema")
+	}
+
+	switch v := v.(type) {
+	case float64:
+		if n, ok := schema["minimum"].(float64); ok && v < n {
+			sv.add(path, "must be >= %v", n)
+		}
+		if n, ok := schema["maximum"].(float64); ok && v > n {
+			sv.add(path, "must be <= %v", n)
+		}
+		if n, ok := schema["exclusiveMinimum"].(float64); ok && v <= n {
+			sv.add(path, "must be > %v", n)
+		}
+		if n, ok := schema["exclusiveMaximum"].(float64); ok && v >= n {
+			sv.add(path, "must be < %v", n)
+		}
+	case string:
+		n := utf8.RuneCountInString(v)
+		if m, ok := schema["minLength"].(float64); ok && float64(n) < m {
+			sv.add(path, "must be at least %v characters", m)
+		}
+		if m, ok := schema["maxLength"].(float64); ok && float64(n) > m {
+			sv.add(path, "must be at most %v characters", m)
+		}
+		if p, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(p); err == nil && !re.MatchString(v) {
+				sv.add(path, "must match pattern %q", p)
+			}
+		}
+	case []any:
+		if m, ok := schema["minItems"].(float64); ok && float64(len(v)) < m {
+			sv.add(path, "must have at least %v items", m)
+		}
+		if m, ok := schema["maxItems"].(float64); ok && float64(len(v)) > m {
+			sv.add(path, "must have at most %v items", m)
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				sv.validate(items, item, fmt.Sprintf("%s[%d]", path, i), depth+1)
+			}
+		}
+	case map[string]any:
+		if req, ok := schema["required"].([]any); ok {
+			for _, r := range req {
+				if name, ok := r.(string); ok {
+					if _, present := v[name]; !present {
+						sv.add(path+"."+name, "required property is missing")
+					}
+				}
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if ps, ok := props[k].(map[string]any); ok {
+				sv.validate(ps, v[k], path+"."+k, depth+1)
+				continue
+			}
+			switch ap := schema["additionalProperties"].(type) {
+			case map[string]any:
+				sv.validate(ap, v[k], path+"."+k, depth+1)
+			case bool:
+				if !ap {
+					sv.add(path+"."+k, "unknown property")
+				}
+			}
+		}
+	}
+}
+
+// resolve looks up a local JSON pointer reference such as "#/$defs/Item".
+func (sv *schemaValidator) resolve(ref string) (map[string]any, bool) {
+	return resolveSchemaRef(sv.root, ref)
+}
+
+// resolveSchemaRef looks up a local JSON pointer reference in root.
+func resolveSchemaRef(root map[string]any, ref string) (map[string]any, bool) {
+	if ref == "#" {
+		return root, true
+	}
+	rest, ok := strings.CutPrefix(ref, "#/")
+	if !ok {
+		return nil, false
+	}
+	var cur any = root
+	for _, tok := range strings.Split(rest, "/") {
+		tok = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		cur = m[tok]
+	}
+	m, ok := cur.(map[string]any)
+	return m, ok
+}
+
+// matchesType reports whether v has the JSON schema type t, which is a
+// type name or a list of them.
+func matchesType(t any, v any) bool {
+	switch t := t.(type) {
+	case string:
+		switch t {
+		case "object":
+			_, ok := v.(map[string]any)
+			return ok
+		case "array":
+			_, ok := v.([]any)
+			return ok
+		case "string":
+			_, ok := v.(string)
+			return ok
+		case "number":
+			_, ok := v.(float64)
+			return ok
+		case "integer":
+			f, ok := v.(float64)
+			return ok && f == math.Trunc(f)
+		case "boolean":
+			_, ok := v.(bool)
+			return ok
+		case "null":
+			return v == nil
+		}
+		return true
+	case []any:
+		for _, tt := range t {
+			if matchesType(tt, v) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func describeType(t any) string {
+	if list, ok := t.([]any); ok {
+		var names []string
+		for _, n := range list {
+			names = append(names, fmt.Sprint(n))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+// jsonType names the JSON type of a decoded value.
+func jsonType(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func containsJSON(list []any, v any) bool {
+	for _, e := range list {
+		if reflect.DeepEqual(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatJSONList(list []any) string {
+	var parts []string
+	for _, e := range list {
+		b, _ := json.Marshal(e)
+		parts = append(parts, string(b))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// normalizeSchema converts a schema of any Go type (a map, a
+// ToolFunctionParams, an MCP library type) into decoded JSON form.
+func normalizeSchema(schema any) (map[string]any, error) {
+	if m, ok := schema.(map[string]any); ok && isDecodedJSON(m) {
+		return m, nil
+	}
+	b, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// isDecodedJSON reports whether v contains only the types encoding/json
+// decodes into.
+func isDecodedJSON(v any) bool {
+	switch v := v.(type) {
+	case nil, string, float64, bool:
+		return true
+	case map[string]any:
+		for _, e := range v {
+			if !isDecodedJSON(e) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		for _, e := range v {
+			if !isDecodedJSON(e) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package gollama
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestHandleToolCallValidation(t *testing.T) {
+	called := false
+	// A schema as an MCP server would send it: decoded JSON with $defs.
+	tool := &Tool{
+		Name: "create_order",
+		Params: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"customer": map[string]any{"type": "string", "pattern": "^c_"},
+				"items":    map[string]any{"type": "array", "minItems": 1.0, "items": map[string]any{"$ref": "#/$defs/Item"}},
+				"note":     map[string]any{"anyOf": []any{map[string]any{"type": "string"}, map[string]any{"type": "null"}}},
+			},
+			"required":             []any{"customer", "items"},
+			"additionalProperties": false,
+			"$defs": map[string]any{
+				"Item": map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"sku": map[string]any{"type": "string"}, "qty": map[string]any{"type": "integer", "exclusiveMinimum": 0.0}},
+					"required":   []any{"sku", "qty"},
+				},
+			},
+		},
+		Call: func(ctx context.Context, params any) (*ToolResult, error) {
+			called = true
+			return &ToolResult{Content: "ok"}, nil
+		},
+	}
+
+	bad := agentToolCall("1", "create_order", `{"customer":"bob","items":[{"sku":"a","qty":0},{"qty":"2"}],"note":5,"rush":true}`)
+	res, err := HandleToolCall(context.Background(), []*Tool{tool}, bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if called || !res.IsError {
+		t.Fatalf("invalid call was not rejected: %+v", res)
+	}
+	for _, want := range []string{
+		`invalid arguments for tool "create_order":`,
+		`- $.customer: must match pattern "^c_"`,
+		"- $.items[0].qty: must be > 0",
+		"- $.items[1].sku: required property is missing",
+		"- $.items[1].qty: expected integer, got string",
+		"- $.note: does not match any of the allowed schemas",
+		"- $.rush: unknown property",
+		"Correct the arguments and call the tool again.",
+	} {
+		if !strings.Contains(res.Content, want) {
+			t.Errorf("result missing %q:\n%s", want, res.Content)
+		}
+	}
+	if argErr := res.Structured.(*ArgumentError); len(argErr.Problems) != 6 {
+		t.Errorf("problems = %+v", argErr.Problems)
+	}
+
+	good := agentToolCall("2", "create_order", `{"customer":"c_1","items":[{"sku":"a","qty":2}],"note":null}`)
+	res, err = HandleToolCall(context.Background(), []*Tool{tool}, good)
+	if err != nil || !called || res.Content != "ok" {
+		t.Errorf("valid call: %+v, %v", res, err)
+	}
+}
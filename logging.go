@@ -0,0 +1,247 @@
+package gollama
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// WithLogger sets the logger the client reports request events to:
+//
+//   - Debug: each call starting, and each HTTP attempt's request and response
+//     with headers and body (credentials redacted)
+//   - Info: each call finishing, with status, request ID, retries, latency
+//     and token usage
+//   - Warn: retries after a 429, 503 or 529 response, and malformed tool
+//     call arguments in the history that were fixed with RepairJSON
+//   - Error: calls that failed
+//
+// By default nothing is logged.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) {
+		c.logger = l
+	}
+}
+
+// discardLogger is used when no logger is configured.
+var discardLogger = slog.New(discardHandler{})
+
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// log returns the client's logger.
+func (c *Client) log() *slog.Logger {
+	if c.logger == nil {
+		return discardLogger
+	}
+	return c.logger
+}
+
+// logArgumentRepairs reports tool calls in a request's history whose
+// arguments had to be repaired.
+func (c *Client) logArgumentRepairs(ctx context.Context, repairs []argumentRepair) {
+	for _, r := range repairs {
+		c.log().WarnContext(ctx, "repaired tool call arguments", "tool", r.Tool, "tool_call_id", r.ID, "fixes", r.Fixes)
+	}
+}
+
+// logCallStart reports that call is about to be sent.
+func (c *Client) logCallStart(ctx context.Context, call *Call) {
+	c.log().DebugContext(ctx, "request started", callAttrs(call)...)
+}
+
+// logCallEnd reports the outcome of call: the decoded response out, or err.
+func (c *Client) logCallEnd(ctx context.Context, call *Call, out any, err error, elapsed time.Duration) {
+	attrs := append(callAttrs(call), "latency", elapsed)
+
+	meta, gen := callOutcome(out, err)
+	if gen != nil {
+		attrs = append(attrs,
+			"input_tokens", gen.Usage.PromptTokens,
+			"output_tokens", gen.Usage.CompletionTokens,
+			"cache_read_tokens", gen.Usage.CacheReadInputTokens,
+			"cache_creation_tokens", gen.Usage.CacheCreationInputTokens,
+			"stop_reason", gen.StopReason,
+		)
+	}
+	if meta != nil {
+		attrs = append(attrs, "status", meta.StatusCode, "request_id", meta.RequestID, "retries", meta.Retries)
+	}
+
+	if err != nil {
+		c.log().ErrorContext(ctx, "request failed", append(attrs, "error", err)...)
+		return
+	}
+	c.log().InfoContext(ctx, "request finished", attrs...)
+}
+
+// callOutcome extracts the ResponseMeta of a call's decoded response or
+// error, and the response itself if it is a chat response.
+func callOutcome(out any, err error) (*ResponseMeta, *ResponseMessageGenerate) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Meta, nil
+	}
+	switch r := out.(type) {
+	case *ResponseMessageGenerate:
+		if r != nil {
+			return r.Meta, r
+		}
+	case *ResponseMessage:
+		if r != nil {
+			return r.Meta, nil
+		}
+	case *GenerateResponse:
+		if r != nil {
+			return r.Meta, nil
+		}
+	}
+	return nil, nil
+}
+
+// logRetry reports that a request will be retried after delay.
+func (c *Client) logRetry(ctx context.Context, req *http.Request, status int, delay time.Duration, attempt int) {
+	c.log().WarnContext(ctx, "retrying request",
+		"method", req.Method,
+		"url", redactSecrets(req.URL.String()),
+		"status", status,
+		"delay", delay,
+		"attempt", attempt,
+		"max_retries", c.maxRetries,
+	)
+}
+
+// logRequest dumps an outgoing request at debug level.
+func (c *Client) logRequest(ctx context.Context, req *http.Request) {
+	if !c.log().Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	var body []byte
+	if req.GetBody != nil {
+		if rc, err := req.GetBody(); err == nil {
+			body, _ = io.ReadAll(rc)
+			rc.Close()
+		}
+	}
+	c.log().DebugContext(ctx, "http request",
+		"method", req.Method,
+		"url", redactSecrets(req.URL.String()),
+		"header", redactHeader(req.Header),
+		"body", redactSecrets(string(body)),
+	)
+}
+
+// logResponse dumps a response at debug level. It reads the body and
+// replaces it with an in-memory copy so the caller can still decode it.
+// Streaming bodies are left alone, so events still reach the caller as they
+// arrive.
+func (c *Client) logResponse(ctx context.Context, resp *http.Response) {
+	if !c.log().Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	if isStreamingResponse(resp) {
+		c.log().DebugContext(ctx, "http response",
+			"status", resp.StatusCode,
+			"header", redactHeader(resp.Header),
+			"body", "(streaming)",
+		)
+		return
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), errReader{err}))
+	c.log().DebugContext(ctx, "http response",
+		"status", resp.StatusCode,
+		"header", redactHeader(resp.Header),
+		"body", redactSecrets(string(body)),
+	)
+}
+
+// isStreamingResponse reports whether resp carries a stream of events, as
+// server-sent events or newline-delimited JSON.
+func isStreamingResponse(resp *http.Response) bool {
+	mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mt == "text/event-stream" || mt == "application/x-ndjson"
+}
+
+// errReader returns err, or io.EOF if err is nil, so a body that failed to
+// read while being logged still fails for the caller.
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	return 0, io.EOF
+}
+
+func callAttrs(call *Call) []any {
+	attrs := []any{"provider", call.Provider, "operation", call.Operation, "endpoint", redactSecrets(call.Endpoint)}
+	if call.Options != nil {
+		attrs = append(attrs, "model", call.Options.Model)
+	}
+	return attrs
+}
+
+// sensitiveHeaders lists headers that carry credentials, in canonical form.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":        true,
+	"Proxy-Authorization":  true,
+	"X-Api-Key":            true,
+	"Api-Key":              true,
+	"X-Amz-Security-Token": true,
+	"Cookie":               true,
+	"Set-Cookie":           true,
+}
+
+// redactHeader returns a copy of h with credentials replaced. For
+// Authorization headers the scheme ("Bearer", "AWS4-HMAC-SHA256") is kept.
+func redactHeader(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for k, vs := range h {
+		if !sensitiveHeaders[http.CanonicalHeaderKey(k)] {
+			out[k] = vs
+			continue
+		}
+		redacted := make([]string, len(vs))
+		for i, v := range vs {
+			if scheme, _, ok := strings.Cut(v, " "); ok && strings.HasSuffix(k, "Authorization") {
+				redacted[i] = scheme + " [REDACTED]"
+			} else {
+				redacted[i] = "[REDACTED]"
+			}
+		}
+		out[k] = redacted
+	}
+	return out
+}
+
+// secretPattern matches credentials that may appear in URLs and bodies:
+// provider API keys and AWS presigned-URL signatures and tokens.
+var secretPattern = regexp.MustCompile(`(sk-[A-Za-z0-9_\-]{8,}|(?i:(x-amz-signature|x-amz-security-token|x-amz-credential|signature|api[_-]?key|key)=)[^&\s"]+)`)
+
+// jsonSecretPattern matches string values of JSON object keys that name
+// credentials, such as "api_key":"...", in request and response bodies.
+var jsonSecretPattern = regexp.MustCompile(`(?i)("(?:api[_-]?key|x-api-key|key|secret|client[_-]?secret|password|token|access[_-]?token|refresh[_-]?token|session[_-]?token|authorization)"\s*:\s*")(?:[^"\\]|\\.)*"`)
+
+// redactSecrets replaces credentials in s with [REDACTED].
+func redactSecrets(s string) string {
+	s = jsonSecretPattern.ReplaceAllString(s, `${1}[REDACTED]"`)
+	return secretPattern.ReplaceAllStringFunc(s, func(m string) string {
+		if name, _, ok := strings.Cut(m, "="); ok {
+			return name + "=[REDACTED]"
+		}
+		return "[REDACTED]"
+	})
+}
@@ -0,0 +1,107 @@
+package gollama
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientLogging(t *testing.T) {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if r.URL.Path == "/v1/messages" && attempts == 1 {
+			http.Error(w, `{"type":"error","error":{"type":"overloaded_error"}}`, 529)
+			return
+		}
+		w.Header().Set("request-id", "req_1")
+		fmt.Fprint(w, anthropicOKBody)
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	opts := RequestOptions{Model: "claude", Messages: []Message{{Role: "user", Content: "hi"}}}
+
+	anthropic := NewClient(srv.URL, WithAnthropicMode(true), WithAPIKey("sk-ant-supersecret123"),
+		WithLogger(logger), WithRetryPolicy(1, time.Millisecond))
+	if _, err := anthropic.Turn(opts); err != nil {
+		t.Fatal(err)
+	}
+	bedrock := NewClient(srv.URL, WithAWSAuth("us-east-1", "AKIDEXAMPLE", "secret", "sessiontoken"), WithLogger(logger))
+	if _, err := bedrock.Turn(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`level=DEBUG msg="request started" provider=anthropic operation=chat`,
+		`level=WARN msg="retrying request"`, `status=529`,
+		`level=INFO msg="request finished" provider=anthropic`, `request_id=req_1`, `retries=1`,
+		`input_tokens=3`, `output_tokens=1`,
+		`msg="http request"`, `msg="http response"`, `\"text\":\"hi\"`,
+		`provider=bedrock`, `Authorization:[AWS4-HMAC-SHA256 [REDACTED]]`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log output missing %q", want)
+		}
+	}
+	for _, secret := range []string{"supersecret", "AKIDEXAMPLE", "sessiontoken", "Signature="} {
+		if strings.Contains(out, secret) {
+			t.Errorf("log output leaks %q", secret)
+		}
+	}
+	if t.Failed() {
+		t.Log(out)
+	}
+}
+
+// TestLogStreamingResponse checks that debug logging does not buffer a
+// streaming body, which would hold back every event until the stream ends.
+func TestLogStreamingResponse(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewClient("http://localhost", WithLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go pw.Write([]byte("data: {}\n\n"))
+	resp := &http.Response{StatusCode: 200, Header: http.Header{"Content-Type": {"text/event-stream; charset=utf-8"}}, Body: pr}
+
+	done := make(chan struct{})
+	go func() {
+		c.logResponse(context.Background(), resp)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("logResponse blocked on an open stream")
+	}
+	line := make([]byte, 10)
+	if _, err := io.ReadFull(resp.Body, line); err != nil || string(line) != "data: {}\n\n" {
+		t.Errorf("body = %q, %v", line, err)
+	}
+	if !strings.Contains(buf.String(), `body=(streaming)`) {
+		t.Errorf("log = %s", buf.String())
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	for in, want := range map[string]string{
+		"https://s3.amazonaws.com/f?X-Amz-Credential=AKID%2F2024&X-Amz-Signature=abc123&x=1": "https://s3.amazonaws.com/f?X-Amz-Credential=[REDACTED]&X-Amz-Signature=[REDACTED]&x=1",
+		`{"api_key":"k","token":"sk-proj-abcdefghijkl"}`:                                     `{"api_key":"[REDACTED]","token":"[REDACTED]"}`,
+		`{"Password": "p\"w", "max_tokens": 5, "keys": "a"}`:                                 `{"Password": "[REDACTED]", "max_tokens": 5, "keys": "a"}`,
+		"/models?key=AIzaSy123": "/models?key=[REDACTED]",
+	} {
+		if got := redactSecrets(in); got != want {
+			t.Errorf("redactSecrets(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
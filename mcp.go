@@ -3,6 +3,7 @@ package gollama
 import (
 	"context"
 	"fmt"
+	"time"
 
 	mcpgo "github.com/metoro-io/mcp-golang"
 	http "github.com/metoro-io/mcp-golang/transport/http"
@@ -16,14 +17,26 @@ type ToolResult struct {
 	Images     []string   // optional base64 encoded images
 	Documents  []Document // optional documents (e.g. PDFs)
 	IsError    bool       // true if this result represents an error
-	Structured any        // optional structured form consumed by codemode/bridge paths; agent-loop path ignores it
+	Structured any        // optional structured form consumed by code-mode bridges (typed by Tool.OutputType); agent-loop path ignores it
+	// Repairs lists the fixes RepairJSON applied to malformed call arguments
+	// before the tool ran. Set by HandleToolCall.
+	Repairs []string
+	// Usage, if set, is the token usage of model calls the tool made itself,
+	// such as a sub-agent's; RunAgent adds it to AgentResult.ToolUsage.
+	Usage *Usage
 }
 
 type Tool struct {
 	Name        string
 	Description string
 	Params      any
-	OutputType  any // optional Go value whose reflected type describes the structured response shape (used by codemode typegen)
+	OutputType  any // optional Go value whose reflected type describes the structured response shape (see GenerateTypeScript)
+
+	// Sequential marks a tool that is not safe to run concurrently with
+	// other tool calls; ExecuteToolCalls runs it on its own.
+	Sequential bool
+	// Timeout bounds each call of the tool, overriding ToolExecOptions.Timeout.
+	Timeout time.Duration
 
 	Call func(context.Context, any) (*ToolResult, error)
 }
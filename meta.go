@@ -0,0 +1,82 @@
+package gollama
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ResponseMeta describes the HTTP exchange that produced a response: the
+// provider's request ID for support tickets, the rate-limit state it reported,
+// how many retries were needed and how long the call took.
+type ResponseMeta struct {
+	// RequestID is the provider's identifier for the final attempt
+	// (Anthropic request-id, OpenAI x-request-id, Bedrock x-amzn-RequestId).
+	RequestID  string
+	StatusCode int
+	// Retries is the number of attempts that were retried after a 429, 503
+	// or 529 response.
+	Retries   int
+	RateLimit RateLimitInfo
+	Header    http.Header // headers of the final attempt's response
+
+	Started time.Time // when the first attempt was sent
+	// TimeToHeaders is the time from sending the final attempt until its
+	// response headers arrived.
+	TimeToHeaders time.Duration
+	// RetryWait is the total time spent in backoff between attempts.
+	RetryWait time.Duration
+	// Duration is the wall-clock time of the whole call, from the first
+	// attempt until the response was decoded.
+	Duration time.Duration
+}
+
+// requestIDHeaders lists the response headers providers use for request IDs,
+// in order of preference.
+var requestIDHeaders = []string{"request-id", "x-request-id", "x-amzn-requestid", "x-amz-request-id"}
+
+// begin stamps the start of the call if it has not been stamped yet.
+func (m *ResponseMeta) begin() {
+	if m.Started.IsZero() {
+		m.Started = time.Now()
+	}
+}
+
+// record captures the status and headers of an attempt's response.
+func (m *ResponseMeta) record(resp *http.Response, sent time.Time) {
+	now := time.Now()
+	m.StatusCode = resp.StatusCode
+	m.Header = resp.Header
+	m.TimeToHeaders = now.Sub(sent)
+	m.RateLimit = ParseRateLimitHeaders(resp.Header, now)
+	m.RequestID = ""
+	for _, h := range requestIDHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			m.RequestID = v
+			break
+		}
+	}
+}
+
+// retried records a backoff of delay before the next attempt.
+func (m *ResponseMeta) retried(delay time.Duration) {
+	m.Retries++
+	m.RetryWait += delay
+}
+
+// finish stamps the total duration of the call.
+func (m *ResponseMeta) finish() {
+	m.Duration = time.Since(m.Started)
+}
+
+// APIError is returned when a provider responds with a non-200 status that is
+// not retried (or is still failing once retries are exhausted).
+type APIError struct {
+	StatusCode int
+	Body       string
+	Meta       *ResponseMeta
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API returned non-200 status code %d: %s", e.StatusCode, e.Body)
+}
@@ -0,0 +1,82 @@
+package gollama
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const anthropicOKBody = `{"role":"assistant","model":"claude","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":3,"output_tokens":1}}`
+
+func TestResponseMeta(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/messages":
+			w.Header().Set("request-id", "req_ant_1")
+			w.Header().Set("anthropic-ratelimit-requests-limit", "50")
+			w.Header().Set("anthropic-ratelimit-requests-remaining", "42")
+			fmt.Fprint(w, anthropicOKBody)
+		case "/model/claude/invoke":
+			w.Header().Set("x-amzn-RequestId", "bedrock-req-1")
+			fmt.Fprint(w, anthropicOKBody)
+		case "/chat/completions":
+			w.Header().Set("x-request-id", "req_oai_1")
+			w.Header().Set("x-ratelimit-limit-tokens", "1000")
+			w.Header().Set("x-ratelimit-remaining-tokens", "990")
+			fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`)
+		default:
+			http.Error(w, `{"error":"nope"}`, http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	opts := RequestOptions{Model: "claude", Messages: []Message{{Role: "user", Content: "hi"}}}
+
+	anthropic := NewClient(srv.URL)
+	anthropic.SetAnthropicMode(true)
+	resp, err := anthropic.Turn(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := resp.Meta
+	if m == nil || m.RequestID != "req_ant_1" || m.StatusCode != 200 || m.Retries != 0 {
+		t.Fatalf("anthropic meta = %+v", m)
+	}
+	if m.RateLimit.Requests == nil || m.RateLimit.Requests.Remaining != 42 {
+		t.Errorf("anthropic rate limit = %+v", m.RateLimit.Requests)
+	}
+	if m.Started.IsZero() || m.Duration <= 0 || m.TimeToHeaders <= 0 || m.TimeToHeaders > m.Duration {
+		t.Errorf("timings: started=%v ttfb=%v duration=%v", m.Started, m.TimeToHeaders, m.Duration)
+	}
+
+	bedrock := NewClient(srv.URL)
+	bedrock.SetAWSAuth("us-east-1", "AKID", "secret", "")
+	resp, err = bedrock.Turn(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Meta == nil || resp.Meta.RequestID != "bedrock-req-1" {
+		t.Errorf("bedrock meta = %+v", resp.Meta)
+	}
+
+	openai := NewClient(srv.URL)
+	resp, err = openai.Turn(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Meta == nil || resp.Meta.RequestID != "req_oai_1" || resp.Meta.RateLimit.Tokens == nil || resp.Meta.RateLimit.Tokens.Remaining != 990 {
+		t.Errorf("openai meta = %+v", resp.Meta)
+	}
+
+	// Non-200 responses surface as *APIError carrying the same metadata.
+	_, err = NewClient(srv.URL + "/missing").Turn(opts)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want *APIError", err)
+	}
+	if apiErr.StatusCode != 404 || apiErr.Meta == nil || apiErr.Meta.StatusCode != 404 {
+		t.Errorf("api error = %+v", apiErr)
+	}
+}
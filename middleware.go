@@ -0,0 +1,137 @@
+package gollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Call describes one logical API call as seen by a Middleware: a chat
+// completion, an Ollama generate, a batch operation and so on. Retries of the
+// same call happen below the middleware chain and are not seen as new calls.
+//
+// Middleware may modify Endpoint, Body and Header before passing the call on.
+type Call struct {
+	// Provider is the API flavor the call targets: "openai", "anthropic",
+	// "bedrock" or "ollama".
+	Provider string
+	// Operation names the call, e.g. "chat", "generate", "models.list",
+	// "batch.create", "batch.get", "batch.list", "batch.cancel",
+	// "batch.results", "file.upload" or "file.content".
+	Operation string
+	// Options holds the caller's request options for chat and generate
+	// calls, and is nil otherwise. Changing it has no effect on the request;
+	// edit Body instead.
+	Options *RequestOptions
+
+	Method   string
+	Endpoint string // path relative to the client's base URL, including any query
+	// Body is the serialized provider request body, or nil for requests
+	// without one.
+	Body []byte
+	// Header holds extra request headers. They are applied after the
+	// client's own headers, so they take precedence.
+	Header http.Header
+}
+
+// Handler performs a Call and returns the decoded response: a
+// *ResponseMessageGenerate for chat calls, a *GenerateResponse or
+// *ResponseMessage for Ollama calls, a *Batch or []BatchResult for batch
+// calls, and so on.
+type Handler func(ctx context.Context, call *Call) (any, error)
+
+// Middleware wraps a Handler to add behavior around every call a Client
+// makes, such as audit logging, header injection, request rewriting or fault
+// injection. A middleware may return without calling next, but if it
+// replaces the response it must return a value of the same type.
+type Middleware func(next Handler) Handler
+
+// WithMiddleware appends middleware to the client's chain. Middleware run in
+// the order they were added, the first one outermost. With can be used to add
+// middleware for a single request.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(c *Client) {
+		c.middleware = append(c.middleware[:len(c.middleware):len(c.middleware)], mw...)
+	}
+}
+
+// newCall builds a Call, marshaling body as JSON unless it is nil and the
+// method is GET.
+func newCall(provider, op, method, endpoint string, opts *RequestOptions, body any) (*Call, error) {
+	call := &Call{
+		Provider:  provider,
+		Operation: op,
+		Options:   opts,
+		Method:    method,
+		Endpoint:  endpoint,
+		Header:    make(http.Header),
+	}
+	if method != http.MethodGet {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling request: %w", err)
+		}
+		call.Body = data
+	}
+	return call, nil
+}
+
+// invoke runs call through the client's middleware chain with send as the
+// innermost handler.
+func invoke[T any](ctx context.Context, c *Client, call *Call, send func(ctx context.Context, call *Call) (T, error)) (T, error) {
+	var h Handler = func(ctx context.Context, call *Call) (any, error) {
+		start := time.Now()
+		c.logCallStart(ctx, call)
+		out, err := send(ctx, call)
+		c.logCallEnd(ctx, call, out, err, time.Since(start))
+		return out, err
+	}
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		h = c.middleware[i](h)
+	}
+
+	ctx, endSpan := c.startCallSpan(ctx, call)
+	out, err := h(ctx, call)
+	endSpan(out, err)
+
+	var zero T
+	if err != nil {
+		return zero, err
+	}
+	res, ok := out.(T)
+	if !ok {
+		return zero, fmt.Errorf("middleware returned %T for %s %s, want %T", out, call.Provider, call.Operation, zero)
+	}
+	return res, nil
+}
+
+// roundTrip sends call's request with retries (see doWithRetry) and returns
+// the successful response.
+func (c *Client) roundTrip(ctx context.Context, meta *ResponseMeta, call *Call) (*http.Response, error) {
+	return c.doWithRetry(ctx, meta, call.Header, func() (*http.Request, error) {
+		req, err := http.NewRequest(call.Method, c.baseURL+call.Endpoint, bodyReader(call.Body))
+		if err != nil {
+			return nil, err
+		}
+		if call.Body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		return req, nil
+	})
+}
+
+// do runs call through the middleware chain, sending it with roundTrip and
+// decoding the successful response with decode.
+func do[T any](ctx context.Context, c *Client, call *Call, decode func(resp *http.Response) (T, error)) (T, error) {
+	return invoke(ctx, c, call, func(ctx context.Context, call *Call) (T, error) {
+		resp, err := c.roundTrip(ctx, nil, call)
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+		defer resp.Body.Close()
+		return decode(resp)
+	})
+}
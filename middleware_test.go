@@ -0,0 +1,122 @@
+package gollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestMiddlewareChain checks that middleware run in order around every
+// backend's chat path, see the serialized body and decoded response, and can
+// add headers and rewrite the body.
+func TestMiddlewareChain(t *testing.T) {
+	var gotHeader, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("x-audit")
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		switch r.URL.Path {
+		case "/v1/messages", "/model/claude/invoke":
+			fmt.Fprint(w, anthropicOKBody)
+		case "/chat/completions":
+			fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`)
+		case "/api/chat":
+			fmt.Fprint(w, `{"message":{"role":"assistant","content":"ok"},"done":true}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	var trace []string
+	tracer := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, call *Call) (any, error) {
+				trace = append(trace, name+">"+call.Provider+"."+call.Operation)
+				out, err := next(ctx, call)
+				trace = append(trace, fmt.Sprintf("%s<%T", name, out))
+				return out, err
+			}
+		}
+	}
+	rewrite := func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (any, error) {
+			if call.Options == nil || call.Options.Model != "claude" {
+				t.Errorf("call options = %+v", call.Options)
+			}
+			call.Header.Set("x-audit", "yes")
+			call.Body = []byte(strings.Replace(string(call.Body), "hello", "HELLO", 1))
+			return next(ctx, call)
+		}
+	}
+
+	opts := RequestOptions{Model: "claude", Messages: []Message{{Role: "user", Content: "hello"}}}
+	for _, tc := range []struct {
+		provider string
+		client   *Client
+		run      func(c *Client) (any, error)
+		want     string
+	}{
+		{"openai", NewClient(srv.URL), func(c *Client) (any, error) { return c.Turn(opts) }, "*gollama.ResponseMessageGenerate"},
+		{"anthropic", NewClient(srv.URL, WithAnthropicMode(true)), func(c *Client) (any, error) { return c.Turn(opts) }, "*gollama.ResponseMessageGenerate"},
+		{"bedrock", NewClient(srv.URL, WithAWSAuth("us-east-1", "AKID", "secret", "")), func(c *Client) (any, error) { return c.Turn(opts) }, "*gollama.ResponseMessageGenerate"},
+		{"ollama", NewClient(srv.URL), func(c *Client) (any, error) { return c.Chat(opts) }, "*gollama.ResponseMessage"},
+	} {
+		t.Run(tc.provider, func(t *testing.T) {
+			trace = nil
+			c := tc.client.With(WithMiddleware(tracer("a"), tracer("b")), WithMiddleware(rewrite))
+			if _, err := tc.run(c); err != nil {
+				t.Fatal(err)
+			}
+
+			op := tc.provider + ".chat"
+			want := []string{"a>" + op, "b>" + op, "b<" + tc.want, "a<" + tc.want}
+			if strings.Join(trace, " ") != strings.Join(want, " ") {
+				t.Errorf("trace = %v, want %v", trace, want)
+			}
+			if gotHeader != "yes" {
+				t.Errorf("x-audit header = %q", gotHeader)
+			}
+			if !strings.Contains(gotBody, "HELLO") {
+				t.Errorf("body was not rewritten: %s", gotBody)
+			}
+		})
+	}
+}
+
+// TestMiddlewareFaultInjection checks that a middleware can fail a call
+// without it reaching the server, including on the batch path.
+func TestMiddlewareFaultInjection(t *testing.T) {
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer srv.Close()
+
+	injected := errors.New("injected")
+	var ops []string
+	c := NewClient(srv.URL, WithAnthropicMode(true), WithMiddleware(func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (any, error) {
+			ops = append(ops, call.Operation)
+			return nil, injected
+		}
+	}))
+
+	if _, err := c.GetBatch("b1"); !errors.Is(err, injected) {
+		t.Errorf("GetBatch err = %v", err)
+	}
+	if _, err := c.Turn(RequestOptions{Model: "m"}); !errors.Is(err, injected) {
+		t.Errorf("Turn err = %v", err)
+	}
+	if hits != 0 {
+		t.Errorf("server saw %d requests", hits)
+	}
+	if strings.Join(ops, ",") != "batch.get,chat" {
+		t.Errorf("ops = %v", ops)
+	}
+}
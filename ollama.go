@@ -1,6 +1,7 @@
 package gollama
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -9,20 +10,33 @@ import (
 // Generate sends a completion request to the Ollama /api/generate endpoint.
 // Returns a GenerateResponse with the generated text and metadata.
 func (c *Client) Generate(opts RequestOptions) (*GenerateResponse, error) {
-	// Set up request
-	resp, err := c.prepareRequest(opts, "/api/generate")
+	call, err := newCall("ollama", "generate", http.MethodPost, "/api/generate", &opts, opts)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	return invoke(context.Background(), c, call, func(ctx context.Context, call *Call) (*GenerateResponse, error) {
+		// Set up request
+		meta := &ResponseMeta{}
+		resp, err := c.roundTrip(ctx, meta, call)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
 
-	// Handle streaming if requested
-	if opts.Stream {
-		return c.handleGenerateStream(resp)
-	}
+		// Handle streaming if requested
+		if opts.Stream {
+			return c.handleGenerateStream(resp)
+		}
 
-	// Handle regular response
-	return c.handleGenerateResponse(resp)
+		// Handle regular response
+		result, err := c.handleGenerateResponse(resp)
+		if err != nil {
+			return nil, err
+		}
+		meta.finish()
+		result.Meta = meta
+		return result, nil
+	})
 }
 
 // handleGenerateResponse processes a non-streaming generate response.
@@ -54,20 +68,33 @@ func (c *Client) handleGenerateStream(resp *http.Response) (*GenerateResponse, e
 // Chat sends a chat completion request to the Ollama /api/chat endpoint.
 // Returns a ResponseMessage with the assistant's reply and metadata.
 func (c *Client) Chat(opts RequestOptions) (*ResponseMessage, error) {
-	// Set up request
-	resp, err := c.prepareRequest(opts, "/api/chat")
+	call, err := newCall("ollama", "chat", http.MethodPost, "/api/chat", &opts, opts)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	return invoke(context.Background(), c, call, func(ctx context.Context, call *Call) (*ResponseMessage, error) {
+		// Set up request
+		meta := &ResponseMeta{}
+		resp, err := c.roundTrip(ctx, meta, call)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
 
-	// Handle streaming if requested
-	if opts.Stream {
-		return c.handleChatStream(resp)
-	}
+		// Handle streaming if requested
+		if opts.Stream {
+			return c.handleChatStream(resp)
+		}
 
-	// Handle regular response
-	return c.handleChatResponse(resp)
+		// Handle regular response
+		result, err := c.handleChatResponse(resp)
+		if err != nil {
+			return nil, err
+		}
+		meta.finish()
+		result.Meta = meta
+		return result, nil
+	})
 }
 
 // handleChatResponse processes a non-streaming chat response.
@@ -1,26 +1,33 @@
 package gollama
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 )
 
 // ListModels retrieves the list of available models from the OpenAI-compatible /models endpoint.
 func (c *Client) ListModels() ([]ModelDesc, error) {
-	resp, err := c.prepareGet("/models")
+	call, err := newCall("openai", "models.list", http.MethodGet, "/models", nil, nil)
 	if err != nil {
 		return nil, err
 	}
+	return invoke(context.Background(), c, call, func(ctx context.Context, call *Call) ([]ModelDesc, error) {
+		resp, err := c.roundTrip(ctx, nil, call)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
 
-	defer resp.Body.Close()
-
-	var out listModelsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return nil, err
-	}
+		var out listModelsResponse
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			return nil, err
+		}
 
-	return out.Data, nil
+		return out.Data, nil
+	})
 }
 
 // openaiRequest is the request body for OpenAI-compatible /chat/completions endpoints.
@@ -37,6 +44,7 @@ type openaiRequest struct {
 	MaxTokens   int         `json:"max_tokens,omitempty"`
 	Temperature *float64    `json:"temperature,omitempty"`
 	TopP        *float64    `json:"top_p,omitempty"`
+	Stop        []string    `json:"stop,omitempty"`
 	Options     *Options    `json:"options,omitempty"`
 }
 
@@ -45,16 +53,47 @@ type openaiRequest struct {
 // Otherwise, uses the OpenAI-compatible /chat/completions endpoint.
 // Returns a ResponseMessageGenerate with choices and usage information.
 func (c *Client) ChatCompletion(opts RequestOptions) (*ResponseMessageGenerate, error) {
+	return c.ChatCompletionContext(context.Background(), opts)
+}
+
+// ChatCompletionContext is ChatCompletion with a context. Cancelling ctx aborts
+// the in-flight HTTP request and any pending retry backoff.
+// If a RateLimiter is set, the call first waits for capacity.
+func (c *Client) ChatCompletionContext(ctx context.Context, opts RequestOptions) (*ResponseMessageGenerate, error) {
+	limiter := c.rateLimiter()
+	if limiter == nil {
+		return c.chatCompletion(ctx, opts)
+	}
+
+	res, err := limiter.acquire(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	// Every attempt's rate-limit headers reach the limiter, including those
+	// of responses that are retried.
+	ctx = withRateLimitObserver(ctx, func(info RateLimitInfo) { limiter.observe(opts.Model, info) })
+	resp, err := c.chatCompletion(ctx, opts)
+	limiter.complete(res, resp, err)
+	return resp, err
+}
+
+// chatCompletion dispatches a chat request to the configured backend.
+func (c *Client) chatCompletion(ctx context.Context, opts RequestOptions) (*ResponseMessageGenerate, error) {
 	// Use AWS Bedrock endpoint
 	if c.IsBedrockAPI() {
-		return c.ChatCompletionBedrock(opts)
+		return c.chatCompletionBedrock(ctx, opts)
 	}
 
 	// Use native Anthropic API for caching support
 	if c.IsAnthropicAPI() {
-		return c.ChatCompletionAnthropic(opts)
+		return c.chatCompletionAnthropic(ctx, opts)
 	}
 
+	return c.chatCompletionOpenAI(ctx, opts)
+}
+
+// chatCompletionOpenAI sends a request to an OpenAI-compatible /chat/completions endpoint.
+func (c *Client) chatCompletionOpenAI(ctx context.Context, opts RequestOptions) (*ResponseMessageGenerate, error) {
 	// For OpenAI-compatible APIs, inject system prompt as a system-role message
 	// at the front of the messages array. SystemBlocks takes priority over System string.
 	messages := opts.Messages
@@ -73,28 +112,24 @@ func (c *Client) ChatCompletion(opts RequestOptions) (*ResponseMessageGenerate,
 
 	// Normalize tool parameters for strict OpenAI-compatible servers (e.g. llama.cpp)
 	// that reject null where an array is expected. Replace nil slices/maps with
-	// empty ones so they serialize as [] / {} instead of null.
-	tools := opts.Tools
-	for i := range tools {
-		if tools[i].Function == nil {
-			continue
-		}
-		if tfp, ok := tools[i].Function.Parameters.(*ToolFunctionParams); ok {
-			if tfp.Required == nil {
-				tfp.Required = []string{}
-			}
-			if tfp.Properties == nil {
-				tfp.Properties = map[string]any{}
+	// empty ones so they serialize as [] / {} instead of null. The tool
+	// definitions are copied first: callers commonly share one []ToolParam
+	// across concurrent requests.
+	var tools []ToolParam
+	for _, t := range opts.Tools {
+		if t.Function != nil {
+			fn := *t.Function
+			if tfp, ok := fn.Parameters.(*ToolFunctionParams); ok && tfp != nil {
+				p := *tfp
+				normalizeToolParams(&p)
+				fn.Parameters = &p
+			} else if tfp, ok := fn.Parameters.(ToolFunctionParams); ok {
+				normalizeToolParams(&tfp)
+				fn.Parameters = tfp
 			}
-		} else if tfp, ok := tools[i].Function.Parameters.(ToolFunctionParams); ok {
-			if tfp.Required == nil {
-				tfp.Required = []string{}
-			}
-			if tfp.Properties == nil {
-				tfp.Properties = map[string]any{}
-			}
-			tools[i].Function.Parameters = tfp
+			t.Function = &fn
 		}
+		tools = append(tools, t)
 	}
 
 	// Build a clean request with field order optimized for prefix caching:
@@ -143,18 +178,36 @@ func (c *Client) ChatCompletion(opts RequestOptions) (*ResponseMessageGenerate,
 	}
 
 	// Set up request for OpenAI-compatible endpoint
-	resp, err := c.prepareRequest(body, "/chat/completions")
+	call, err := newCall("openai", "chat", http.MethodPost, "/chat/completions", &opts, body)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	return invoke(ctx, c, call, func(ctx context.Context, call *Call) (*ResponseMessageGenerate, error) {
+		meta := &ResponseMeta{}
+		resp, err := c.roundTrip(ctx, meta, call)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
 
-	// Handle regular response
-	decoder := json.NewDecoder(resp.Body)
-	var response ResponseMessageGenerate
-	if err := decoder.Decode(&response); err != nil {
-		return nil, fmt.Errorf("error decoding response: %w", err)
-	}
+		decoder := json.NewDecoder(resp.Body)
+		var response ResponseMessageGenerate
+		if err := decoder.Decode(&response); err != nil {
+			return nil, fmt.Errorf("error decoding response: %w", err)
+		}
+		meta.finish()
+		response.Meta = meta
 
-	return &response, nil
+		return &response, nil
+	})
+}
+
+// normalizeToolParams replaces nil Required/Properties with empty values.
+func normalizeToolParams(p *ToolFunctionParams) {
+	if p.Required == nil {
+		p.Required = []string{}
+	}
+	if p.Properties == nil {
+		p.Properties = map[string]any{}
+	}
 }
@@ -0,0 +1,313 @@
+package gollama
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// PoolStrategy selects how a PoolClient spreads requests over its endpoints.
+type PoolStrategy int
+
+const (
+	// RoundRobin sends requests to each endpoint in turn.
+	RoundRobin PoolStrategy = iota
+	// LeastOutstanding sends each request to the endpoint with the fewest
+	// requests in flight.
+	LeastOutstanding
+	// Weighted spreads requests in proportion to PoolEndpoint.Weight.
+	Weighted
+)
+
+// PoolEndpoint is one server of a PoolClient.
+type PoolEndpoint struct {
+	BaseURL string
+	Weight  int // relative share of requests under Weighted (default 1)
+}
+
+// PoolOptions configures a PoolClient.
+type PoolOptions struct {
+	Strategy PoolStrategy
+	// ClientOptions are applied to the Client created for each endpoint,
+	// e.g. WithBearerToken or WithTimeout.
+	ClientOptions []Option
+
+	// HealthCheckInterval is how often each endpoint is probed. Zero
+	// disables probing; ejected endpoints then return after EjectDuration.
+	HealthCheckInterval time.Duration
+	// HealthCheckPath is the path probed with a GET, relative to the
+	// endpoint's base URL (default "/models"). An endpoint is unhealthy if
+	// the probe cannot connect, times out or gets a 5xx response; any other
+	// response, such as the 404 a native Ollama server gives for /models,
+	// shows it is up.
+	HealthCheckPath string
+	// HealthCheckTimeout bounds each probe (default 5s).
+	HealthCheckTimeout time.Duration
+	// EjectDuration is how long an endpoint is taken out of rotation after a
+	// connection error or failed probe (default 30s). A successful probe
+	// brings it back early.
+	EjectDuration time.Duration
+}
+
+// PoolClient spreads Turns over several servers running the same model, such
+// as a set of Ollama or vLLM boxes. Endpoints that fail a health probe or a
+// connection are ejected from rotation for a while, and a request whose
+// connection fails is retried on another endpoint.
+//
+// A PoolClient is safe for concurrent use. Call Close to stop health probes.
+type PoolClient struct {
+	endpoints []*poolEndpoint
+	opts      PoolOptions
+	now       func() time.Time
+
+	mu   sync.Mutex
+	next int // round-robin position
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type poolEndpoint struct {
+	PoolEndpoint
+	client *Client
+
+	// guarded by PoolClient.mu
+	outstanding  int
+	current      int // smooth weighted round-robin state
+	ejectedUntil time.Time
+}
+
+// PoolEndpointStatus is a snapshot of a PoolClient endpoint.
+type PoolEndpointStatus struct {
+	BaseURL      string
+	Healthy      bool
+	Outstanding  int       // requests in flight
+	EjectedUntil time.Time // zero while healthy
+}
+
+// NewPoolClient creates a PoolClient over endpoints and starts health probes
+// if opts.HealthCheckInterval is set.
+func NewPoolClient(endpoints []PoolEndpoint, opts PoolOptions) *PoolClient {
+	if opts.HealthCheckPath == "" {
+		opts.HealthCheckPath = "/models"
+	}
+	if opts.HealthCheckTimeout <= 0 {
+		opts.HealthCheckTimeout = 5 * time.Second
+	}
+	if opts.EjectDuration <= 0 {
+		opts.EjectDuration = 30 * time.Second
+	}
+
+	p := &PoolClient{opts: opts, now: time.Now}
+	for _, e := range endpoints {
+		if e.Weight <= 0 {
+			e.Weight = 1
+		}
+		p.endpoints = append(p.endpoints, &poolEndpoint{
+			PoolEndpoint: e,
+			client:       NewClient(e.BaseURL, opts.ClientOptions...),
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	if opts.HealthCheckInterval > 0 {
+		for _, e := range p.endpoints {
+			p.wg.Add(1)
+			go p.probeLoop(ctx, e)
+		}
+	}
+	return p
+}
+
+// Close stops the health probes.
+func (p *PoolClient) Close() {
+	p.cancel()
+	p.wg.Wait()
+}
+
+// Turn sends opts to an endpoint chosen by the pool's strategy. If the
+// connection fails, the endpoint is ejected and the request is retried on
+// another endpoint. ResponseMessageGenerate.Backend is set to the base URL
+// of the endpoint that served the response.
+func (p *PoolClient) Turn(opts RequestOptions) (*ResponseMessageGenerate, error) {
+	return p.TurnContext(context.Background(), opts)
+}
+
+// TurnContext is Turn with a context; cancelling ctx aborts the request.
+func (p *PoolClient) TurnContext(ctx context.Context, opts RequestOptions) (*ResponseMessageGenerate, error) {
+	tried := make(map[*poolEndpoint]bool)
+	var lastErr error
+	for {
+		e := p.pick(tried)
+		if e == nil {
+			if lastErr == nil {
+				lastErr = errors.New("pool has no endpoints")
+			}
+			return nil, lastErr
+		}
+		tried[e] = true
+
+		resp, err := e.client.TurnContext(ctx, opts)
+		p.release(e)
+		if err == nil {
+			resp.Backend = e.BaseURL
+			return resp, nil
+		}
+		if ctx.Err() != nil || !isConnError(err) {
+			return nil, err
+		}
+		p.eject(e)
+		lastErr = err
+	}
+}
+
+// Endpoints returns the state of each endpoint.
+func (p *PoolClient) Endpoints() []PoolEndpointStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	out := make([]PoolEndpointStatus, len(p.endpoints))
+	for i, e := range p.endpoints {
+		out[i] = PoolEndpointStatus{
+			BaseURL:     e.BaseURL,
+			Healthy:     !now.Before(e.ejectedUntil),
+			Outstanding: e.outstanding,
+		}
+		if !out[i].Healthy {
+			out[i].EjectedUntil = e.ejectedUntil
+		}
+	}
+	return out
+}
+
+// pick chooses an endpoint not in tried and counts a request against it.
+// Healthy endpoints are preferred; if every untried endpoint is ejected,
+// they are used anyway rather than failing the request.
+func (p *PoolClient) pick(tried map[*poolEndpoint]bool) *poolEndpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	var healthy, ejected []*poolEndpoint
+	for _, e := range p.endpoints {
+		switch {
+		case tried[e]:
+		case now.Before(e.ejectedUntil):
+			ejected = append(ejected, e)
+		default:
+			healthy = append(healthy, e)
+		}
+	}
+	candidates := healthy
+	if len(candidates) == 0 {
+		candidates = ejected
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	var e *poolEndpoint
+	switch p.opts.Strategy {
+	case LeastOutstanding:
+		start := p.next % len(candidates)
+		for i := range candidates {
+			c := candidates[(start+i)%len(candidates)]
+			if e == nil || c.outstanding < e.outstanding {
+				e = c
+			}
+		}
+		p.next++
+	case Weighted:
+		// Smooth weighted round-robin, as in nginx: every candidate gains
+		// its weight, the richest is picked and pays back the total.
+		total := 0
+		for _, c := range candidates {
+			c.current += c.Weight
+			total += c.Weight
+			if e == nil || c.current > e.current {
+				e = c
+			}
+		}
+		e.current -= total
+	default:
+		e = candidates[p.next%len(candidates)]
+		p.next++
+	}
+
+	e.outstanding++
+	return e
+}
+
+func (p *PoolClient) release(e *poolEndpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e.outstanding--
+}
+
+func (p *PoolClient) eject(e *poolEndpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e.ejectedUntil = p.now().Add(p.opts.EjectDuration)
+}
+
+func (p *PoolClient) setHealthy(e *poolEndpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e.ejectedUntil = time.Time{}
+}
+
+// probeLoop probes e every HealthCheckInterval until ctx is done.
+func (p *PoolClient) probeLoop(ctx context.Context, e *poolEndpoint) {
+	defer p.wg.Done()
+	t := time.NewTicker(p.opts.HealthCheckInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if p.probe(ctx, e) {
+			p.setHealthy(e)
+		} else if ctx.Err() == nil {
+			p.eject(e)
+		}
+	}
+}
+
+// probe reports whether e answers its health check with a status below 500.
+func (p *PoolClient) probe(ctx context.Context, e *poolEndpoint) bool {
+	ctx, cancel := context.WithTimeout(ctx, p.opts.HealthCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.BaseURL+p.opts.HealthCheckPath, nil)
+	if err != nil {
+		return false
+	}
+	e.client.applyHeaders(req)
+	resp, err := e.client.httpClient.Do(req)
+	if err != nil {
+		return false
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.StatusCode < 500
+}
+
+// isConnError reports whether err is a failure to connect to, or a broken
+// connection with, the server, as opposed to an error response or a timeout
+// waiting for the model.
+func isConnError(err error) bool {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) || urlErr.Timeout() {
+		return false
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,186 @@
+package gollama
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// poolServer is an OpenAI-compatible server that counts chat requests. If
+// block is non-nil, chat requests wait on it.
+func poolServer(t *testing.T, hits *atomic.Int32, block chan struct{}) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			return
+		}
+		hits.Add(1)
+		if block != nil {
+			<-block
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+var poolOpts = RequestOptions{Model: "m", Messages: []Message{{Role: "user", Content: "hi"}}}
+
+func TestPoolStrategies(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		strategy PoolStrategy
+		weights  []int
+		turns    int
+		want     []int32
+	}{
+		{"round robin", RoundRobin, []int{1, 1, 1}, 6, []int32{2, 2, 2}},
+		{"weighted", Weighted, []int{3, 1}, 8, []int32{6, 2}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			hits := make([]atomic.Int32, len(tc.weights))
+			var endpoints []PoolEndpoint
+			for i, w := range tc.weights {
+				endpoints = append(endpoints, PoolEndpoint{BaseURL: poolServer(t, &hits[i], nil).URL, Weight: w})
+			}
+			p := NewPoolClient(endpoints, PoolOptions{Strategy: tc.strategy})
+			defer p.Close()
+
+			for i := 0; i < tc.turns; i++ {
+				if _, err := p.Turn(poolOpts); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i := range hits {
+				if got := hits[i].Load(); got != tc.want[i] {
+					t.Errorf("endpoint %d got %d requests, want %d", i, got, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPoolLeastOutstanding(t *testing.T) {
+	var slowHits, fastHits atomic.Int32
+	block := make(chan struct{})
+	slow := poolServer(t, &slowHits, block)
+	fast := poolServer(t, &fastHits, nil)
+
+	p := NewPoolClient([]PoolEndpoint{{BaseURL: slow.URL}, {BaseURL: fast.URL}}, PoolOptions{Strategy: LeastOutstanding})
+	defer p.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, err := p.Turn(poolOpts); err != nil {
+			t.Error(err)
+		}
+	}()
+	for slowHits.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	for i := 0; i < 3; i++ {
+		resp, err := p.Turn(poolOpts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Backend != fast.URL {
+			t.Errorf("turn %d went to %s while the slow endpoint was busy", i, resp.Backend)
+		}
+	}
+	close(block)
+	wg.Wait()
+}
+
+func TestPoolFailover(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	deadURL := dead.URL
+	dead.Close()
+	var hits atomic.Int32
+	live := poolServer(t, &hits, nil)
+
+	p := NewPoolClient([]PoolEndpoint{{BaseURL: deadURL}, {BaseURL: live.URL}}, PoolOptions{})
+	defer p.Close()
+
+	for i := 0; i < 3; i++ {
+		resp, err := p.Turn(poolOpts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Backend != live.URL {
+			t.Errorf("served by %s", resp.Backend)
+		}
+	}
+	if st := p.Endpoints(); st[0].Healthy || !st[1].Healthy {
+		t.Errorf("endpoints = %+v", st)
+	}
+}
+
+func TestPoolHealthProbe(t *testing.T) {
+	var healthy atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" && !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewPoolClient([]PoolEndpoint{{BaseURL: srv.URL}}, PoolOptions{
+		HealthCheckInterval: 5 * time.Millisecond,
+		HealthCheckPath:     "/health",
+	})
+	defer p.Close()
+
+	waitFor := func(want bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for p.Endpoints()[0].Healthy != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("endpoint never became healthy=%v", want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitFor(false)
+	healthy.Store(true)
+	waitFor(true)
+}
+
+// TestPoolHealthProbeOllama checks that the default probe keeps a native
+// Ollama server, which has no /models, in rotation, while a server that is
+// down is ejected.
+func TestPoolHealthProbeOllama(t *testing.T) {
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"models":[]}`)
+	}))
+	defer ollama.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	p := NewPoolClient([]PoolEndpoint{{BaseURL: ollama.URL}, {BaseURL: down.URL}}, PoolOptions{
+		HealthCheckInterval: 5 * time.Millisecond,
+	})
+	defer p.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for p.Endpoints()[1].Healthy {
+		if time.Now().After(deadline) {
+			t.Fatal("unreachable endpoint never ejected")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if st := p.Endpoints()[0]; !st.Healthy {
+		t.Errorf("Ollama endpoint ejected: %+v", st)
+	}
+}
@@ -0,0 +1,422 @@
+package gollama
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimits configures the client-side limits enforced by a RateLimiter.
+// A zero value for any field leaves that dimension unlimited until a provider
+// reports a limit in its response headers.
+type RateLimits struct {
+	RequestsPerMinute     int
+	InputTokensPerMinute  int
+	OutputTokensPerMinute int
+	// TokensPerMinute limits input and output tokens together, as OpenAI's
+	// token limit does.
+	TokensPerMinute int
+}
+
+// RateLimiter throttles requests before they are sent using token buckets for
+// requests, input tokens and output tokens per minute, tracked per model.
+//
+// Input tokens are estimated from the request before sending and reconciled
+// against the returned Usage afterwards. Output tokens cannot be known ahead
+// of time, so they are charged after the response arrives and a request waits
+// while the output bucket is in debt. A combined token limit is charged with
+// both. Bucket capacities follow the limits the provider reports via
+// anthropic-ratelimit-* or x-ratelimit-* headers, and after a response with a
+// Retry-After header no request is sent for that model until it has passed.
+//
+// A RateLimiter is safe for concurrent use and may be shared between clients
+// that draw on the same provider quota.
+type RateLimiter struct {
+	mu       sync.Mutex
+	defaults RateLimits
+	limits   map[string]RateLimits // per-model overrides
+	buckets  map[string]*modelBuckets
+	now      func() time.Time
+}
+
+// modelBuckets holds the buckets for a single model.
+type modelBuckets struct {
+	requests tokenBucket
+	input    tokenBucket
+	output   tokenBucket
+	tokens   tokenBucket // input and output combined
+	retryAt  time.Time   // from Retry-After; zero if not set
+}
+
+// tokenBucket is a per-minute token bucket. A zero capacity means unlimited.
+// Tokens may go negative when actual usage exceeds what was reserved.
+type tokenBucket struct {
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+// rateReservation records what was taken from the buckets for one request so
+// it can be reconciled once the response arrives.
+type rateReservation struct {
+	model    string
+	inputEst int
+}
+
+// NewRateLimiter returns a RateLimiter applying limits to every model.
+func NewRateLimiter(limits RateLimits) *RateLimiter {
+	return &RateLimiter{
+		defaults: limits,
+		limits:   make(map[string]RateLimits),
+		buckets:  make(map[string]*modelBuckets),
+		now:      time.Now,
+	}
+}
+
+// SetModelLimits overrides the limits for a single model.
+func (rl *RateLimiter) SetModelLimits(model string, limits RateLimits) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.limits[model] = limits
+	if b, ok := rl.buckets[model]; ok {
+		b.requests.setCapacity(float64(limits.RequestsPerMinute))
+		b.input.setCapacity(float64(limits.InputTokensPerMinute))
+		b.output.setCapacity(float64(limits.OutputTokensPerMinute))
+		b.tokens.setCapacity(float64(limits.TokensPerMinute))
+	}
+}
+
+// bucketsFor returns the buckets for model, creating them full. Must be called with rl.mu held.
+func (rl *RateLimiter) bucketsFor(model string, now time.Time) *modelBuckets {
+	if b, ok := rl.buckets[model]; ok {
+		return b
+	}
+	limits, ok := rl.limits[model]
+	if !ok {
+		limits = rl.defaults
+	}
+	b := &modelBuckets{
+		requests: newTokenBucket(float64(limits.RequestsPerMinute), now),
+		input:    newTokenBucket(float64(limits.InputTokensPerMinute), now),
+		output:   newTokenBucket(float64(limits.OutputTokensPerMinute), now),
+		tokens:   newTokenBucket(float64(limits.TokensPerMinute), now),
+	}
+	rl.buckets[model] = b
+	return b
+}
+
+// acquire blocks until the request fits within the limits for its model or
+// ctx is done, then reserves one request and the estimated input tokens.
+func (rl *RateLimiter) acquire(ctx context.Context, opts RequestOptions) (*rateReservation, error) {
+	est := EstimateInputTokens(opts)
+	for {
+		rl.mu.Lock()
+		now := rl.now()
+		b := rl.bucketsFor(opts.Model, now)
+		b.requests.refill(now)
+		b.input.refill(now)
+		b.output.refill(now)
+		b.tokens.refill(now)
+
+		wait := max(
+			b.requests.waitFor(1),
+			b.input.waitFor(float64(est)),
+			b.output.waitFor(0),
+			b.tokens.waitFor(float64(est)),
+			b.retryAt.Sub(now),
+		)
+		if wait <= 0 {
+			b.requests.take(1)
+			b.input.take(float64(est))
+			b.tokens.take(float64(est))
+			rl.mu.Unlock()
+			return &rateReservation{model: opts.Model, inputEst: est}, nil
+		}
+		rl.mu.Unlock()
+
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// complete reconciles a reservation with the outcome of the request. On
+// success the input estimate is corrected to the reported usage and the
+// output tokens are charged; on failure the input estimate is refunded. The
+// combined bucket follows both.
+func (rl *RateLimiter) complete(res *rateReservation, resp *ResponseMessageGenerate, err error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	b := rl.bucketsFor(res.model, rl.now())
+	if err != nil || resp == nil {
+		b.input.give(float64(res.inputEst))
+		b.tokens.give(float64(res.inputEst))
+		return
+	}
+	actualIn := resp.Usage.PromptTokens + resp.Usage.CacheCreationInputTokens
+	b.input.give(float64(res.inputEst - actualIn))
+	b.tokens.give(float64(res.inputEst - actualIn))
+	b.output.take(float64(resp.Usage.CompletionTokens))
+	b.tokens.take(float64(resp.Usage.CompletionTokens))
+}
+
+// observe adjusts the buckets for model from provider rate-limit headers:
+// reported limits replace the bucket capacity, and a reported remaining count
+// lowers the available tokens when the provider has seen more usage than we
+// have (e.g. other processes sharing the key). A Retry-After delay holds back
+// further requests for model until it has passed.
+func (rl *RateLimiter) observe(model string, info RateLimitInfo) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	b := rl.bucketsFor(model, now)
+	b.requests.observe(info.Requests, now)
+	b.input.observe(info.InputTokens, now)
+	b.output.observe(info.OutputTokens, now)
+	if info.InputTokens == nil && info.OutputTokens == nil {
+		// OpenAI reports a single combined token limit. Anthropic's combined
+		// window only restates its separate input and output ones.
+		b.tokens.observe(info.Tokens, now)
+	}
+	if info.RetryAfter > 0 {
+		if at := now.Add(info.RetryAfter); at.After(b.retryAt) {
+			b.retryAt = at
+		}
+	}
+}
+
+// rateLimitObserverKey is the context key of the function that doWithRetry
+// reports each attempt's rate-limit headers to.
+type rateLimitObserverKey struct{}
+
+// withRateLimitObserver returns a context under which doWithRetry passes
+// the rate-limit headers of every response it receives to fn.
+func withRateLimitObserver(ctx context.Context, fn func(RateLimitInfo)) context.Context {
+	return context.WithValue(ctx, rateLimitObserverKey{}, fn)
+}
+
+// observeRateLimit reports info to ctx's rate-limit observer, if any.
+func observeRateLimit(ctx context.Context, info RateLimitInfo) {
+	if fn, ok := ctx.Value(rateLimitObserverKey{}).(func(RateLimitInfo)); ok {
+		fn(info)
+	}
+}
+
+func newTokenBucket(capacity float64, now time.Time) tokenBucket {
+	return tokenBucket{capacity: capacity, tokens: capacity, last: now}
+}
+
+// refill adds the tokens accrued since the last refill, up to capacity.
+func (b *tokenBucket) refill(now time.Time) {
+	if b.capacity <= 0 {
+		return
+	}
+	elapsed := now.Sub(b.last)
+	if elapsed > 0 {
+		b.tokens = math.Min(b.capacity, b.tokens+b.capacity*elapsed.Minutes())
+	}
+	b.last = now
+}
+
+// waitFor returns how long until n tokens are available. Requests larger than
+// the capacity wait for a full bucket rather than forever.
+func (b *tokenBucket) waitFor(n float64) time.Duration {
+	if b.capacity <= 0 {
+		return 0
+	}
+	n = math.Min(n, b.capacity)
+	if b.tokens >= n {
+		return 0
+	}
+	missing := n - b.tokens
+	d := time.Duration(missing / b.capacity * float64(time.Minute))
+	return max(d, time.Millisecond)
+}
+
+func (b *tokenBucket) take(n float64) {
+	if b.capacity > 0 {
+		b.tokens -= n
+	}
+}
+
+func (b *tokenBucket) give(n float64) {
+	if b.capacity > 0 {
+		b.tokens = math.Min(b.capacity, b.tokens+n)
+	}
+}
+
+func (b *tokenBucket) setCapacity(capacity float64) {
+	if b.capacity <= 0 && capacity > 0 {
+		b.tokens = capacity
+	}
+	b.capacity = capacity
+	b.tokens = math.Min(b.tokens, math.Max(capacity, 0))
+}
+
+func (b *tokenBucket) observe(w *RateLimitWindow, now time.Time) {
+	if w == nil {
+		return
+	}
+	if w.Limit > 0 && float64(w.Limit) != b.capacity {
+		b.refill(now)
+		b.setCapacity(float64(w.Limit))
+		b.last = now
+	}
+	if b.capacity > 0 && w.Remaining >= 0 && float64(w.Remaining) < b.tokens {
+		b.tokens = float64(w.Remaining)
+	}
+}
+
+// EstimateInputTokens returns a rough estimate of the input tokens a request
+// will consume, used by RateLimiter before the real count is known. It
+// assumes about four characters per token for text, tool definitions and tool
+// call arguments, a fixed cost per image, and scales documents by their
+// encoded size.
+func EstimateInputTokens(opts RequestOptions) int {
+	const (
+		charsPerToken    = 4
+		perMessage       = 4
+		perImage         = 1600
+		docCharsPerToken = 16 // base64 PDF bytes are mostly layout, not text
+	)
+
+	chars := len(opts.System) + len(opts.Prompt)
+	for _, b := range opts.SystemBlocks {
+		chars += len(b.Text)
+	}
+	if len(opts.Tools) > 0 {
+		if data, err := json.Marshal(opts.Tools); err == nil {
+			chars += len(data)
+		}
+	}
+
+	tokens := 0
+	images := len(opts.Images)
+	docChars := 0
+	for _, m := range opts.Messages {
+		tokens += perMessage
+		chars += len(m.Content) + len(m.Thinking) + len(m.ReasoningContent)
+		for _, tb := range m.ThinkingBlocks {
+			chars += len(tb.Thinking)
+		}
+		for _, tc := range m.ToolCalls {
+			chars += len(tc.Function.Name) + len(tc.Function.Arguments)
+		}
+		images += len(m.Images)
+		for _, d := range m.Documents {
+			docChars += len(d.Base64)
+		}
+		for _, block := range m.MultiContent {
+			chars += len(block.Text)
+			if block.ImageURL != "" || block.ImageBase64 != "" {
+				images++
+			}
+			docChars += len(block.DocumentBase64)
+		}
+	}
+
+	tokens += chars/charsPerToken + images*perImage + docChars/docCharsPerToken
+	return tokens
+}
+
+// RateLimitWindow is one rate-limit dimension reported by a provider.
+type RateLimitWindow struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time // zero if not reported
+}
+
+// RateLimitInfo holds the rate-limit state parsed from provider response
+// headers. A nil window means the provider did not report that dimension.
+type RateLimitInfo struct {
+	Requests     *RateLimitWindow
+	InputTokens  *RateLimitWindow
+	OutputTokens *RateLimitWindow
+	// Tokens is a combined input+output limit (OpenAI x-ratelimit-*-tokens,
+	// Anthropic anthropic-ratelimit-tokens-*).
+	Tokens *RateLimitWindow
+	// RetryAfter is the server-requested delay from a Retry-After header.
+	RetryAfter time.Duration
+}
+
+// ParseRateLimitHeaders extracts rate-limit information from Anthropic
+// (anthropic-ratelimit-*) and OpenAI-style (x-ratelimit-*) response headers.
+// Relative reset values are resolved against now.
+func ParseRateLimitHeaders(h http.Header, now time.Time) RateLimitInfo {
+	info := RateLimitInfo{
+		Requests:     parseAnthropicWindow(h, "requests", now),
+		InputTokens:  parseAnthropicWindow(h, "input-tokens", now),
+		OutputTokens: parseAnthropicWindow(h, "output-tokens", now),
+		Tokens:       parseAnthropicWindow(h, "tokens", now),
+	}
+	if info.Requests == nil {
+		info.Requests = parseOpenAIWindow(h, "requests", now)
+	}
+	if info.Tokens == nil {
+		info.Tokens = parseOpenAIWindow(h, "tokens", now)
+	}
+	if ra := h.Get("Retry-After"); ra != "" {
+		if secs, err := strconv.Atoi(ra); err == nil {
+			info.RetryAfter = time.Duration(secs) * time.Second
+		} else if t, err := http.ParseTime(ra); err == nil {
+			info.RetryAfter = t.Sub(now)
+		}
+	}
+	return info
+}
+
+// parseAnthropicWindow reads anthropic-ratelimit-<kind>-{limit,remaining,reset}.
+// Reset is an RFC 3339 timestamp.
+func parseAnthropicWindow(h http.Header, kind string, now time.Time) *RateLimitWindow {
+	prefix := "anthropic-ratelimit-" + kind + "-"
+	w, ok := parseWindowCounts(h.Get(prefix+"limit"), h.Get(prefix+"remaining"))
+	if !ok {
+		return nil
+	}
+	if reset := h.Get(prefix + "reset"); reset != "" {
+		if t, err := time.Parse(time.RFC3339, reset); err == nil {
+			w.Reset = t
+		}
+	}
+	return w
+}
+
+// parseOpenAIWindow reads x-ratelimit-{limit,remaining,reset}-<kind>. Reset is
+// a duration such as "1s", "6m0s" or "20ms".
+func parseOpenAIWindow(h http.Header, kind string, now time.Time) *RateLimitWindow {
+	w, ok := parseWindowCounts(h.Get("x-ratelimit-limit-"+kind), h.Get("x-ratelimit-remaining-"+kind))
+	if !ok {
+		return nil
+	}
+	if reset := h.Get("x-ratelimit-reset-" + kind); reset != "" {
+		if d, err := time.ParseDuration(reset); err == nil {
+			w.Reset = now.Add(d)
+		} else if secs, err := strconv.ParseFloat(strings.TrimSuffix(reset, "s"), 64); err == nil {
+			w.Reset = now.Add(time.Duration(secs * float64(time.Second)))
+		}
+	}
+	return w
+}
+
+// parseWindowCounts parses limit and remaining values. Remaining is -1 when
+// only the limit was reported.
+func parseWindowCounts(limit, remaining string) (*RateLimitWindow, bool) {
+	if limit == "" && remaining == "" {
+		return nil, false
+	}
+	w := &RateLimitWindow{Remaining: -1}
+	if v, err := strconv.Atoi(limit); err == nil {
+		w.Limit = v
+	}
+	if v, err := strconv.Atoi(remaining); err == nil {
+		w.Remaining = v
+	}
+	return w, true
+}
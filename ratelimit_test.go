@@ -0,0 +1,243 @@
+package gollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimitHeaders(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	h := http.Header{}
+	h.Set("anthropic-ratelimit-requests-limit", "50")
+	h.Set("anthropic-ratelimit-requests-remaining", "49")
+	h.Set("anthropic-ratelimit-requests-reset", "2026-01-01T12:00:01Z")
+	h.Set("anthropic-ratelimit-input-tokens-limit", "30000")
+	h.Set("anthropic-ratelimit-input-tokens-remaining", "29000")
+	h.Set("anthropic-ratelimit-output-tokens-limit", "8000")
+	h.Set("anthropic-ratelimit-output-tokens-remaining", "7990")
+	h.Set("Retry-After", "7")
+	info := ParseRateLimitHeaders(h, now)
+	if info.Requests == nil || info.Requests.Limit != 50 || info.Requests.Remaining != 49 || !info.Requests.Reset.Equal(now.Add(time.Second)) {
+		t.Errorf("requests = %+v", info.Requests)
+	}
+	if info.InputTokens == nil || info.InputTokens.Limit != 30000 || info.InputTokens.Remaining != 29000 {
+		t.Errorf("input tokens = %+v", info.InputTokens)
+	}
+	if info.OutputTokens == nil || info.OutputTokens.Remaining != 7990 {
+		t.Errorf("output tokens = %+v", info.OutputTokens)
+	}
+	if info.Tokens != nil {
+		t.Errorf("tokens = %+v, want nil", info.Tokens)
+	}
+	if info.RetryAfter != 7*time.Second {
+		t.Errorf("retry after = %v", info.RetryAfter)
+	}
+
+	h = http.Header{}
+	h.Set("x-ratelimit-limit-requests", "500")
+	h.Set("x-ratelimit-remaining-requests", "499")
+	h.Set("x-ratelimit-reset-requests", "120ms")
+	h.Set("x-ratelimit-limit-tokens", "200000")
+	h.Set("x-ratelimit-remaining-tokens", "150000")
+	h.Set("x-ratelimit-reset-tokens", "6m0s")
+	info = ParseRateLimitHeaders(h, now)
+	if info.Requests == nil || info.Requests.Limit != 500 || !info.Requests.Reset.Equal(now.Add(120*time.Millisecond)) {
+		t.Errorf("openai requests = %+v", info.Requests)
+	}
+	if info.Tokens == nil || info.Tokens.Remaining != 150000 || !info.Tokens.Reset.Equal(now.Add(6*time.Minute)) {
+		t.Errorf("openai tokens = %+v", info.Tokens)
+	}
+}
+
+func TestRateLimiterBuckets(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	rl := NewRateLimiter(RateLimits{RequestsPerMinute: 2, InputTokensPerMinute: 1000, OutputTokensPerMinute: 100})
+	rl.now = func() time.Time { return now }
+
+	opts := RequestOptions{Model: "m", Messages: []Message{{Role: "user", Content: "hello there"}}}
+	est := EstimateInputTokens(opts)
+
+	expired := func() context.Context {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		t.Cleanup(cancel)
+		return ctx
+	}
+
+	r1, err := rl.acquire(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rl.acquire(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rl.acquire(expired(), opts); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("third request within a minute: err = %v, want deadline exceeded", err)
+	}
+
+	// Half a minute refills one request.
+	now = now.Add(30 * time.Second)
+	if _, err := rl.acquire(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reconciliation: actual input replaces the estimate, output is charged
+	// and the next request waits while the output bucket is in debt.
+	b := rl.buckets["m"]
+	before := b.input.tokens
+	rl.complete(r1, &ResponseMessageGenerate{Usage: Usage{PromptTokens: est + 100, CompletionTokens: 150}}, nil)
+	if got := before - b.input.tokens; got != 100 {
+		t.Errorf("input reconciliation charged %v extra tokens, want 100", got)
+	}
+	if b.output.tokens != -50 {
+		t.Errorf("output tokens = %v, want -50", b.output.tokens)
+	}
+	now = now.Add(time.Minute)
+	if _, err := rl.acquire(expired(), opts); err != nil {
+		t.Fatalf("after refill: %v", err)
+	}
+
+	// A failed request refunds its input estimate.
+	r, _ := rl.acquire(context.Background(), RequestOptions{Model: "other", Messages: opts.Messages})
+	rl.complete(r, nil, errors.New("boom"))
+	if rl.buckets["other"].input.tokens != 1000 {
+		t.Errorf("input after refund = %v, want 1000", rl.buckets["other"].input.tokens)
+	}
+}
+
+func TestRateLimiterCombinedTokens(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	rl := NewRateLimiter(RateLimits{})
+	rl.now = func() time.Time { return now }
+	expired := func() context.Context {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		t.Cleanup(cancel)
+		return ctx
+	}
+
+	// An OpenAI-style combined limit is charged with input and output.
+	rl.observe("gpt", RateLimitInfo{Tokens: &RateLimitWindow{Limit: 1000, Remaining: 1000}})
+	opts := RequestOptions{Model: "gpt", Messages: []Message{{Role: "user", Content: "hello there"}}}
+	est := EstimateInputTokens(opts)
+	r, err := rl.acquire(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl.complete(r, &ResponseMessageGenerate{Usage: Usage{PromptTokens: est, CompletionTokens: 1200}}, nil)
+	b := rl.buckets["gpt"]
+	if want := float64(1000 - est - 1200); b.tokens.tokens != want {
+		t.Errorf("combined tokens = %v, want %v", b.tokens.tokens, want)
+	}
+	if b.input.capacity != 0 || b.output.capacity != 0 {
+		t.Errorf("per-direction buckets should stay unlimited: %+v", b)
+	}
+	if _, err := rl.acquire(expired(), opts); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("acquire while combined bucket is in debt: err = %v", err)
+	}
+	now = now.Add(time.Minute)
+	if _, err := rl.acquire(expired(), opts); err != nil {
+		t.Fatalf("after refill: %v", err)
+	}
+
+	// Anthropic's combined window does not add a third bucket.
+	rl.observe("claude", RateLimitInfo{
+		InputTokens: &RateLimitWindow{Limit: 100, Remaining: 100},
+		Tokens:      &RateLimitWindow{Limit: 100, Remaining: 100},
+	})
+	if c := rl.buckets["claude"].tokens.capacity; c != 0 {
+		t.Errorf("claude combined capacity = %v, want 0", c)
+	}
+}
+
+func TestRateLimiterRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	rl := NewRateLimiter(RateLimits{})
+	rl.now = func() time.Time { return now }
+	expired := func() context.Context {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		t.Cleanup(cancel)
+		return ctx
+	}
+	opts := RequestOptions{Model: "m"}
+
+	rl.observe("m", RateLimitInfo{RetryAfter: 30 * time.Second})
+	if _, err := rl.acquire(expired(), opts); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("acquire during Retry-After: err = %v", err)
+	}
+	if _, err := rl.acquire(expired(), RequestOptions{Model: "other"}); err != nil {
+		t.Fatalf("other model held back: %v", err)
+	}
+	now = now.Add(30 * time.Second)
+	if _, err := rl.acquire(expired(), opts); err != nil {
+		t.Fatalf("after Retry-After: %v", err)
+	}
+}
+
+// TestRateLimiterHeaders checks that the limiter adopts the limits reported
+// in response headers by a client it is attached to.
+func TestRateLimiterHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("anthropic-ratelimit-requests-limit", "60")
+		w.Header().Set("anthropic-ratelimit-requests-remaining", "10")
+		w.Header().Set("anthropic-ratelimit-input-tokens-limit", "40000")
+		w.Header().Set("anthropic-ratelimit-input-tokens-remaining", "39000")
+		fmt.Fprint(w, `{"role":"assistant","model":"claude","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":20,"output_tokens":5}}`)
+	}))
+	defer srv.Close()
+
+	rl := NewRateLimiter(RateLimits{})
+	c := NewClient(srv.URL)
+	c.SetAnthropicMode(true)
+	c.SetRateLimiter(rl)
+	if _, err := c.Turn(RequestOptions{Model: "claude", Messages: []Message{{Role: "user", Content: "hi"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	b := rl.buckets["claude"]
+	if b.requests.capacity != 60 || b.input.capacity != 40000 {
+		t.Fatalf("capacities = %v/%v, want 60/40000", b.requests.capacity, b.input.capacity)
+	}
+	if b.requests.tokens > 10 {
+		t.Errorf("requests available = %v, want <= 10 remaining", b.requests.tokens)
+	}
+	if b.output.capacity != 0 {
+		t.Errorf("output bucket should stay unlimited, capacity = %v", b.output.capacity)
+	}
+}
+
+// TestRateLimiterRetries checks that a retried 429 waits for its Retry-After
+// delay rather than the backoff, and that the limiter sees the headers of
+// every attempt, not just the last.
+func TestRateLimiterRetries(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("anthropic-ratelimit-requests-limit", "50")
+			w.Header().Set("retry-after", "1")
+			http.Error(w, `{"type":"error","error":{"type":"rate_limit_error"}}`, http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{"role":"assistant","model":"claude","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":20,"output_tokens":5}}`)
+	}))
+	defer srv.Close()
+
+	rl := NewRateLimiter(RateLimits{})
+	c := NewClient(srv.URL, WithAnthropicMode(true), WithRateLimiter(rl), WithRetryPolicy(1, time.Hour))
+	resp, err := c.Turn(RequestOptions{Model: "claude", Messages: []Message{{Role: "user", Content: "hi"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Meta.Retries != 1 || resp.Meta.RetryWait != time.Second {
+		t.Errorf("retries = %d, wait = %v", resp.Meta.Retries, resp.Meta.RetryWait)
+	}
+	b := rl.buckets["claude"]
+	if b.requests.capacity != 50 || b.retryAt.IsZero() {
+		t.Errorf("requests capacity = %v, retryAt = %v", b.requests.capacity, b.retryAt)
+	}
+}
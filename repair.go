@@ -0,0 +1,311 @@
+package gollama
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Fixes reported by RepairJSON.
+const (
+	RepairCodeFence       = "removed markdown code fence"
+	RepairSurroundingText = "removed text around JSON"
+	RepairEmpty           = "replaced empty input with {}"
+	RepairSingleQuotes    = "replaced single quotes with double quotes"
+	RepairTrailingComma   = "removed trailing comma"
+	RepairComments        = "removed comments"
+	RepairBareKeys        = "quoted bare keys"
+	RepairBareWords       = "quoted bare words"
+	RepairPythonLiterals  = "replaced Python literals"
+	RepairControlChars    = "escaped control characters in strings"
+	RepairTruncated       = "completed truncated JSON"
+)
+
+// ErrUnrepairableJSON is returned by RepairJSON when its input cannot be
+// turned into valid JSON.
+var ErrUnrepairableJSON = errors.New("could not repair JSON")
+
+// RepairJSON turns the almost-JSON that models sometimes produce for tool
+// arguments into valid JSON. It strips markdown code fences and surrounding
+// prose, converts single-quoted strings, drops trailing commas and comments,
+// quotes bare keys, maps Python's True/False/None, escapes raw control
+// characters in strings and closes JSON cut off mid-way (dropping an
+// incomplete last member). It returns the repaired JSON and the fixes it
+// applied, which are empty if s was already valid.
+func RepairJSON(s string) (string, []string, error) {
+	trimmed := strings.TrimSpace(s)
+	if json.Valid([]byte(trimmed)) {
+		return trimmed, nil, nil
+	}
+
+	r := &jsonRepairer{}
+	trimmed = r.stripWrapping(trimmed)
+	if trimmed == "" {
+		r.fix(RepairEmpty)
+		return "{}", r.fixes, nil
+	}
+	r.in = trimmed
+	r.scan()
+
+	out, ok := r.finish()
+	if !ok {
+		return "", nil, ErrUnrepairableJSON
+	}
+	return out, r.fixes, nil
+}
+
+type repairFrame struct {
+	closer byte
+	// cut is the output length to truncate to when dropping the frame's
+	// incomplete last member: just past the opener or at the last comma.
+	cut int
+}
+
+type jsonRepairer struct {
+	in    string
+	pos   int
+	out   strings.Builder
+	stack []repairFrame
+	fixes []string
+	// open is set when the input ended inside a string.
+	open bool
+}
+
+func (r *jsonRepairer) fix(f string) {
+	for _, have := range r.fixes {
+		if have == f {
+			return
+		}
+	}
+	r.fixes = append(r.fixes, f)
+}
+
+// stripWrapping removes a markdown code fence and any text before the first
+// '{' or '['.
+func (r *jsonRepairer) stripWrapping(s string) string {
+	if strings.HasPrefix(s, "```") {
+		r.fix(RepairCodeFence)
+		if nl := strings.IndexByte(s, '\n'); nl >= 0 {
+			s = s[nl+1:]
+		} else {
+			s = strings.TrimLeft(s[3:], "abcdefghijklmnopqrstuvwxyz")
+		}
+		if end := strings.LastIndex(s, "```"); end >= 0 {
+			s = s[:end]
+		}
+		s = strings.TrimSpace(s)
+	}
+	if s != "" && s[0] != '{' && s[0] != '[' {
+		if i := strings.IndexAny(s, "{["); i >= 0 {
+			r.fix(RepairSurroundingText)
+			s = s[i:]
+		}
+	}
+	return s
+}
+
+func (r *jsonRepairer) peekNonSpace() (byte, bool) {
+	for i := r.pos; i < len(r.in); i++ {
+		switch c := r.in[i]; c {
+		case ' ', '\t', '\n', '\r':
+		default:
+			return c, true
+		}
+	}
+	return 0, false
+}
+
+func (r *jsonRepairer) scan() {
+	for r.pos < len(r.in) {
+		c := r.in[r.pos]
+		switch {
+		case c == '"' || c == '\'':
+			if c == '\'' {
+				r.fix(RepairSingleQuotes)
+			}
+			r.pos++
+			r.scanString(c)
+		case c == '{' || c == '[':
+			r.out.WriteByte(c)
+			r.pos++
+			closer := byte('}')
+			if c == '[' {
+				closer = ']'
+			}
+			r.stack = append(r.stack, repairFrame{closer: closer, cut: r.out.Len()})
+		case c == '}' || c == ']':
+			r.pos++
+			if len(r.stack) == 0 {
+				continue
+			}
+			top := r.stack[len(r.stack)-1]
+			r.stack = r.stack[:len(r.stack)-1]
+			r.out.WriteByte(top.closer)
+			if len(r.stack) == 0 {
+				if rest := strings.TrimSpace(r.in[r.pos:]); rest != "" {
+					r.fix(RepairSurroundingText)
+				}
+				r.pos = len(r.in)
+			}
+		case c == ',':
+			r.pos++
+			if next, ok := r.peekNonSpace(); ok && (next == '}' || next == ']') {
+				r.fix(RepairTrailingComma)
+				continue
+			}
+			if len(r.stack) > 0 {
+				r.stack[len(r.stack)-1].cut = r.out.Len()
+			}
+			r.out.WriteByte(',')
+		case c == '/' && r.pos+1 < len(r.in) && (r.in[r.pos+1] == '/' || r.in[r.pos+1] == '*'):
+			r.fix(RepairComments)
+			if r.in[r.pos+1] == '/' {
+				if nl := strings.IndexByte(r.in[r.pos:], '\n'); nl >= 0 {
+					r.pos += nl
+				} else {
+					r.pos = len(r.in)
+				}
+			} else if end := strings.Index(r.in[r.pos+2:], "*/"); end >= 0 {
+				r.pos += end + 4
+			} else {
+				r.pos = len(r.in)
+			}
+		case (c >= '0' && c <= '9') || c == '-':
+			// Numbers are copied whole so an exponent is not taken for a word.
+			start := r.pos
+			for r.pos < len(r.in) && isWordByte(r.in[r.pos]) {
+				r.pos++
+			}
+			r.out.WriteString(r.in[start:r.pos])
+		case isWordByte(c) && c != '.' && c != '+':
+			r.scanWord()
+		default:
+			r.out.WriteByte(c)
+			r.pos++
+		}
+	}
+}
+
+// scanString copies a string literal opened by quote, re-quoting it with
+// double quotes.
+func (r *jsonRepairer) scanString(quote byte) {
+	r.out.WriteByte('"')
+	for r.pos < len(r.in) {
+		c := r.in[r.pos]
+		r.pos++
+		switch {
+		case c == quote:
+			r.out.WriteByte('"')
+			return
+		case c == '\\':
+			if r.pos >= len(r.in) {
+				continue
+			}
+			next := r.in[r.pos]
+			r.pos++
+			if next == '\'' {
+				r.out.WriteByte('\'')
+			} else {
+				r.out.WriteByte('\\')
+				r.out.WriteByte(next)
+			}
+		case c == '"':
+			r.out.WriteString(`\"`)
+		case c == '\n':
+			r.fix(RepairControlChars)
+			r.out.WriteString(`\n`)
+		case c == '\r':
+			r.fix(RepairControlChars)
+			r.out.WriteString(`\r`)
+		case c == '\t':
+			r.fix(RepairControlChars)
+			r.out.WriteString(`\t`)
+		default:
+			r.out.WriteByte(c)
+		}
+	}
+	r.open = true
+}
+
+// scanWord handles an unquoted identifier: a JSON or Python literal, a bare
+// object key, or a bare string value.
+func (r *jsonRepairer) scanWord() {
+	start := r.pos
+	for r.pos < len(r.in) && isWordByte(r.in[r.pos]) {
+		r.pos++
+	}
+	word := r.in[start:r.pos]
+	switch word {
+	case "true", "false", "null":
+		r.out.WriteString(word)
+		return
+	case "True", "False", "None":
+		r.fix(RepairPythonLiterals)
+		r.out.WriteString(map[string]string{"True": "true", "False": "false", "None": "null"}[word])
+		return
+	}
+	if next, ok := r.peekNonSpace(); ok && next == ':' {
+		r.fix(RepairBareKeys)
+	} else if !ok && r.atPartialLiteral(word) {
+		// A literal cut off by truncation, e.g. "tru"; finish drops it.
+		return
+	} else {
+		r.fix(RepairBareWords)
+	}
+	b, _ := json.Marshal(word)
+	r.out.Write(b)
+}
+
+// atPartialLiteral reports whether word, found at the end of the input, is
+// the beginning of a JSON literal.
+func (r *jsonRepairer) atPartialLiteral(word string) bool {
+	for _, lit := range []string{"true", "false", "null"} {
+		if strings.HasPrefix(lit, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '-' || c == '.' || c == '+' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// finish closes any containers left open by truncated input and returns the
+// result if it is valid JSON.
+func (r *jsonRepairer) finish() (string, bool) {
+	out := r.out.String()
+	if r.open {
+		out += `"`
+	}
+	if len(r.stack) == 0 && !r.open {
+		return out, json.Valid([]byte(out))
+	}
+	r.fix(RepairTruncated)
+
+	closers := func(stack []repairFrame) string {
+		var sb strings.Builder
+		for i := len(stack) - 1; i >= 0; i-- {
+			sb.WriteByte(stack[i].closer)
+		}
+		return sb.String()
+	}
+
+	// Try closing as is, then completing a dangling key, then dropping the
+	// innermost incomplete member, moving outwards until something parses.
+	body := strings.TrimRight(out, " \t\r\n")
+	candidates := []string{body + closers(r.stack)}
+	if strings.HasSuffix(body, ":") {
+		candidates = append(candidates, body+"null"+closers(r.stack))
+	}
+	for i := len(r.stack) - 1; i >= 0; i-- {
+		candidates = append(candidates, out[:r.stack[i].cut]+closers(r.stack[:i+1]))
+	}
+	for _, c := range candidates {
+		if json.Valid([]byte(c)) {
+			return c, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,75 @@
+package gollama
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRepairJSON(t *testing.T) {
+	cases := []struct {
+		name  string
+		in    string
+		want  string
+		fixes []string
+	}{
+		{"valid", ` {"a": 1} `, `{"a": 1}`, nil},
+		{"fence", "```json\n{\"a\": 1}\n```", `{"a": 1}`, []string{RepairCodeFence}},
+		{"prose", `Sure! Here you go: {"a": 1} hope that helps`, `{"a": 1}`, []string{RepairSurroundingText}},
+		{"trailing comma", `{"a": [1, 2,], "b": 3,}`, `{"a": [1, 2], "b": 3}`, []string{RepairTrailingComma}},
+		{"single quotes", `{'a': 'it\'s "x"'}`, `{"a": "it's \"x\""}`, []string{RepairSingleQuotes}},
+		{"bare keys and python", `{a: True, b: None, c: 1e5}`, `{"a": true, "b": null, "c": 1e5}`, []string{RepairBareKeys, RepairPythonLiterals}},
+		{"comments", "{\"a\": 1 // one\n, /* two */ \"b\": 2}", "{\"a\": 1 \n,  \"b\": 2}", []string{RepairComments}},
+		{"control chars", "{\"a\": \"line\nbreak\"}", `{"a": "line\nbreak"}`, []string{RepairControlChars}},
+		{"empty", "  ", `{}`, []string{RepairEmpty}},
+		{"truncated string", `{"path": "/tmp/fo`, `{"path": "/tmp/fo"}`, []string{RepairTruncated}},
+		{"truncated nested", `{"a": [1, {"b": 2`, `{"a": [1, {"b": 2}]}`, []string{RepairTruncated}},
+		{"truncated key", `{"a": 1, "b`, `{"a": 1}`, []string{RepairTruncated}},
+		{"truncated colon", `{"a": 1, "b":`, `{"a": 1, "b":null}`, []string{RepairTruncated}},
+		{"truncated literal", `{"a": [true, fal`, `{"a": [true]}`, []string{RepairTruncated}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, fixes, err := RepairJSON(c.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.want {
+				t.Errorf("RepairJSON(%q) = %s, want %s", c.in, got, c.want)
+			}
+			if !reflect.DeepEqual(fixes, c.fixes) {
+				t.Errorf("fixes = %q, want %q", fixes, c.fixes)
+			}
+		})
+	}
+
+	if _, _, err := RepairJSON("not json at all"); !errors.Is(err, ErrUnrepairableJSON) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestRepairToolArguments(t *testing.T) {
+	call := agentToolCall("1", "echo", "```json\n{'s': 'hi',}\n```")
+	res, err := HandleToolCall(context.Background(), []*Tool{echoTool}, call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Content != "echo hi" || len(res.Repairs) != 3 {
+		t.Errorf("result = %+v", res)
+	}
+
+	// Replayed history is repaired rather than rejected.
+	req, err := buildAnthropicRequest(RequestOptions{Messages: []Message{
+		{Role: "user", Content: "go"},
+		{Role: "assistant", ToolCalls: []ToolCall{call}},
+		{Role: "tool", ToolCallID: "1", Content: "echo hi"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := req.Messages[1].Content[0].(anthropicToolUseBlock)
+	if !reflect.DeepEqual(block.Input, map[string]any{"s": "hi"}) || len(req.repairs) != 1 || req.repairs[0].ID != "1" {
+		t.Errorf("tool_use input = %#v, repairs = %+v", block.Input, req.repairs)
+	}
+}
@@ -0,0 +1,176 @@
+package gollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SubAgent describes an agent that NewSubAgentTool exposes as a tool, so an
+// orchestrating model can delegate tasks to it.
+type SubAgent struct {
+	// Name and Description are the tool's; the description should tell the
+	// orchestrator what the sub-agent is good at.
+	Name        string
+	Description string
+
+	// Turner takes the sub-agent's turns, typically its own *Client.
+	Turner Turner
+	// Model and System select the sub-agent's model and system prompt.
+	Model  string
+	System string
+	// Request holds any other request options, such as Options or Thinking.
+	// Model and System, if set, override its fields; its Messages are sent
+	// before the task.
+	Request RequestOptions
+
+	// Tools are the sub-agent's own tools.
+	Tools []*Tool
+	// Options configures the sub-agent's loop; see RunAgent.
+	Options AgentOptions
+
+	// Output, if set, is a Go value whose type describes a structured
+	// result. The sub-agent is asked to end with a JSON object of that
+	// shape, derived as for NewTypedTool, which is decoded into a new value
+	// of the type and returned in ToolResult.Structured. If its answer does
+	// not match, it is asked once to correct it.
+	Output any
+
+	// OnResult, if set, is called with the outcome of every run, e.g. to
+	// keep the sub-agent's transcript.
+	OnResult func(ctx context.Context, task string, res *AgentResult)
+}
+
+type subAgentArgs struct {
+	Task string `json:"task" description:"The task to carry out, with all the context needed to do it"`
+}
+
+// NewSubAgentTool returns a tool that runs sa on the task the model passes
+// it. Each call is a fresh RunAgent loop with sa's own Turner, model, system
+// prompt and tools; the sub-agent does not see the caller's conversation.
+//
+// The tool's result is the sub-agent's final answer, and its Usage is the
+// token usage of the whole inner run, which RunAgent adds to the caller's
+// AgentResult.ToolUsage. A failed run is reported to the model as an IsError
+// result rather than ending the caller's run.
+//
+// NewSubAgentTool panics if sa has no Name or Turner, or if no schema can be
+// derived from sa.Output.
+func NewSubAgentTool(sa SubAgent) *Tool {
+	if sa.Name == "" || sa.Turner == nil {
+		panic("gollama: NewSubAgentTool: Name and Turner are required")
+	}
+
+	req := sa.Request
+	if sa.Model != "" {
+		req.Model = sa.Model
+	}
+	if sa.System != "" {
+		req.System = sa.System
+	}
+
+	var outType reflect.Type
+	var outSchema map[string]any
+	if sa.Output != nil {
+		outType = reflect.TypeOf(sa.Output)
+		schema, err := schemaForType(outType)
+		if err != nil {
+			panic(fmt.Sprintf("gollama: NewSubAgentTool %q: %v", sa.Name, err))
+		}
+		b, err := json.Marshal(schema)
+		if err != nil {
+			panic(fmt.Sprintf("gollama: NewSubAgentTool %q: %v", sa.Name, err))
+		}
+		if outSchema, err = normalizeSchema(schema); err != nil {
+			panic(fmt.Sprintf("gollama: NewSubAgentTool %q: %v", sa.Name, err))
+		}
+		instr := "When you have finished, reply with only a JSON object, and no other text, matching this JSON schema:\n" + string(b)
+		if len(req.SystemBlocks) > 0 {
+			req.SystemBlocks = append(append([]SystemBlock(nil), req.SystemBlocks...), SystemBlock{Text: instr})
+		} else if req.System != "" {
+			req.System += "\n\n" + instr
+		} else {
+			req.System = instr
+		}
+	}
+
+	tool := NewTypedTool(sa.Name, sa.Description, func(ctx context.Context, args subAgentArgs) (*ToolResult, error) {
+		run := req
+		run.Messages = append(append([]Message(nil), req.Messages...), Message{Role: "user", Content: args.Task})
+
+		res, err := RunAgent(ctx, sa.Turner, run, sa.Tools, sa.Options)
+		usage := res.Usage
+		usage.Add(res.ToolUsage)
+
+		var out any
+		if err == nil && outType != nil {
+			var problem string
+			out, problem = decodeSubAgentOutput(res.Text(), outType, outSchema)
+			if problem != "" {
+				// Give the sub-agent one chance to fix its answer.
+				retry := run
+				retry.Messages = append(res.Messages, Message{Role: "user", Content: problem + "\nReply again with only the corrected JSON object."})
+				opts := sa.Options
+				opts.MaxIterations = 1
+				res, err = RunAgent(ctx, sa.Turner, retry, sa.Tools, opts)
+				usage.Add(res.Usage)
+				usage.Add(res.ToolUsage)
+				if err == nil {
+					if out, problem = decodeSubAgentOutput(res.Text(), outType, outSchema); problem != "" {
+						err = fmt.Errorf("%s", problem)
+					}
+				}
+			}
+		}
+		if sa.OnResult != nil {
+			sa.OnResult(ctx, args.Task, res)
+		}
+
+		if err != nil {
+			content := fmt.Sprintf("sub-agent %q failed: %s", sa.Name, err)
+			if text := res.Text(); text != "" {
+				content += "\n\nIts last response was:\n" + text
+			}
+			return &ToolResult{Content: content, IsError: true, Usage: &usage}, nil
+		}
+		if out != nil {
+			b, err := json.Marshal(out)
+			if err != nil {
+				return nil, fmt.Errorf("error encoding sub-agent result: %w", err)
+			}
+			return &ToolResult{Content: string(b), Structured: out, Usage: &usage}, nil
+		}
+		return &ToolResult{Content: res.Text(), Usage: &usage}, nil
+	})
+	tool.OutputType = sa.Output
+	return tool
+}
+
+// decodeSubAgentOutput decodes the JSON object in text into a new value of
+// type t after checking it against schema. On failure it returns a
+// description of the problem for the sub-agent.
+func decodeSubAgentOutput(text string, t reflect.Type, schema map[string]any) (any, string) {
+	fixed, _, err := RepairJSON(text)
+	if err != nil {
+		return nil, "Your answer did not contain a JSON result."
+	}
+	var generic any
+	if err := json.Unmarshal([]byte(fixed), &generic); err != nil {
+		return nil, fmt.Sprintf("Your JSON result could not be parsed: %s.", err)
+	}
+	if argErr := validateArgs("", schema, generic); argErr != nil {
+		var sb strings.Builder
+		sb.WriteString("Your JSON result does not match the schema:")
+		for _, p := range argErr.Problems {
+			fmt.Fprintf(&sb, "\n- %s: %s", p.Path, p.Message)
+		}
+		return nil, sb.String()
+	}
+	v := reflect.New(t)
+	if err := json.Unmarshal([]byte(fixed), v.Interface()); err != nil {
+		return nil, fmt.Sprintf("Your JSON result could not be decoded: %s.", err)
+	}
+	return v.Elem().Interface(), ""
+}
@@ -0,0 +1,130 @@
+package gollama
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSubAgentTool(t *testing.T) {
+	inner := &scriptedTurner{next: func(turn int, opts RequestOptions) *ResponseMessageGenerate {
+		if turn == 0 {
+			return agentResponse("", "tool_use", agentToolCall("e", "echo", `{"s":"x"}`))
+		}
+		return agentResponse("research done", "end_turn")
+	}}
+	var transcript []Message
+	researcher := NewSubAgentTool(SubAgent{
+		Name:        "researcher",
+		Description: "Researches things.",
+		Turner:      inner,
+		Model:       "small-model",
+		System:      "You research.",
+		Tools:       []*Tool{echoTool},
+		OnResult: func(ctx context.Context, task string, res *AgentResult) {
+			transcript = res.Messages
+		},
+	})
+
+	outer := &scriptedTurner{next: func(turn int, opts RequestOptions) *ResponseMessageGenerate {
+		if turn == 0 {
+			return agentResponse("", "tool_use", agentToolCall("r", "researcher", `{"task":"find x"}`))
+		}
+		return agentResponse("final", "end_turn")
+	}}
+	res, err := RunAgent(context.Background(), outer,
+		RequestOptions{Model: "big-model", Messages: []Message{{Role: "user", Content: "go"}}},
+		[]*Tool{researcher}, AgentOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(inner.calls) != 2 {
+		t.Fatalf("inner turns = %d", len(inner.calls))
+	}
+	first := inner.calls[0]
+	if first.Model != "small-model" || first.System != "You research." || len(first.Tools) != 1 || first.Tools[0].Function.Name != "echo" {
+		t.Errorf("inner request = %+v", first)
+	}
+	if len(first.Messages) != 1 || first.Messages[0].Content != "find x" {
+		t.Errorf("inner messages = %+v", first.Messages)
+	}
+	if got := res.Messages[2].Content; got != "research done" {
+		t.Errorf("tool result = %q", got)
+	}
+	if res.Usage.PromptTokens != 20 || res.ToolUsage.PromptTokens != 20 || res.ToolUsage.TotalTokens != 24 {
+		t.Errorf("usage = %+v, tool usage = %+v", res.Usage, res.ToolUsage)
+	}
+	if len(transcript) != 4 {
+		t.Errorf("transcript = %+v", transcript)
+	}
+}
+
+func TestSubAgentToolStructured(t *testing.T) {
+	type finding struct {
+		Title string   `json:"title"`
+		Score int      `json:"score" min:"0" max:"10"`
+		Tags  []string `json:"tags,omitempty"`
+	}
+	answers := []string{
+		"Here you go: {\"title\": \"x\", \"score\": 11}",
+		"```json\n{\"title\": \"x\", \"score\": 7}\n```",
+	}
+	inner := &scriptedTurner{next: func(turn int, opts RequestOptions) *ResponseMessageGenerate {
+		return agentResponse(answers[turn], "end_turn")
+	}}
+	tool := NewSubAgentTool(SubAgent{Name: "rate", Turner: inner, Output: finding{}})
+	if _, ok := tool.OutputType.(finding); !ok {
+		t.Errorf("OutputType = %T", tool.OutputType)
+	}
+
+	res, err := HandleToolCall(context.Background(), []*Tool{tool}, agentToolCall("a", "rate", `{"task":"rate x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.IsError {
+		t.Fatalf("error result: %s", res.Content)
+	}
+	if got, ok := res.Structured.(finding); !ok || got.Title != "x" || got.Score != 7 {
+		t.Errorf("structured = %#v", res.Structured)
+	}
+	if res.Content != `{"title":"x","score":7}` {
+		t.Errorf("content = %q", res.Content)
+	}
+	if res.Usage == nil || res.Usage.PromptTokens != 20 {
+		t.Errorf("usage = %+v", res.Usage)
+	}
+
+	if len(inner.calls) != 2 {
+		t.Fatalf("inner turns = %d", len(inner.calls))
+	}
+	if !strings.Contains(inner.calls[0].System, `"score":{"maximum":10`) {
+		t.Errorf("system prompt = %q", inner.calls[0].System)
+	}
+	msgs := inner.calls[1].Messages
+	if last := msgs[len(msgs)-1].Content; !strings.Contains(last, "$.score") {
+		t.Errorf("correction = %q", last)
+	}
+}
+
+func TestSubAgentToolFailure(t *testing.T) {
+	inner := &scriptedTurner{next: func(turn int, opts RequestOptions) *ResponseMessageGenerate {
+		return agentResponse("still working", "tool_use", agentToolCall("e", "echo", `{"s":"x"}`))
+	}}
+	tool := NewSubAgentTool(SubAgent{
+		Name:    "worker",
+		Turner:  inner,
+		Tools:   []*Tool{echoTool},
+		Options: AgentOptions{MaxIterations: 2},
+	})
+	res, err := HandleToolCall(context.Background(), []*Tool{tool}, agentToolCall("a", "worker", `{"task":"t"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.IsError || !strings.Contains(res.Content, "max iterations") || !strings.Contains(res.Content, "still working") {
+		t.Errorf("result = %+v", res)
+	}
+	if res.Usage == nil || res.Usage.PromptTokens != 20 {
+		t.Errorf("usage = %+v", res.Usage)
+	}
+}
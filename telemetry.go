@@ -0,0 +1,287 @@
+package gollama
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Tracer starts spans for the calls a Client makes. It is a small subset of
+// OpenTelemetry's trace.Tracer; the gollamaotel module adapts an
+// OpenTelemetry TracerProvider to it. The returned context must carry the
+// span so that spans started from it become its children.
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attr) (context.Context, Span)
+}
+
+// Span is an in-progress span started by a Tracer.
+type Span interface {
+	SetAttributes(attrs ...Attr)
+	RecordError(err error)
+	End()
+}
+
+// Meter records the client's metrics (see the Metric* constants).
+// Implementations create instruments by name on first use.
+type Meter interface {
+	// Add adds value to a counter.
+	Add(ctx context.Context, counter string, value int64, attrs ...Attr)
+	// Record records value in a histogram.
+	Record(ctx context.Context, histogram string, value float64, attrs ...Attr)
+}
+
+// Attr is a span or metric attribute. Value is a string, bool, int, int64,
+// float64 or []string.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// Metric names recorded through a Meter. The histograms follow the
+// OpenTelemetry GenAI semantic conventions.
+const (
+	// MetricOperationDuration is a histogram of call latency in seconds.
+	MetricOperationDuration = "gen_ai.client.operation.duration"
+	// MetricTokenUsage is a histogram of tokens per call, split by the
+	// gen_ai.token.type attribute ("input" or "output").
+	MetricTokenUsage = "gen_ai.client.token.usage"
+	// MetricRequests counts calls; failed calls carry an error.type attribute.
+	MetricRequests = "gollama.client.requests"
+	// MetricTokens counts tokens, split by gen_ai.token.type ("input",
+	// "output", "cache_read" or "cache_creation").
+	MetricTokens = "gollama.client.tokens"
+	// MetricRetries counts HTTP attempts retried after a 429, 503 or 529.
+	MetricRetries = "gollama.client.retries"
+)
+
+// WithTracer makes the client report a span for every call, with a child span
+// for each HTTP attempt. Chat spans carry GenAI semantic-convention
+// attributes: provider, model, token usage and finish reason.
+func WithTracer(t Tracer) Option {
+	return func(c *Client) {
+		c.tracer = t
+	}
+}
+
+// WithMeter makes the client record latency, token and retry metrics.
+func WithMeter(m Meter) Option {
+	return func(c *Client) {
+		c.meter = m
+	}
+}
+
+// genAIOperations maps call operations to GenAI semantic-convention
+// operation names.
+var genAIOperations = map[string]string{
+	"chat":     "chat",
+	"generate": "text_completion",
+}
+
+// genAIProvider returns the semantic-convention provider name for a Call provider.
+func genAIProvider(provider string) string {
+	if provider == "bedrock" {
+		return "aws.bedrock"
+	}
+	return provider
+}
+
+// callSpanAttrs returns the attributes known before a call is sent.
+func callSpanAttrs(call *Call) []Attr {
+	op, ok := genAIOperations[call.Operation]
+	if !ok {
+		op = call.Operation
+	}
+	attrs := []Attr{
+		{"gen_ai.operation.name", op},
+		{"gen_ai.provider.name", genAIProvider(call.Provider)},
+	}
+	if o := call.Options; o != nil {
+		attrs = append(attrs, Attr{"gen_ai.request.model", o.Model})
+		if o.Options != nil {
+			if o.Options.MaxTokens > 0 {
+				attrs = append(attrs, Attr{"gen_ai.request.max_tokens", o.Options.MaxTokens})
+			}
+			if o.Options.Temperature != 0 {
+				attrs = append(attrs, Attr{"gen_ai.request.temperature", o.Options.Temperature})
+			}
+			if o.Options.TopP != 0 {
+				attrs = append(attrs, Attr{"gen_ai.request.top_p", o.Options.TopP})
+			}
+		}
+	}
+	return attrs
+}
+
+// startCallSpan starts the span for call. The returned function ends it and
+// records the call's metrics.
+func (c *Client) startCallSpan(ctx context.Context, call *Call) (context.Context, func(out any, err error)) {
+	if c.tracer == nil && c.meter == nil {
+		return ctx, func(any, error) {}
+	}
+
+	start := time.Now()
+	attrs := callSpanAttrs(call)
+	var span Span
+	if c.tracer != nil {
+		name := attrs[0].Value.(string)
+		if call.Options != nil && call.Options.Model != "" {
+			name += " " + call.Options.Model
+		}
+		ctx, span = c.tracer.Start(ctx, name, attrs...)
+	}
+
+	return ctx, func(out any, err error) {
+		var end []Attr
+		metricAttrs := attrs
+		_, gen := callOutcome(out, err)
+		if gen != nil {
+			if gen.Model != "" {
+				end = append(end, Attr{"gen_ai.response.model", gen.Model})
+			}
+			if reason := finishReason(gen); reason != "" {
+				end = append(end, Attr{"gen_ai.response.finish_reasons", []string{reason}})
+			}
+			toolCalls := 0
+			for _, ch := range gen.Choices {
+				toolCalls += len(ch.Message.ToolCalls)
+			}
+			end = append(end,
+				Attr{"gen_ai.usage.input_tokens", gen.Usage.PromptTokens},
+				Attr{"gen_ai.usage.output_tokens", gen.Usage.CompletionTokens},
+				Attr{"gen_ai.usage.cache_read.input_tokens", gen.Usage.CacheReadInputTokens},
+				Attr{"gen_ai.usage.cache_creation.input_tokens", gen.Usage.CacheCreationInputTokens},
+				Attr{"gollama.response.tool_calls", toolCalls},
+			)
+		}
+		if err != nil {
+			errType := Attr{"error.type", errorType(err)}
+			end = append(end, errType)
+			metricAttrs = append(metricAttrs[:len(metricAttrs):len(metricAttrs)], errType)
+		}
+
+		if span != nil {
+			span.SetAttributes(end...)
+			if err != nil {
+				span.RecordError(err)
+			}
+			span.End()
+		}
+
+		if c.meter != nil {
+			c.meter.Record(ctx, MetricOperationDuration, time.Since(start).Seconds(), metricAttrs...)
+			c.meter.Add(ctx, MetricRequests, 1, metricAttrs...)
+			if gen != nil {
+				c.recordTokens(ctx, gen.Usage, metricAttrs)
+			}
+		}
+	}
+}
+
+// recordTokens records the token counts of a chat response.
+func (c *Client) recordTokens(ctx context.Context, u Usage, attrs []Attr) {
+	with := func(kind string) []Attr {
+		return append(attrs[:len(attrs):len(attrs)], Attr{"gen_ai.token.type", kind})
+	}
+	c.meter.Record(ctx, MetricTokenUsage, float64(u.PromptTokens), with("input")...)
+	c.meter.Record(ctx, MetricTokenUsage, float64(u.CompletionTokens), with("output")...)
+	for kind, n := range map[string]int{
+		"input":          u.PromptTokens,
+		"output":         u.CompletionTokens,
+		"cache_read":     u.CacheReadInputTokens,
+		"cache_creation": u.CacheCreationInputTokens,
+	} {
+		if n > 0 {
+			c.meter.Add(ctx, MetricTokens, int64(n), with(kind)...)
+		}
+	}
+}
+
+// startAttemptSpan starts a child span for one HTTP attempt of a call. The
+// returned function ends it with the response status, or err if the request
+// could not be sent.
+func (c *Client) startAttemptSpan(ctx context.Context, req *http.Request, attempt int) func(status int, err error) {
+	if c.tracer == nil && c.meter == nil {
+		return func(int, error) {}
+	}
+
+	if attempt > 0 && c.meter != nil {
+		c.meter.Add(ctx, MetricRetries, 1, Attr{"http.request.method", req.Method}, Attr{"server.address", req.URL.Hostname()})
+	}
+	if c.tracer == nil {
+		return func(int, error) {}
+	}
+
+	attrs := []Attr{
+		{"http.request.method", req.Method},
+		{"url.full", redactSecrets(req.URL.String())},
+		{"server.address", req.URL.Hostname()},
+	}
+	if attempt > 0 {
+		attrs = append(attrs, Attr{"http.request.resend_count", attempt})
+	}
+	_, span := c.tracer.Start(ctx, req.Method, attrs...)
+	return func(status int, err error) {
+		if err != nil {
+			span.SetAttributes(Attr{"error.type", errorType(err)})
+			span.RecordError(err)
+		} else {
+			span.SetAttributes(Attr{"http.response.status_code", status})
+			if status >= 400 {
+				span.SetAttributes(Attr{"error.type", strconv.Itoa(status)})
+			}
+		}
+		span.End()
+	}
+}
+
+// HandleToolCall runs a tool call like the package-level HandleToolCall,
+// reporting it as an execute_tool span (a child of any span in ctx) when the
+// client has a Tracer.
+func (c *Client) HandleToolCall(ctx context.Context, tools []*Tool, call ToolCall) (*ToolResult, error) {
+	if c.tracer == nil {
+		return HandleToolCall(ctx, tools, call)
+	}
+
+	ctx, span := c.tracer.Start(ctx, "execute_tool "+call.Function.Name,
+		Attr{"gen_ai.operation.name", "execute_tool"},
+		Attr{"gen_ai.tool.name", call.Function.Name},
+		Attr{"gen_ai.tool.call.id", call.ID},
+	)
+	defer span.End()
+
+	res, err := HandleToolCall(ctx, tools, call)
+	if err != nil {
+		span.SetAttributes(Attr{"error.type", errorType(err)})
+		span.RecordError(err)
+	}
+	return res, err
+}
+
+// finishReason returns the stop reason of a chat response.
+func finishReason(r *ResponseMessageGenerate) string {
+	if r.StopReason != "" {
+		return r.StopReason
+	}
+	if len(r.Choices) > 0 {
+		return r.Choices[0].FinishReason
+	}
+	return ""
+}
+
+// errorType returns a low-cardinality description of err for the error.type
+// attribute: the HTTP status for API errors, otherwise a coarse category.
+func errorType(err error) string {
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr):
+		return strconv.Itoa(apiErr.StatusCode)
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	default:
+		return "_OTHER"
+	}
+}
@@ -0,0 +1,156 @@
+package gollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memTelemetry is an in-memory Tracer and Meter that records finished spans
+// and metric values for assertions.
+type memTelemetry struct {
+	mu       sync.Mutex
+	spans    []*memSpan
+	counters map[string]int64
+	hists    map[string][]float64
+}
+
+type memSpan struct {
+	t      *memTelemetry
+	name   string
+	parent *memSpan
+	attrs  map[string]any
+	err    error
+}
+
+type memSpanKey struct{}
+
+func newMemTelemetry() *memTelemetry {
+	return &memTelemetry{counters: map[string]int64{}, hists: map[string][]float64{}}
+}
+
+func (t *memTelemetry) Start(ctx context.Context, name string, attrs ...Attr) (context.Context, Span) {
+	parent, _ := ctx.Value(memSpanKey{}).(*memSpan)
+	s := &memSpan{t: t, name: name, parent: parent, attrs: map[string]any{}}
+	s.SetAttributes(attrs...)
+	return context.WithValue(ctx, memSpanKey{}, s), s
+}
+
+func (s *memSpan) SetAttributes(attrs ...Attr) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *memSpan) RecordError(err error) { s.err = err }
+
+func (s *memSpan) End() {
+	s.t.mu.Lock()
+	defer s.t.mu.Unlock()
+	s.t.spans = append(s.t.spans, s)
+}
+
+func (t *memTelemetry) Add(ctx context.Context, name string, v int64, attrs ...Attr) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.counters[name] += v
+}
+
+func (t *memTelemetry) Record(ctx context.Context, name string, v float64, attrs ...Attr) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.hists[name] = append(t.hists[name], v)
+}
+
+func (t *memTelemetry) span(name string) *memSpan {
+	for _, s := range t.spans {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func TestTelemetry(t *testing.T) {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(529)
+			return
+		}
+		fmt.Fprint(w, `{"role":"assistant","model":"claude-x","stop_reason":"tool_use",
+			"content":[{"type":"tool_use","id":"tu_1","name":"echo","input":{"s":"hi"}}],
+			"usage":{"input_tokens":10,"output_tokens":4,"cache_read_input_tokens":6}}`)
+	}))
+	defer srv.Close()
+
+	tel := newMemTelemetry()
+	c := NewClient(srv.URL, WithAnthropicMode(true), WithTracer(tel), WithMeter(tel), WithRetryPolicy(1, time.Millisecond))
+
+	resp, err := c.Turn(RequestOptions{Model: "claude", Messages: []Message{{Role: "user", Content: "hi"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chat := tel.span("chat claude")
+	if chat == nil {
+		t.Fatalf("no chat span in %v", tel.spans)
+	}
+	for k, want := range map[string]any{
+		"gen_ai.operation.name":                "chat",
+		"gen_ai.provider.name":                 "anthropic",
+		"gen_ai.request.model":                 "claude",
+		"gen_ai.response.model":                "claude-x",
+		"gen_ai.usage.input_tokens":            10,
+		"gen_ai.usage.output_tokens":           4,
+		"gen_ai.usage.cache_read.input_tokens": 6,
+		"gollama.response.tool_calls":          1,
+	} {
+		if chat.attrs[k] != want {
+			t.Errorf("chat span %s = %v, want %v", k, chat.attrs[k], want)
+		}
+	}
+	if fr, _ := chat.attrs["gen_ai.response.finish_reasons"].([]string); len(fr) != 1 || fr[0] != "tool_use" {
+		t.Errorf("finish_reasons = %v", chat.attrs["gen_ai.response.finish_reasons"])
+	}
+
+	var httpSpans []*memSpan
+	for _, s := range tel.spans {
+		if s.name == "POST" {
+			httpSpans = append(httpSpans, s)
+		}
+	}
+	if len(httpSpans) != 2 || httpSpans[0].parent != chat || httpSpans[1].parent != chat {
+		t.Fatalf("expected two POST children of the chat span, got %d", len(httpSpans))
+	}
+	if httpSpans[0].attrs["http.response.status_code"] != 529 || httpSpans[1].attrs["http.request.resend_count"] != 1 {
+		t.Errorf("attempt spans = %v, %v", httpSpans[0].attrs, httpSpans[1].attrs)
+	}
+
+	if tel.counters[MetricRequests] != 1 || tel.counters[MetricRetries] != 1 ||
+		tel.counters[MetricTokens] != 20 || len(tel.hists[MetricOperationDuration]) != 1 ||
+		len(tel.hists[MetricTokenUsage]) != 2 {
+		t.Errorf("metrics: counters=%v hists=%v", tel.counters, tel.hists)
+	}
+
+	// Tool executions are children of whatever span the caller is in.
+	tools := []*Tool{{Name: "echo", Call: StringResultCall(func(ctx context.Context, p any) (string, error) {
+		return "", errors.New("boom")
+	})}}
+	ctx, agent := tel.Start(context.Background(), "agent")
+	_, err = c.HandleToolCall(ctx, tools, resp.Choices[0].Message.ToolCalls[0])
+	agent.End()
+	if err == nil {
+		t.Fatal("expected tool error")
+	}
+	tool := tel.span("execute_tool echo")
+	if tool == nil || tool.parent == nil || tool.parent.name != "agent" || tool.attrs["gen_ai.tool.call.id"] != "tu_1" || tool.err == nil {
+		t.Errorf("tool span = %+v", tool)
+	}
+}
@@ -0,0 +1,183 @@
+package gollama
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ToolExecOptions configures ExecuteToolCalls.
+type ToolExecOptions struct {
+	// Concurrency is the number of tool calls run at once (default 1).
+	// Calls to Sequential tools always run alone.
+	Concurrency int
+	// Timeout bounds each tool call unless the tool sets its own Timeout.
+	// Zero means no timeout.
+	Timeout time.Duration
+}
+
+// ExecuteToolCalls runs calls, as emitted by a model in one turn, with up to
+// opts.Concurrency running at once, and returns their results in call order.
+// A Sequential tool waits for the calls before it to finish and runs before
+// any call after it starts.
+//
+// Failures never abort the other calls: an unknown tool, bad arguments, a
+// tool error, a timeout or a panic each become an IsError result describing
+// the problem, ready to be sent back to the model with ToolResultMessage.
+func ExecuteToolCalls(ctx context.Context, tools []*Tool, calls []ToolCall, opts ToolExecOptions) []*ToolResult {
+	results, _ := executeToolCalls(ctx, tools, calls, opts, func(ctx context.Context, call ToolCall) (*ToolResult, error) {
+		return HandleToolCall(ctx, tools, call)
+	}, nil)
+	return results
+}
+
+// ToolResultMessage returns the tool message answering call with res.
+func ToolResultMessage(call ToolCall, res *ToolResult) Message {
+	return Message{
+		Role:       "tool",
+		ToolCallID: call.ID,
+		Content:    res.Content,
+		Images:     res.Images,
+		Documents:  res.Documents,
+	}
+}
+
+// executeToolCalls runs each call through handle, bounded by the timeout of
+// its tool and guarded against panics. Errors from handle become IsError
+// results. wrap, if set, is called around each call with the result and may
+// return a fatal error, such as one from an agent hook; the first one is
+// returned and calls not yet started are skipped.
+func executeToolCalls(ctx context.Context, tools []*Tool, calls []ToolCall, opts ToolExecOptions,
+	handle func(context.Context, ToolCall) (*ToolResult, error),
+	wrap func(ctx context.Context, call ToolCall, run func(context.Context) *ToolResult) (*ToolResult, error),
+) ([]*ToolResult, error) {
+	concurrency := max(opts.Concurrency, 1)
+	results := make([]*ToolResult, len(calls))
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		fatal    error
+		inflight = make(chan struct{}, concurrency)
+	)
+	run := func(i int) {
+		call := calls[i]
+		timeout := opts.Timeout
+		if tool := findTool(tools, call.Function.Name); tool != nil && tool.Timeout > 0 {
+			timeout = tool.Timeout
+		}
+		exec := func(ctx context.Context) *ToolResult {
+			return runToolCall(ctx, call, timeout, handle)
+		}
+
+		var res *ToolResult
+		var err error
+		if wrap != nil {
+			res, err = guardPanic(call, func() (*ToolResult, error) { return wrap(ctx, call, exec) })
+		} else {
+			res = exec(ctx)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil && fatal == nil {
+			fatal = err
+		}
+		results[i] = res
+	}
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return fatal != nil
+	}
+
+	for i, call := range calls {
+		if failed() {
+			break
+		}
+		if tool := findTool(tools, call.Function.Name); tool != nil && tool.Sequential {
+			wg.Wait()
+			if failed() {
+				break
+			}
+			run(i)
+			continue
+		}
+		inflight <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-inflight
+				wg.Done()
+			}()
+			run(i)
+		}(i)
+	}
+	wg.Wait()
+
+	if fatal != nil {
+		return nil, fatal
+	}
+	return results, nil
+}
+
+// runToolCall runs one call with an optional timeout. A call that overruns
+// its timeout is abandoned: its result is discarded once it returns.
+func runToolCall(ctx context.Context, call ToolCall, timeout time.Duration, handle func(context.Context, ToolCall) (*ToolResult, error)) *ToolResult {
+	if timeout <= 0 {
+		res, err := guardPanic(call, func() (*ToolResult, error) { return handle(ctx, call) })
+		return toolOutcome(res, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	type outcome struct {
+		res *ToolResult
+		err error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		res, err := guardPanic(call, func() (*ToolResult, error) { return handle(ctx, call) })
+		done <- outcome{res, err}
+	}()
+	select {
+	case o := <-done:
+		return toolOutcome(o.res, o.err)
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return toolOutcome(nil, fmt.Errorf("tool %q timed out after %s", call.Function.Name, timeout))
+		}
+		return toolOutcome(nil, ctx.Err())
+	}
+}
+
+// guardPanic calls fn, turning a panic into an error.
+func guardPanic(call ToolCall, fn func() (*ToolResult, error)) (res *ToolResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			res, err = nil, fmt.Errorf("tool %q panicked: %v", call.Function.Name, r)
+		}
+	}()
+	return fn()
+}
+
+// toolOutcome converts a tool's return values into the result sent to the
+// model.
+func toolOutcome(res *ToolResult, err error) *ToolResult {
+	if err != nil {
+		return &ToolResult{Content: fmt.Sprintf("error: %s", err), IsError: true}
+	}
+	if res == nil {
+		return &ToolResult{}
+	}
+	return res
+}
+
+func findTool(tools []*Tool, name string) *Tool {
+	for _, t := range tools {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package gollama
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExecuteToolCalls(t *testing.T) {
+	var running, peak atomic.Int32
+	track := func() func() {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		return func() { running.Add(-1) }
+	}
+	var seqOverlap atomic.Bool
+
+	tools := []*Tool{
+		{Name: "sleep", Call: func(ctx context.Context, params any) (*ToolResult, error) {
+			defer track()()
+			d := time.Duration(params.(map[string]any)["ms"].(float64)) * time.Millisecond
+			time.Sleep(d)
+			return &ToolResult{Content: fmt.Sprint(d)}, nil
+		}},
+		{Name: "seq", Sequential: true, Call: func(ctx context.Context, params any) (*ToolResult, error) {
+			if running.Load() != 0 {
+				seqOverlap.Store(true)
+			}
+			defer track()()
+			time.Sleep(5 * time.Millisecond)
+			return &ToolResult{Content: "seq"}, nil
+		}},
+		{Name: "panic", Call: func(ctx context.Context, params any) (*ToolResult, error) {
+			panic("boom")
+		}},
+		{Name: "hang", Timeout: 10 * time.Millisecond, Call: func(ctx context.Context, params any) (*ToolResult, error) {
+			time.Sleep(time.Second)
+			return &ToolResult{Content: "late"}, nil
+		}},
+	}
+	calls := []ToolCall{
+		agentToolCall("1", "sleep", `{"ms":30}`),
+		agentToolCall("2", "sleep", `{"ms":10}`),
+		agentToolCall("3", "seq", `{}`),
+		agentToolCall("4", "panic", `{}`),
+		agentToolCall("5", "hang", `{}`),
+		agentToolCall("6", "sleep", `{"ms":1}`),
+	}
+
+	start := time.Now()
+	results := ExecuteToolCalls(context.Background(), tools, calls, ToolExecOptions{Concurrency: 2})
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("hung tool was not abandoned, took %s", elapsed)
+	}
+
+	want := []string{"30ms", "10ms", "seq", `tool "panic" panicked: boom`, `tool "hang" timed out after 10ms`, "1ms"}
+	for i, w := range want {
+		if !strings.Contains(results[i].Content, w) {
+			t.Errorf("result %d = %q, want %q", i, results[i].Content, w)
+		}
+		if results[i].IsError != (i == 3 || i == 4) {
+			t.Errorf("result %d IsError = %v", i, results[i].IsError)
+		}
+	}
+	if peak.Load() != 2 {
+		t.Errorf("peak concurrency %d, want 2", peak.Load())
+	}
+	if seqOverlap.Load() {
+		t.Error("sequential tool ran alongside other calls")
+	}
+
+	msg := ToolResultMessage(calls[2], results[2])
+	if msg.Role != "tool" || msg.ToolCallID != "3" || msg.Content != "seq" {
+		t.Errorf("message = %+v", msg)
+	}
+}
@@ -0,0 +1,263 @@
+package gollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ToolNamespaceSeparator joins a namespace and a tool name in the names a
+// ToolRegistry exposes, e.g. "github__create_issue".
+const ToolNamespaceSeparator = "__"
+
+// ErrToolCollision is returned when registering a tool or alias whose name
+// is already taken.
+var ErrToolCollision = errors.New("tool name collision")
+
+// ToolRegistry holds the tools offered to a model, typically gathered from
+// several MCP servers. Tools are registered under a namespace, so that two
+// servers can both provide a "search" tool, and collisions are reported
+// instead of one tool silently shadowing another.
+//
+// Every tool belongs to the set named after its namespace and to any sets it
+// is added to with AddToSet. Disabling a set hides its tools from Tools,
+// ApiDefs and Lookup until it is enabled again, so the tools offered can
+// change from turn to turn. ApiDefs is sorted by name so that the tool
+// definitions, which lead the prompt, stay byte-identical across turns and
+// keep prompt caching effective.
+//
+// A ToolRegistry is safe for concurrent use.
+type ToolRegistry struct {
+	mu       sync.RWMutex
+	tools    map[string]*registeredTool
+	aliases  map[string]string
+	disabled map[string]bool
+}
+
+type registeredTool struct {
+	tool *Tool // copy of the registered tool, named with its qualified name
+	base string
+	sets []string
+}
+
+// NewToolRegistry returns an empty ToolRegistry.
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{
+		tools:    make(map[string]*registeredTool),
+		aliases:  make(map[string]string),
+		disabled: make(map[string]bool),
+	}
+}
+
+// QualifiedToolName returns the name a tool registered under namespace is
+// exposed as.
+func QualifiedToolName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + ToolNamespaceSeparator + name
+}
+
+// Register adds tools under namespace (which may be empty) and to the set of
+// the same name. The tools are copied; later changes to them have no effect.
+// If any name collides with a registered tool or alias, or with another tool
+// in the same call, nothing is registered and the error wraps
+// ErrToolCollision.
+func (r *ToolRegistry) Register(namespace string, tools ...*Tool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	added := make(map[string]bool)
+	for _, t := range tools {
+		name := QualifiedToolName(namespace, t.Name)
+		if r.taken(name) || added[name] {
+			return fmt.Errorf("error registering tool %q: %w", name, ErrToolCollision)
+		}
+		added[name] = true
+	}
+	for _, t := range tools {
+		cp := *t
+		cp.Name = QualifiedToolName(namespace, t.Name)
+		r.tools[cp.Name] = &registeredTool{tool: &cp, base: t.Name, sets: []string{namespace}}
+	}
+	return nil
+}
+
+// taken reports whether name is a registered tool or alias. r.mu must be
+// held.
+func (r *ToolRegistry) taken(name string) bool {
+	_, isTool := r.tools[name]
+	_, isAlias := r.aliases[name]
+	return isTool || isAlias
+}
+
+// Unregister removes the named tools and any aliases pointing at them.
+func (r *ToolRegistry) Unregister(names ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, name := range names {
+		delete(r.tools, name)
+		for alias, target := range r.aliases {
+			if target == name {
+				delete(r.aliases, alias)
+			}
+		}
+	}
+}
+
+// Alias makes alias resolve to the registered tool name in Lookup. Aliases
+// are not offered to the model; they catch names a model is likely to use
+// by mistake.
+func (r *ToolRegistry) Alias(alias, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tools[name]; !ok {
+		return fmt.Errorf("error adding alias %q: no such tool %q", alias, name)
+	}
+	if r.taken(alias) {
+		return fmt.Errorf("error adding alias %q: %w", alias, ErrToolCollision)
+	}
+	r.aliases[alias] = name
+	return nil
+}
+
+// AddToSet adds the named tools to set, e.g. to group the destructive tools
+// of several namespaces so they can be disabled together.
+func (r *ToolRegistry) AddToSet(set string, names ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, name := range names {
+		if _, ok := r.tools[name]; !ok {
+			return fmt.Errorf("error adding %q to set %q: no such tool", name, set)
+		}
+	}
+	for _, name := range names {
+		rt := r.tools[name]
+		if !containsString(rt.sets, set) {
+			rt.sets = append(rt.sets, set)
+		}
+	}
+	return nil
+}
+
+// Enable re-enables the given sets.
+func (r *ToolRegistry) Enable(sets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range sets {
+		delete(r.disabled, s)
+	}
+}
+
+// Disable hides the tools of the given sets. A tool is hidden if any of its
+// sets is disabled.
+func (r *ToolRegistry) Disable(sets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range sets {
+		r.disabled[s] = true
+	}
+}
+
+// enabled reports whether rt is visible. r.mu must be held.
+func (r *ToolRegistry) enabled(rt *registeredTool) bool {
+	for _, s := range rt.sets {
+		if r.disabled[s] {
+			return false
+		}
+	}
+	return true
+}
+
+// Tools returns the enabled tools sorted by name.
+func (r *ToolRegistry) Tools() []*Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []*Tool
+	for _, rt := range r.tools {
+		if r.enabled(rt) {
+			out = append(out, rt.tool)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// ApiDefs returns the definitions of the enabled tools for
+// RequestOptions.Tools, sorted by name.
+func (r *ToolRegistry) ApiDefs() []ToolParam {
+	var defs []ToolParam
+	for _, t := range r.Tools() {
+		defs = append(defs, t.ApiDef())
+	}
+	return defs
+}
+
+// Lookup finds an enabled tool by its exact name, an alias, the qualified
+// name spelled with "." or "/" instead of ToolNamespaceSeparator, or, if
+// exactly one enabled tool has it, the name it was registered with before
+// namespacing.
+func (r *ToolRegistry) Lookup(name string) (*Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if target, ok := r.aliases[name]; ok {
+		name = target
+	}
+	if rt, ok := r.tools[name]; ok {
+		if r.enabled(rt) {
+			return rt.tool, true
+		}
+		return nil, false
+	}
+	for _, sep := range []string{".", "/"} {
+		if rt, ok := r.tools[strings.Replace(name, sep, ToolNamespaceSeparator, 1)]; ok && strings.Contains(name, sep) {
+			if r.enabled(rt) {
+				return rt.tool, true
+			}
+		}
+	}
+
+	var found *Tool
+	for _, rt := range r.tools {
+		if rt.base == name && r.enabled(rt) {
+			if found != nil {
+				return nil, false // ambiguous
+			}
+			found = rt.tool
+		}
+	}
+	return found, found != nil
+}
+
+// HandleToolCall runs call against the tool Lookup finds for its name, like
+// the package-level HandleToolCall.
+func (r *ToolRegistry) HandleToolCall(ctx context.Context, call ToolCall) (*ToolResult, error) {
+	return HandleToolCall(ctx, r.toolsFor([]ToolCall{call}), call)
+}
+
+// toolsFor resolves the tool of each call, returning copies named as the
+// model called them so HandleToolCall finds them. Calls that resolve to no
+// enabled tool are left out and fail as unknown tools.
+func (r *ToolRegistry) toolsFor(calls []ToolCall) []*Tool {
+	var out []*Tool
+	for _, call := range calls {
+		if t, ok := r.Lookup(call.Function.Name); ok {
+			cp := *t
+			cp.Name = call.Function.Name
+			out = append(out, &cp)
+		}
+	}
+	return out
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package gollama
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func namedTool(name, reply string) *Tool {
+	return &Tool{Name: name, Call: StringResultCall(func(context.Context, any) (string, error) { return reply, nil })}
+}
+
+func toolNames(tools []*Tool) []string {
+	var names []string
+	for _, t := range tools {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+func TestToolRegistry(t *testing.T) {
+	reg := NewToolRegistry()
+	if err := reg.Register("github", namedTool("search", "gh"), namedTool("create_issue", "issue")); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Register("web", namedTool("search", "web"), namedTool("fetch", "page")); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Register("", namedTool("shell", "ran")); err != nil {
+		t.Fatal(err)
+	}
+
+	err := reg.Register("web", namedTool("weather", "sunny"), namedTool("fetch", "again"))
+	if !errors.Is(err, ErrToolCollision) {
+		t.Errorf("collision err = %v", err)
+	}
+	if _, ok := reg.Lookup("web__weather"); ok {
+		t.Error("failed Register left a partial registration")
+	}
+
+	want := []string{"github__create_issue", "github__search", "shell", "web__fetch", "web__search"}
+	if got := toolNames(reg.Tools()); !reflect.DeepEqual(got, want) {
+		t.Errorf("tools = %v", got)
+	}
+	defs := reg.ApiDefs()
+	if len(defs) != 5 || defs[0].Function.Name != "github__create_issue" {
+		t.Errorf("defs = %+v", defs)
+	}
+
+	if err := reg.Alias("gh_search", "github__search"); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"web__search":   "web__search",
+		"gh_search":     "github__search",
+		"web.fetch":     "web__fetch",
+		"github/search": "github__search",
+		"create_issue":  "github__create_issue",
+	} {
+		if tool, ok := reg.Lookup(name); !ok || tool.Name != want {
+			t.Errorf("Lookup(%q) = %v, %v", name, tool, ok)
+		}
+	}
+	if _, ok := reg.Lookup("search"); ok {
+		t.Error("ambiguous unqualified name resolved")
+	}
+
+	if err := reg.AddToSet("destructive", "shell", "github__create_issue"); err != nil {
+		t.Fatal(err)
+	}
+	reg.Disable("destructive", "web")
+	if got := toolNames(reg.Tools()); !reflect.DeepEqual(got, []string{"github__search"}) {
+		t.Errorf("tools after Disable = %v", got)
+	}
+	if _, ok := reg.Lookup("search"); !ok {
+		t.Error("unqualified name should be unambiguous once web is disabled")
+	}
+	if _, err := reg.HandleToolCall(context.Background(), agentToolCall("1", "shell", `{}`)); err == nil {
+		t.Error("disabled tool was called")
+	}
+	reg.Enable("destructive")
+	res, err := reg.HandleToolCall(context.Background(), agentToolCall("1", "shell", `{}`))
+	if err != nil || res.Content != "ran" {
+		t.Errorf("shell = %+v, %v", res, err)
+	}
+
+	reg.Unregister("github__search")
+	if _, ok := reg.Lookup("gh_search"); ok {
+		t.Error("alias survived Unregister")
+	}
+}
+
+func TestRunAgentRegistry(t *testing.T) {
+	reg := NewToolRegistry()
+	reg.Register("a", namedTool("one", "first"))
+	reg.Register("b", namedTool("two", "second"))
+
+	turner := &scriptedTurner{next: func(turn int, opts RequestOptions) *ResponseMessageGenerate {
+		if turn == 0 {
+			// The model drops the namespace; the registry still resolves it.
+			return agentResponse("", "tool_use", agentToolCall("x", "one", `{}`))
+		}
+		return agentResponse("done", "end_turn")
+	}}
+	res, err := RunAgent(context.Background(), turner, RequestOptions{Messages: []Message{{Role: "user", Content: "go"}}}, nil, AgentOptions{
+		Registry: reg,
+		BeforeTurn: func(ctx context.Context, turn int, opts *RequestOptions) error {
+			if turn == 1 {
+				reg.Disable("a")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Messages[2].Content != "first" {
+		t.Errorf("tool result = %+v", res.Messages[2])
+	}
+	if len(turner.calls[0].Tools) != 2 || len(turner.calls[1].Tools) != 1 || turner.calls[1].Tools[0].Function.Name != "b__two" {
+		t.Errorf("tools offered: %+v then %+v", turner.calls[0].Tools, turner.calls[1].Tools)
+	}
+}
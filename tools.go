@@ -45,22 +45,34 @@ type ToolCallFunction struct {
 
 // HandleToolCall finds and executes a tool by name from the given tool list.
 // Returns the tool's response or an error.
+//
+// Malformed arguments are first fixed with RepairJSON; the fixes are
+// reported in the result's Repairs. The arguments are then validated against
+// the tool's Params schema. If they do not match, the tool is not called and
+// the result is the ArgumentError's Result, telling the model what to fix.
 func HandleToolCall(ctx context.Context, tools []*Tool, call ToolCall) (*ToolResult, error) {
-	var tool *Tool
-	for _, t := range tools {
-		if t.Name == call.Function.Name {
-			tool = t
-			break
-		}
-	}
+	tool := findTool(tools, call.Function.Name)
 	if tool == nil {
 		return nil, fmt.Errorf("no such tool %q", call.Function.Name)
 	}
 
+	args, repairs, err := RepairJSON(call.Function.Arguments)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tool arguments: %w", err)
+	}
 	var params map[string]any
-	if err := json.Unmarshal([]byte(call.Function.Arguments), &params); err != nil {
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
 		return nil, fmt.Errorf("invalid tool arguments: %w", err)
 	}
+	if argErr := validateToolArgs(tool, params); argErr != nil {
+		res := argErr.Result()
+		res.Repairs = repairs
+		return res, nil
+	}
 
-	return tool.Call(ctx, params)
+	res, err := tool.Call(ctx, params)
+	if res != nil && len(repairs) > 0 {
+		res.Repairs = repairs
+	}
+	return res, err
 }
@@ -1,6 +1,24 @@
 package gollama
 
-import "strings"
+import (
+	"context"
+	"strings"
+)
+
+// Turner takes a single model turn. *Client satisfies it, as do
+// FallbackClient, PoolClient and HedgedClient, so agent code can depend on
+// Turner and be tested against a scripted fake such as gollamatest.Fake.
+type Turner interface {
+	Turn(opts RequestOptions) (*ResponseMessageGenerate, error)
+	TurnContext(ctx context.Context, opts RequestOptions) (*ResponseMessageGenerate, error)
+}
+
+var (
+	_ Turner = (*Client)(nil)
+	_ Turner = (*FallbackClient)(nil)
+	_ Turner = (*PoolClient)(nil)
+	_ Turner = (*HedgedClient)(nil)
+)
 
 // Backend identifies which provider/transport a Client is configured to talk to.
 type Backend int
@@ -58,6 +76,11 @@ func (c *Client) Backend() Backend {
 // to depend on and never have to branch per provider. Streaming is always
 // disabled (the agent loop consumes whole turns).
 func (c *Client) Turn(opts RequestOptions) (*ResponseMessageGenerate, error) {
+	return c.TurnContext(context.Background(), opts)
+}
+
+// TurnContext is Turn with a context; cancelling ctx aborts the request.
+func (c *Client) TurnContext(ctx context.Context, opts RequestOptions) (*ResponseMessageGenerate, error) {
 	opts.Stream = false
-	return c.ChatCompletion(opts)
+	return c.ChatCompletionContext(ctx, opts)
 }
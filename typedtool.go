@@ -0,0 +1,102 @@
+package gollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// NewTypedTool builds a Tool from a typed Go function. The parameter schema
+// is derived from In, which must be a struct (or pointer to one). Fields are
+// named by their json tag and described by these tags:
+//
+//	description:"..."   the property's description
+//	enum:"a,b,c"        the allowed values
+//	required:"true"     whether the property is required; by default it is
+//	                    unless it is a pointer or tagged omitempty
+//	min:"1" max:"10"    bounds: minimum/maximum for numbers, minLength/
+//	                    maxLength for strings, minItems/maxItems for arrays
+//
+// Pointer fields also accept null, and unsigned integer fields have a
+// minimum of 0. OutputType is set from Out.
+//
+// Before fn is called the model's arguments are validated against the
+// schema, so a missing field or a value of the wrong type is reported back
+// to the model with ArgumentError.Result instead of reaching fn, and then
+// decoded into In.
+//
+// fn's result becomes the ToolResult: a string is used as its Content, a
+// *ToolResult is returned as is, and any other value is encoded as JSON into
+// Content and kept in Structured.
+//
+// NewTypedTool panics if no schema can be derived from In.
+func NewTypedTool[In, Out any](name, description string, fn func(context.Context, In) (Out, error)) *Tool {
+	inType := reflect.TypeOf((*In)(nil)).Elem()
+	st := inType
+	for st.Kind() == reflect.Pointer {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("gollama: NewTypedTool %q: input type %s is not a struct", name, inType))
+	}
+	schema, err := schemaForType(inType)
+	if err != nil {
+		panic(fmt.Sprintf("gollama: NewTypedTool %q: %v", name, err))
+	}
+	params := ToolFunctionParams{
+		Type:       "object",
+		Properties: schema["properties"].(map[string]any),
+		Required:   schema["required"].([]string),
+	}
+	check, err := normalizeSchema(params)
+	if err != nil {
+		panic(fmt.Sprintf("gollama: NewTypedTool %q: %v", name, err))
+	}
+
+	var out Out
+	return &Tool{
+		Name:        name,
+		Description: description,
+		Params:      params,
+		OutputType:  out,
+		Call: func(ctx context.Context, args any) (*ToolResult, error) {
+			raw, err := json.Marshal(args)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tool arguments: %w", err)
+			}
+			var generic any
+			if err := json.Unmarshal(raw, &generic); err != nil {
+				return nil, fmt.Errorf("invalid tool arguments: %w", err)
+			}
+			if argErr := validateArgs(name, check, generic); argErr != nil {
+				return argErr.Result(), nil
+			}
+			var in In
+			if err := json.Unmarshal(raw, &in); err != nil {
+				return nil, fmt.Errorf("invalid tool arguments: %w", err)
+			}
+
+			res, err := fn(ctx, in)
+			if err != nil {
+				return nil, err
+			}
+			return typedToolResult(res)
+		},
+	}
+}
+
+// typedToolResult converts the result of a typed tool function.
+func typedToolResult(v any) (*ToolResult, error) {
+	switch v := v.(type) {
+	case string:
+		return &ToolResult{Content: v}, nil
+	case *ToolResult:
+		return v, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding tool result: %w", err)
+	}
+	return &ToolResult{Content: string(b), Structured: v}, nil
+}
@@ -0,0 +1,118 @@
+package gollama
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type weatherQuery struct {
+	City  string   `json:"city" description:"City name" min:"1"`
+	Units string   `json:"units,omitempty" enum:"metric,imperial"`
+	Days  int      `json:"days" min:"1" max:"7"`
+	Tags  []string `json:"tags,omitempty" max:"2"`
+	Debug *bool    `json:"debug"`
+}
+
+type weatherReport struct {
+	City  string  `json:"city"`
+	TempC float64 `json:"temp_c"`
+}
+
+func TestNewTypedTool(t *testing.T) {
+	var got weatherQuery
+	tool := NewTypedTool("weather", "Get the forecast", func(ctx context.Context, q weatherQuery) (weatherReport, error) {
+		got = q
+		return weatherReport{City: q.City, TempC: 21.5}, nil
+	})
+
+	schema, _ := json.Marshal(tool.Params)
+	for _, want := range []string{
+		`"required":["city","days"]`,
+		`"city":{"description":"City name","minLength":1,"type":"string"}`,
+		`"units":{"enum":["metric","imperial"],"type":"string"}`,
+		`"days":{"maximum":7,"minimum":1,"type":"integer"}`,
+		`"tags":{"items":{"type":"string"},"maxItems":2,"type":"array"}`,
+	} {
+		if !strings.Contains(string(schema), want) {
+			t.Errorf("schema %s\nmissing %s", schema, want)
+		}
+	}
+	if _, ok := tool.OutputType.(weatherReport); !ok {
+		t.Errorf("OutputType = %T", tool.OutputType)
+	}
+
+	call := agentToolCall("1", "weather", `{"city":"Oslo","units":"metric","days":3}`)
+	res, err := HandleToolCall(context.Background(), []*Tool{tool}, call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.City != "Oslo" || got.Days != 3 || got.Units != "metric" {
+		t.Errorf("decoded %+v", got)
+	}
+	if res.Content != `{"city":"Oslo","temp_c":21.5}` || res.Structured.(weatherReport).TempC != 21.5 {
+		t.Errorf("result = %+v", res)
+	}
+
+	got = weatherQuery{}
+	res, err = tool.Call(context.Background(), map[string]any{"units": "kelvin", "days": 9.5, "tags": []string{"a", "b", "c"}})
+	argErr, ok := res.Structured.(*ArgumentError)
+	if err != nil || !ok || !res.IsError || got.Days != 0 {
+		t.Fatalf("result = %+v, %v", res, err)
+	}
+	var problems []string
+	for _, p := range argErr.Problems {
+		problems = append(problems, p.Path+": "+p.Message)
+	}
+	want := []string{
+		"$.city: required property is missing",
+		"$.days: expected integer, got number",
+		"$.tags: must have at most 2 items",
+		`$.units: must be one of "metric", "imperial"`,
+	}
+	if strings.Join(problems, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems:\n%s", strings.Join(problems, "\n"))
+	}
+}
+
+func TestNewTypedToolNullAndUnsigned(t *testing.T) {
+	type query struct {
+		Limit *int    `json:"limit"`
+		Count uint    `json:"count"`
+		Mode  *string `json:"mode" enum:"fast,slow"`
+	}
+	tool := NewTypedTool("q", "", func(ctx context.Context, q query) (string, error) { return "ok", nil })
+
+	schema, _ := json.Marshal(tool.Params)
+	for _, want := range []string{
+		`"limit":{"type":["integer","null"]}`,
+		`"count":{"minimum":0,"type":"integer"}`,
+		`"mode":{"enum":["fast","slow",null],"type":["string","null"]}`,
+	} {
+		if !strings.Contains(string(schema), want) {
+			t.Errorf("schema %s\nmissing %s", schema, want)
+		}
+	}
+
+	res, err := HandleToolCall(context.Background(), []*Tool{tool}, agentToolCall("1", "q", `{"limit":null,"count":2,"mode":null}`))
+	if err != nil || res.IsError {
+		t.Fatalf("nulls rejected: %+v, %v", res, err)
+	}
+	res, err = HandleToolCall(context.Background(), []*Tool{tool}, agentToolCall("2", "q", `{"count":-1}`))
+	if err != nil || !res.IsError || !strings.Contains(res.Content, "$.count: must be >= 0") {
+		t.Errorf("negative count: %+v, %v", res, err)
+	}
+}
+
+func TestNewTypedToolStringResult(t *testing.T) {
+	tool := NewTypedTool("greet", "", func(ctx context.Context, in struct {
+		Name string `json:"name"`
+	}) (string, error) {
+		return "hello " + in.Name, nil
+	})
+	res, err := HandleToolCall(context.Background(), []*Tool{tool}, agentToolCall("1", "greet", `{"name":"bob"}`))
+	if err != nil || res.Content != "hello bob" || res.Structured != nil {
+		t.Errorf("result = %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,486 @@
+package gollama
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// TypeGenOptions configures GenerateTypeScript.
+type TypeGenOptions struct {
+	// Namespace, if set, puts the declarations in
+	// "declare namespace Namespace { ... }" instead of the global scope, so
+	// code calls the tools as Namespace.read_file(...).
+	Namespace string
+}
+
+// GenerateTypeScript returns a TypeScript declaration file (.d.ts) for
+// tools, for code-mode bridges in which the model calls tools from code it
+// writes. Each tool is declared as an async function taking its arguments as
+// an object:
+//
+//	/** Read a text file. */
+//	declare function read_file(input: ReadFileInput): Promise<ReadFileOutput>;
+//
+// Input types are derived from the tools' Params schemas and output types
+// from their OutputType, reflected as for NewTypedTool. A tool without an
+// OutputType (or whose OutputType is a ToolResult) returns string, the
+// result's Content. Tool and property descriptions become doc comments.
+//
+// Tool names that are not valid identifiers have the offending characters
+// replaced with underscores; it is an error for two tools to end up with the
+// same name.
+func GenerateTypeScript(tools []*Tool, opts TypeGenOptions) (string, error) {
+	types, err := toolTypes(tools)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("// Code generated by gollama. DO NOT EDIT.\n")
+	indent, declare := "", "declare "
+	if opts.Namespace != "" {
+		if !isTSIdentifier(opts.Namespace) {
+			return "", fmt.Errorf("invalid namespace %q", opts.Namespace)
+		}
+		fmt.Fprintf(&sb, "\ndeclare namespace %s {\n", opts.Namespace)
+		indent, declare = "  ", ""
+	}
+	for i, tt := range types {
+		if i > 0 || opts.Namespace == "" {
+			sb.WriteString("\n")
+		}
+		writeTSDecl(&sb, indent, tt.inputName, fmt.Sprintf("Arguments of %s.", tt.fn), tt.input)
+		writeTSDecl(&sb, indent, tt.outputName, "", tt.output)
+		sb.WriteString(tsDoc(tt.tool.Description, indent))
+		fmt.Fprintf(&sb, "%s%sfunction %s(input: %s): Promise<%s>;\n", indent, declare, tt.fn, tt.inputName, tt.outputName)
+	}
+	if opts.Namespace != "" {
+		sb.WriteString("}\n")
+	}
+	return sb.String(), nil
+}
+
+// GenerateJSONSchema returns a JSON Schema document describing the inputs
+// and outputs of tools. Each is a definition under $defs, named as in
+// GenerateTypeScript (ReadFileInput, ReadFileOutput), with the tool's
+// description on its input. Local references in the Params schemas are
+// inlined, so every definition stands on its own.
+func GenerateJSONSchema(tools []*Tool) ([]byte, error) {
+	types, err := toolTypes(tools)
+	if err != nil {
+		return nil, err
+	}
+	defs := map[string]any{}
+	for _, tt := range types {
+		input := tt.input
+		if tt.tool.Description != "" {
+			if _, ok := input["description"]; !ok {
+				input = copySchema(input)
+				input["description"] = tt.tool.Description
+			}
+		}
+		defs[tt.inputName] = input
+		defs[tt.outputName] = tt.output
+	}
+	return json.MarshalIndent(map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$defs":   defs,
+	}, "", "  ")
+}
+
+// toolType holds the generated names and schemas of one tool.
+type toolType struct {
+	tool                  *Tool
+	fn                    string // function name
+	inputName, outputName string
+	input, output         map[string]any
+}
+
+var toolResultType = reflect.TypeOf(ToolResult{})
+
+func toolTypes(tools []*Tool) ([]toolType, error) {
+	var out []toolType
+	seen := map[string]string{}
+	for _, tool := range tools {
+		fn := tsIdentifier(tool.Name)
+		base := pascalCase(tool.Name)
+		for _, name := range []string{fn, base + "Input", base + "Output"} {
+			if prev, ok := seen[name]; ok {
+				return nil, fmt.Errorf("tools %q and %q both generate %s", prev, tool.Name, name)
+			}
+			seen[name] = tool.Name
+		}
+
+		input := map[string]any{"type": "object"}
+		if tool.Params != nil {
+			s, err := normalizeSchema(tool.Params)
+			if err != nil {
+				return nil, fmt.Errorf("error reading schema of tool %q: %w", tool.Name, err)
+			}
+			if s != nil {
+				input = inlineSchemaRefs(s, s, map[string]bool{}).(map[string]any)
+			}
+		}
+
+		output := map[string]any{"type": "string"}
+		if tool.OutputType != nil {
+			t := reflect.TypeOf(tool.OutputType)
+			for t.Kind() == reflect.Pointer {
+				t = t.Elem()
+			}
+			if t != toolResultType {
+				s, err := schemaForType(t)
+				if err != nil {
+					return nil, fmt.Errorf("error deriving output schema of tool %q: %w", tool.Name, err)
+				}
+				if output, err = normalizeSchema(s); err != nil {
+					return nil, fmt.Errorf("error deriving output schema of tool %q: %w", tool.Name, err)
+				}
+			}
+		}
+
+		out = append(out, toolType{
+			tool:       tool,
+			fn:         fn,
+			inputName:  base + "Input",
+			outputName: base + "Output",
+			input:      input,
+			output:     output,
+		})
+	}
+	return out, nil
+}
+
+// inlineSchemaRefs returns a copy of the schema v with local $refs replaced
+// by what they point to in root, and $defs dropped. A reference to a schema
+// that is already being expanded (a recursive type) becomes the empty
+// schema.
+func inlineSchemaRefs(v any, root map[string]any, expanding map[string]bool) any {
+	switch v := v.(type) {
+	case map[string]any:
+		if ref, ok := v["$ref"].(string); ok {
+			target, found := resolveSchemaRef(root, ref)
+			if !found || expanding[ref] {
+				return map[string]any{}
+			}
+			expanding[ref] = true
+			resolved := inlineSchemaRefs(target, root, expanding).(map[string]any)
+			delete(expanding, ref)
+			// Keep annotations written next to the reference.
+			for k, e := range v {
+				if k != "$ref" {
+					resolved[k] = inlineSchemaRefs(e, root, expanding)
+				}
+			}
+			return resolved
+		}
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			switch k {
+			case "$defs", "definitions", "$schema", "$id":
+				continue
+			}
+			out[k] = inlineSchemaRefs(e, root, expanding)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = inlineSchemaRefs(e, root, expanding)
+		}
+		return out
+	}
+	return v
+}
+
+func copySchema(s map[string]any) map[string]any {
+	out := make(map[string]any, len(s))
+	for k, v := range s {
+		out[k] = v
+	}
+	return out
+}
+
+// writeTSDecl declares name as the type of schema: an interface for plain
+// object schemas, a type alias otherwise.
+func writeTSDecl(sb *strings.Builder, indent, name, doc string, schema map[string]any) {
+	sb.WriteString(tsDoc(doc, indent))
+	ts := tsType(schema, indent)
+	if schemaTypeIs(schema, "object") && strings.HasPrefix(ts, "{") && !hasCombinator(schema) {
+		fmt.Fprintf(sb, "%sinterface %s %s\n", indent, name, ts)
+		return
+	}
+	fmt.Fprintf(sb, "%stype %s = %s;\n", indent, name, ts)
+}
+
+// tsType renders schema as a TypeScript type. Nested object types are
+// written over several lines, indented from indent.
+func tsType(schema map[string]any, indent string) string {
+	if c, ok := schema["const"]; ok {
+		return tsLiteral(c)
+	}
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 {
+		parts := make([]string, len(enum))
+		for i, e := range enum {
+			parts[i] = tsLiteral(e)
+		}
+		return strings.Join(parts, " | ")
+	}
+
+	var parts []string
+	if _, ok := schema["type"]; ok || schema["properties"] != nil {
+		parts = append(parts, tsBaseType(schema, indent))
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		if subs, ok := schema[key].([]any); ok && len(subs) > 0 {
+			parts = append(parts, tsUnion(subs, indent))
+		}
+	}
+	if subs, ok := schema["allOf"].([]any); ok {
+		for _, sub := range subs {
+			if m, ok := sub.(map[string]any); ok {
+				parts = append(parts, tsType(m, indent))
+			}
+		}
+	}
+	switch len(parts) {
+	case 0:
+		return "unknown"
+	case 1:
+		return parts[0]
+	}
+	for i, p := range parts {
+		if strings.Contains(p, " | ") {
+			parts[i] = "(" + p + ")"
+		}
+	}
+	return strings.Join(parts, " & ")
+}
+
+func tsUnion(subs []any, indent string) string {
+	var parts []string
+	for _, sub := range subs {
+		if m, ok := sub.(map[string]any); ok {
+			t := tsType(m, indent)
+			if strings.Contains(t, " & ") {
+				t = "(" + t + ")"
+			}
+			parts = append(parts, t)
+		}
+	}
+	return strings.Join(parts, " | ")
+}
+
+// tsBaseType renders the type keyword of schema (a name or a list of
+// names).
+func tsBaseType(schema map[string]any, indent string) string {
+	var names []string
+	switch t := schema["type"].(type) {
+	case string:
+		names = []string{t}
+	case []any:
+		for _, n := range t {
+			if s, ok := n.(string); ok {
+				names = append(names, s)
+			}
+		}
+	default:
+		names = []string{"object"}
+	}
+
+	var parts []string
+	for _, name := range names {
+		switch name {
+		case "string", "boolean", "null":
+			parts = append(parts, name)
+		case "integer", "number":
+			parts = append(parts, "number")
+		case "array":
+			items, ok := schema["items"].(map[string]any)
+			if !ok {
+				parts = append(parts, "unknown[]")
+				break
+			}
+			it := tsType(items, indent)
+			if strings.ContainsAny(it, " |&") {
+				parts = append(parts, "Array<"+it+">")
+			} else {
+				parts = append(parts, it+"[]")
+			}
+		case "object":
+			parts = append(parts, tsObject(schema, indent))
+		default:
+			parts = append(parts, "unknown")
+		}
+	}
+	if len(parts) == 0 {
+		return "unknown"
+	}
+	return strings.Join(parts, " | ")
+}
+
+// tsObject renders an object schema as a type literal, properties sorted by
+// name, or as a Record when it has no properties.
+func tsObject(schema map[string]any, indent string) string {
+	props, _ := schema["properties"].(map[string]any)
+	if len(props) == 0 {
+		switch extra := schema["additionalProperties"].(type) {
+		case map[string]any:
+			return "Record<string, " + tsType(extra, indent) + ">"
+		case bool:
+			if !extra {
+				return "Record<string, never>"
+			}
+		}
+		return "Record<string, unknown>"
+	}
+
+	required := map[string]bool{}
+	if list, ok := schema["required"].([]any); ok {
+		for _, r := range list {
+			if s, ok := r.(string); ok {
+				required[s] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	inner := indent + "  "
+	var sb strings.Builder
+	sb.WriteString("{\n")
+	for _, name := range names {
+		prop, _ := props[name].(map[string]any)
+		if desc, ok := prop["description"].(string); ok {
+			sb.WriteString(tsDoc(desc, inner))
+		}
+		key := name
+		if !isTSIdentifier(name) {
+			b, _ := json.Marshal(name)
+			key = string(b)
+		}
+		opt := ""
+		if !required[name] {
+			opt = "?"
+		}
+		fmt.Fprintf(&sb, "%s%s%s: %s;\n", inner, key, opt, tsType(prop, inner))
+	}
+	sb.WriteString(indent + "}")
+	return sb.String()
+}
+
+func schemaTypeIs(schema map[string]any, name string) bool {
+	if t, ok := schema["type"].(string); ok {
+		return t == name
+	}
+	return schema["type"] == nil && schema["properties"] != nil
+}
+
+func hasCombinator(schema map[string]any) bool {
+	for _, key := range []string{"anyOf", "oneOf", "allOf", "enum", "const"} {
+		if _, ok := schema[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func tsLiteral(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "unknown"
+	}
+	return string(b)
+}
+
+// tsDoc renders text as a JSDoc comment, or nothing if text is empty.
+func tsDoc(text, indent string) string {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "*/", "*\\/"))
+	if text == "" {
+		return ""
+	}
+	lines := strings.Split(text, "\n")
+	if len(lines) == 1 {
+		return indent + "/** " + text + " */\n"
+	}
+	var sb strings.Builder
+	sb.WriteString(indent + "/**\n")
+	for _, l := range lines {
+		l = strings.TrimRight(l, " \t")
+		if l == "" {
+			sb.WriteString(indent + " *\n")
+		} else {
+			sb.WriteString(indent + " * " + l + "\n")
+		}
+	}
+	sb.WriteString(indent + " */\n")
+	return sb.String()
+}
+
+var tsReserved = map[string]bool{
+	"break": true, "case": true, "catch": true, "class": true, "const": true, "continue": true,
+	"debugger": true, "default": true, "delete": true, "do": true, "else": true, "enum": true,
+	"export": true, "extends": true, "false": true, "finally": true, "for": true, "function": true,
+	"if": true, "import": true, "in": true, "instanceof": true, "new": true, "null": true,
+	"return": true, "super": true, "switch": true, "this": true, "throw": true, "true": true,
+	"try": true, "typeof": true, "var": true, "void": true, "while": true, "with": true,
+}
+
+func isTSIdentifier(s string) bool {
+	return s != "" && tsIdentifier(s) == s
+}
+
+// tsIdentifier turns a tool name into a valid identifier.
+func tsIdentifier(name string) string {
+	var sb strings.Builder
+	for i, r := range name {
+		switch {
+		case r == '_' || r == '$' || unicode.IsLetter(r):
+			sb.WriteRune(r)
+		case unicode.IsDigit(r):
+			if i == 0 {
+				sb.WriteRune('_')
+			}
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('_')
+		}
+	}
+	id := sb.String()
+	if id == "" || tsReserved[id] {
+		id += "_"
+	}
+	return id
+}
+
+// pascalCase turns a tool name such as "github__search_issues" into a type
+// name prefix, "GithubSearchIssues".
+func pascalCase(name string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if sb.Len() == 0 && unicode.IsDigit(r) {
+			sb.WriteRune('T')
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	if sb.Len() == 0 {
+		return "Tool"
+	}
+	return sb.String()
+}
@@ -0,0 +1,169 @@
+package gollama
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type typegenArgs struct {
+	Query string   `json:"query" description:"Search terms"`
+	Limit int      `json:"limit,omitempty" min:"1"`
+	Sort  string   `json:"sort,omitempty" enum:"new,top"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+type typegenHit struct {
+	Title string            `json:"title"`
+	Score *float64          `json:"score,omitempty"`
+	Meta  map[string]string `json:"meta,omitempty"`
+}
+
+type typegenResult struct {
+	Hits  []typegenHit `json:"hits"`
+	Total int          `json:"total"`
+}
+
+func typegenTools() []*Tool {
+	search := NewTypedTool("web.search", "Search the web.\nResults are ranked.",
+		func(ctx context.Context, args typegenArgs) (typegenResult, error) {
+			return typegenResult{}, nil
+		})
+	mcpStyle := &Tool{
+		Name:        "get_node",
+		Description: "Fetch a node.",
+		Params: map[string]any{
+			"type": "object",
+			"$defs": map[string]any{
+				"node": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"id":    map[string]any{"type": "string"},
+						"child": map[string]any{"$ref": "#/$defs/node"},
+					},
+				},
+			},
+			"properties": map[string]any{
+				"root":      map[string]any{"$ref": "#/$defs/node", "description": "Where to start"},
+				"depth":     map[string]any{"type": []any{"integer", "null"}},
+				"mode":      map[string]any{"anyOf": []any{map[string]any{"const": "fast"}, map[string]any{"type": "number"}}},
+				"x-request": map[string]any{"type": "boolean"},
+			},
+			"required": []any{"root"},
+		},
+	}
+	return []*Tool{search, mcpStyle}
+}
+
+func TestGenerateTypeScript(t *testing.T) {
+	got, err := GenerateTypeScript(typegenTools(), TypeGenOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `// Code generated by gollama. DO NOT EDIT.
+
+/** Arguments of web_search. */
+interface WebSearchInput {
+  limit?: number;
+  /** Search terms */
+  query: string;
+  sort?: "new" | "top";
+  tags?: string[];
+}
+interface WebSearchOutput {
+  hits: Array<{
+    meta?: Record<string, string>;
+    score?: number | null;
+    title: string;
+  }>;
+  total: number;
+}
+/**
+ * Search the web.
+ * Results are ranked.
+ */
+declare function web_search(input: WebSearchInput): Promise<WebSearchOutput>;
+
+/** Arguments of get_node. */
+interface GetNodeInput {
+  depth?: number | null;
+  mode?: "fast" | number;
+  /** Where to start */
+  root: {
+    child?: unknown;
+    id?: string;
+  };
+  "x-request"?: boolean;
+}
+type GetNodeOutput = string;
+/** Fetch a node. */
+declare function get_node(input: GetNodeInput): Promise<GetNodeOutput>;
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestGenerateTypeScriptNamespace(t *testing.T) {
+	got, err := GenerateTypeScript(typegenTools()[1:], TypeGenOptions{Namespace: "tools"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, "// Code generated by gollama. DO NOT EDIT.\n\ndeclare namespace tools {\n  /** Arguments of get_node. */\n  interface GetNodeInput {\n") {
+		t.Errorf("got:\n%s", got)
+	}
+	if !strings.Contains(got, "\n  function get_node(input: GetNodeInput): Promise<GetNodeOutput>;\n}\n") {
+		t.Errorf("got:\n%s", got)
+	}
+	if _, err := GenerateTypeScript(nil, TypeGenOptions{Namespace: "not valid"}); err == nil {
+		t.Error("invalid namespace accepted")
+	}
+}
+
+func TestGenerateTypeScriptCollision(t *testing.T) {
+	tools := []*Tool{{Name: "a.b"}, {Name: "a_b"}}
+	if _, err := GenerateTypeScript(tools, TypeGenOptions{}); err == nil || !strings.Contains(err.Error(), "a_b") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestGenerateJSONSchema(t *testing.T) {
+	b, err := GenerateJSONSchema(typegenTools())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Schema string                    `json:"$schema"`
+		Defs   map[string]map[string]any `json:"$defs"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Schema == "" || len(doc.Defs) != 4 {
+		t.Fatalf("doc = %s", b)
+	}
+	in := doc.Defs["WebSearchInput"]
+	if in["description"] != "Search the web.\nResults are ranked." {
+		t.Errorf("input = %v", in)
+	}
+	if props := doc.Defs["WebSearchOutput"]["properties"].(map[string]any); props["total"].(map[string]any)["type"] != "integer" {
+		t.Errorf("output = %v", doc.Defs["WebSearchOutput"])
+	}
+
+	// References are inlined and $defs dropped.
+	node := doc.Defs["GetNodeInput"]
+	if _, ok := node["$defs"]; ok {
+		t.Errorf("$defs kept: %v", node)
+	}
+	root := node["properties"].(map[string]any)["root"].(map[string]any)
+	if root["type"] != "object" || root["description"] != "Where to start" {
+		t.Errorf("root = %v", root)
+	}
+	if strings.Contains(string(b), "$ref") {
+		t.Errorf("$ref kept: %s", b)
+	}
+	if doc.Defs["GetNodeOutput"]["type"] != "string" {
+		t.Errorf("output = %v", doc.Defs["GetNodeOutput"])
+	}
+}
@@ -241,6 +241,8 @@ type GenerateResponse struct {
 	EvalDuration       int64  `json:"eval_duration,omitempty"`
 	EvalCount          int    `json:"eval_count,omitempty"`
 	Error              string `json:"error,omitempty"`
+
+	Meta *ResponseMeta `json:"-"` // HTTP exchange details; see ResponseMeta
 }
 
 // ResponseMessage represents a response from the Ollama /api/chat endpoint.
@@ -254,6 +256,8 @@ type ResponseMessage struct {
 	PromptEvalDuration int64   `json:"prompt_eval_duration,omitempty"`
 	EvalDuration       int64   `json:"eval_duration,omitempty"`
 	EvalCount          int     `json:"eval_count,omitempty"`
+
+	Meta *ResponseMeta `json:"-"` // HTTP exchange details; see ResponseMeta
 }
 
 // ResponseMessageGenerate represents a response from OpenAI-compatible /chat/completions endpoint.
@@ -270,6 +274,18 @@ type ResponseMessageGenerate struct {
 	// "tool_use"/"stop_sequence"; OpenAI: "stop"/"length"/"tool_calls"). Callers
 	// that only need to know whether the turn was cut short should use Truncated.
 	StopReason string `json:"stop_reason,omitempty"`
+
+	// Meta describes the HTTP exchange behind this response (request ID,
+	// rate-limit headers, retries and timings). Set by ChatCompletion and Turn.
+	Meta *ResponseMeta `json:"-"`
+
+	// Backend names the FallbackClient backend, PoolClient endpoint or
+	// HedgedClient target that served the response. Empty for responses from
+	// a plain Client.
+	Backend string `json:"-"`
+
+	// Hedge reports the losing requests of a HedgedClient call.
+	Hedge *HedgeStats `json:"-"`
 }
 
 // Truncated reports whether the turn was cut short because it hit the output
@@ -309,6 +325,21 @@ type Usage struct {
 	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
 }
 
+// Add accumulates the token counts of o into u.
+func (u *Usage) Add(o Usage) {
+	u.PromptTokens += o.PromptTokens
+	u.CompletionTokens += o.CompletionTokens
+	u.TotalTokens += o.TotalTokens
+	u.CacheCreationInputTokens += o.CacheCreationInputTokens
+	u.CacheReadInputTokens += o.CacheReadInputTokens
+	if o.PromptTokensDetails != nil {
+		if u.PromptTokensDetails == nil {
+			u.PromptTokensDetails = &PromptTokensDetails{}
+		}
+		u.PromptTokensDetails.CachedTokens += o.PromptTokensDetails.CachedTokens
+	}
+}
+
 // PromptTokensDetails provides detailed information about prompt token usage.
 type PromptTokensDetails struct {
 	CachedTokens int `json:"cached_tokens"`